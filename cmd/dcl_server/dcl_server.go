@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"mutex-benchmark/pkg/server"
+)
+
+/*
+main initialise et démarre le serveur HTTP démontrant le double-checked locking.
+Le repository et les handlers sont définis dans pkg/server.
+
+@behavior:
+  - Lit les options communes depuis les flags (et -config)
+  - Démarre le serveur sur -addr, par défaut le port 8086
+  - Affiche les endpoints disponibles
+  - Exporte les traces OpenTelemetry si -otel est fourni
+  - S'arrête proprement sur SIGINT/SIGTERM
+
+@endpoints:
+  - GET /process : Résultat mis en cache par double-checked locking (paramètre key)
+  - GET /stats : Statistiques du serveur
+*/
+func main() {
+	var opts server.Options
+	opts.RegisterFlags(flag.CommandLine)
+	if err := server.ParseFlags(flag.CommandLine, os.Args[1:], &opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Erreur: %v\n", err)
+		os.Exit(2)
+	}
+	server.EnableContentionProfiling(opts.MutexProfileFraction)
+	shutdownTracing, err := server.SetupTracing(context.Background(), opts.OTelEndpoint, "mutex-benchmark-dcl")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erreur: %v\n", err)
+		os.Exit(2)
+	}
+	defer shutdownTracing(context.Background())
+
+	variant, _ := server.Lookup("dcl")
+	addr := variant.ListenAddr(opts)
+	variant.PrintBanner(addr)
+
+	srv := variant.NewServer(addr, opts)
+	if err := server.Run([]*http.Server{srv}); err != nil {
+		panic(err)
+	}
+}
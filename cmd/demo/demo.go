@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"mutex-benchmark/pkg/latency"
+	"mutex-benchmark/pkg/server"
+)
+
+/*
+main démarre les serveurs bad, good et syncmap dans le processus, sur des
+listeners httptest, puis affiche le tableau comparatif des latences : la
+démonstration complète en une commande, sans lancer les serveurs à part.
+
+	go run ./cmd/demo
+	go run ./cmd/demo -levels=1,10,50 -requests=200
+	go run ./cmd/demo -throughput=10s
+	go run ./cmd/demo -capacity=100ms
+	go run ./cmd/demo -scenarios=sweep.csv
+	go run ./cmd/demo -scenarios=sweep.csv -insecure   # URL https:// vers des serveurs -tls
+
+Avec -scenarios, les serveurs ne sont pas démarrés : chaque ligne du CSV
+(url,concurrency,total,readRatio) vise un serveur déjà lancé, et un tableau
+unique rassemble les mesures (voir latency.RunScenarios). Avec -throughput,
+chaque serveur est ensuite chargé pendant la durée donnée au plus haut niveau
+de -levels, et ses requêtes terminées par 100ms sont tracées en sparkline.
+Avec -capacity, la plus haute concurrence que chaque serveur soutient avec un
+p99 sous la cible est cherchée par dichotomie (voir latency.FindMaxConcurrency).
+
+@behavior:
+  - Chaque serveur écoute sur un port libre choisi par httptest
+  - Les serveurs sont construits avec les options par défaut (copie des données activée)
+  - Les serveurs sont arrêtés après l'affichage du tableau
+*/
+func main() {
+	levels := flag.String("levels", "1,10,50", "Niveaux de concurrence, croissants, séparés par des virgules")
+	requests := flag.Int("requests", 100, "Requêtes par serveur et par niveau de concurrence")
+	throughput := flag.Duration("throughput", 0, "Durée de charge par serveur pour tracer le throughput par 100ms (0 = désactivé)")
+	capacity := flag.Duration("capacity", 0, "Cible de p99 pour chercher la concurrence maximum sûre de chaque serveur (0 = désactivé)")
+	scenarios := flag.String("scenarios", "", "Fichier CSV de scénarios (url,concurrency,total,readRatio) à mesurer sur des serveurs déjà lancés")
+	insecure := flag.Bool("insecure", false, "Accepte le certificat auto-signé des serveurs lancés avec -tls (URL https:// des scénarios)")
+	flag.Parse()
+
+	if *insecure {
+		// latency clone http.DefaultTransport : ses clients héritent de la configuration TLS
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		http.DefaultTransport = transport
+	}
+
+	if *scenarios != "" {
+		runScenarios(*scenarios)
+		return
+	}
+
+	concurrencyLevels, err := latency.ParseLevels(*levels)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erreur: -levels: %v\n", err)
+		os.Exit(2)
+	}
+	if *throughput < 0 {
+		fmt.Fprintln(os.Stderr, "Erreur: -throughput doit être positif")
+		os.Exit(2)
+	}
+	if *capacity < 0 {
+		fmt.Fprintln(os.Stderr, "Erreur: -capacity doit être positif")
+		os.Exit(2)
+	}
+	if *requests <= 0 {
+		fmt.Fprintln(os.Stderr, "Erreur: -requests doit être positif")
+		os.Exit(2)
+	}
+
+	opts := server.DefaultConfig().Options()
+	columns := []struct {
+		variant string
+		name    string
+		label   string
+	}{
+		{"bad", "Bad (defer)", "BAD"},
+		{"good", "Good (no defer)", "GOOD"},
+		{"syncmap", "SyncMap (no mutex)", "SYNC.MAP"},
+	}
+
+	var servers []latency.Server
+	for _, col := range columns {
+		variant, _ := server.Lookup(col.variant)
+		handler := variant.NewHandler(opts)
+		ts := httptest.NewServer(handler)
+		defer ts.Close()
+		if closer, ok := handler.(io.Closer); ok {
+			defer closer.Close()
+		}
+		servers = append(servers, latency.Server{Name: col.name, Label: col.label, URL: ts.URL + "/process"})
+	}
+
+	fmt.Printf("\n%s%s=== 🚀 COMPARAISON DE LATENCE : DEFER vs UNLOCK EXPLICITE ===%s\n", latency.Bold, latency.ColorCyan, latency.ColorReset)
+	latency.Compare(os.Stdout, servers, concurrencyLevels, *requests)
+
+	if *throughput > 0 {
+		concurrency := concurrencyLevels[len(concurrencyLevels)-1]
+		fmt.Printf("\n%s%s=== 📈 THROUGHPUT PAR 100ms (%d en vol, %s par serveur) ===%s\n", latency.Bold, latency.ColorCyan, concurrency, *throughput, latency.ColorReset)
+		latency.CompareThroughput(os.Stdout, servers, concurrency, *throughput)
+	}
+
+	if *capacity > 0 {
+		fmt.Printf("\n%s%s=== 🎯 CONCURRENCE MAXIMUM SÛRE (p99 ≤ %s) ===%s\n", latency.Bold, latency.ColorCyan, *capacity, latency.ColorReset)
+		latency.CompareCapacity(os.Stdout, servers, *capacity)
+	}
+}
+
+/*
+runScenarios mesure les scénarios du fichier path et affiche le tableau combiné.
+
+@params:
+  - path: string fichier CSV de scénarios
+*/
+func runScenarios(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erreur: -scenarios: %v\n", err)
+		os.Exit(2)
+	}
+	defer f.Close()
+
+	results, err := latency.RunScenarios(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erreur: %s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n%s%s=== 🚀 SCÉNARIOS (%s) ===%s\n", latency.Bold, latency.ColorCyan, path, latency.ColorReset)
+	latency.WriteScenarios(os.Stdout, results)
+}
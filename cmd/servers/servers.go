@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"mutex-benchmark/pkg/server"
+)
+
+/*
+usage affiche l'aide de la commande et les sous-commandes disponibles.
+*/
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: servers <sous-commande> [flags]")
+	fmt.Fprintln(os.Stderr, "\nSous-commandes:")
+	for _, v := range server.Variants {
+		fmt.Fprintf(os.Stderr, "  %-8s %s sur %s\n", v.Name, v.Title, v.Addr)
+	}
+	fmt.Fprintln(os.Stderr, "  all      Tous les serveurs dans le même processus")
+}
+
+/*
+main lance un ou plusieurs serveurs de démonstration dans le même processus.
+
+@behavior:
+  - bad, good, syncmap : démarre la variante sur son port (modifiable avec -addr, ou -socket pour une socket Unix)
+  - all : démarre toutes les variantes, chacune sur son port par défaut
+  - Les options communes (-copy, ...) s'appliquent à tous les serveurs démarrés
+  - -otel=hôte:port exporte les traces OpenTelemetry vers un collecteur OTLP/HTTP
+  - -tls sert en HTTPS, avec -cert/-key ou un certificat auto-signé
+  - -config=fichier.json charge les options depuis un fichier, les flags explicites priment
+  - Arrêt propre de tous les serveurs sur SIGINT/SIGTERM
+*/
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	var opts server.Options
+	opts.RegisterFlags(fs)
+
+	var variants []server.Variant
+	switch name {
+	case "all":
+		variants = server.Variants
+	default:
+		v, ok := server.Lookup(name)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Sous-commande inconnue: %s\n\n", name)
+			usage()
+			os.Exit(2)
+		}
+		variants = []server.Variant{v}
+	}
+	if err := server.ParseFlags(fs, os.Args[2:], &opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Erreur: %v\n", err)
+		os.Exit(2)
+	}
+	if len(variants) > 1 && (opts.Addr != "" || opts.Socket != "") {
+		fmt.Fprintln(os.Stderr, "Erreur: -addr et -socket ne s'appliquent qu'à un seul serveur")
+		os.Exit(2)
+	}
+	server.EnableContentionProfiling(opts.MutexProfileFraction)
+	shutdownTracing, err := server.SetupTracing(context.Background(), opts.OTelEndpoint, "mutex-benchmark")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erreur: %v\n", err)
+		os.Exit(2)
+	}
+
+	servers := make([]*http.Server, 0, len(variants))
+	for _, v := range variants {
+		listenAddr := v.ListenAddr(opts)
+		v.PrintBanner(listenAddr)
+		servers = append(servers, v.NewServer(listenAddr, opts))
+	}
+
+	err = server.Run(servers)
+	// Vide les spans en attente avant de quitter (os.Exit ignore les defer)
+	shutdownTracing(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erreur: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"mutex-benchmark/pkg/latency"
+)
+
+/*
+main charge un serveur déjà lancé à concurrence fixe pendant une durée
+donnée, affiche une ligne par fenêtre puis un verdict de stabilité : là où
+un benchmark s'arrête après b.N requêtes, un soak test de plusieurs minutes
+révèle ce qui n'apparaît qu'avec le temps, comme une map qui grandit sans
+borne ou les pauses du GC.
+
+	go run ./cmd/soak                                   # serveur good, 10 minutes
+	go run ./cmd/soak -url=http://localhost:8082/process -c=10 -duration=10m -interval=10s
+	go run ./cmd/soak -duration=2m -tolerance=0.3
+
+@behavior:
+  - /stats est lu sur le même hôte que -url, sauf -stats explicite (data_size et tas)
+  - Ctrl-C arrête la charge et juge les fenêtres déjà closes
+  - Code de sortie 1 si le verdict est DÉGRADÉ, 2 pour un flag invalide
+*/
+func main() {
+	target := flag.String("url", "http://localhost:8082/process", "URL chargée pendant le soak test")
+	statsURL := flag.String("stats", "", "URL de /stats du serveur (défaut : /stats sur l'hôte de -url, \"none\" désactive)")
+	concurrency := flag.Int("c", 10, "Nombre de requêtes en vol")
+	duration := flag.Duration("duration", 10*time.Minute, "Durée totale du soak test")
+	interval := flag.Duration("interval", 10*time.Second, "Durée d'une fenêtre, une ligne affichée par fenêtre")
+	tolerance := flag.Float64("tolerance", latency.DefaultSoakTolerance, "Variation relative tolérée entre le début et la fin (throughput, p99, data_size, tas)")
+	flag.Parse()
+
+	switch {
+	case *concurrency <= 0:
+		fail("-c doit être positif")
+	case *interval <= 0 || *duration < *interval:
+		fail("-interval doit être positif et -duration au moins égale à -interval")
+	case *tolerance <= 0:
+		fail("-tolerance doit être positive")
+	}
+	stats, err := resolveStatsURL(*target, *statsURL)
+	if err != nil {
+		fail(err.Error())
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("\n%s%s=== 🔁 SOAK TEST : %s (%d en vol, %s par fenêtre de %s) ===%s\n",
+		latency.Bold, latency.ColorCyan, *target, *concurrency, *duration, *interval, latency.ColorReset)
+	samples := latency.Soak(ctx, *target, stats, *concurrency, *duration, *interval, func(s latency.SoakSample) {
+		latency.WriteSoakSample(os.Stdout, s)
+	})
+
+	verdict := latency.JudgeSoak(samples, *tolerance)
+	latency.WriteSoakVerdict(os.Stdout, verdict, *tolerance)
+	if !verdict.Stable() {
+		os.Exit(1)
+	}
+}
+
+/*
+resolveStatsURL retourne l'URL de /stats à relever.
+
+@params:
+  - target: string URL chargée (-url)
+  - explicit: string valeur de -stats ("" = /stats sur l'hôte de target, "none" = aucune)
+
+@returns: (string, error) - URL de /stats ("" pour ne pas la relever), ou
+erreur si target n'est pas une URL http(s) absolue
+*/
+func resolveStatsURL(target, explicit string) (string, error) {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("-url %q: want an absolute http(s) URL", target)
+	}
+	switch explicit {
+	case "none":
+		return "", nil
+	case "":
+		return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/stats"}).String(), nil
+	}
+	return explicit, nil
+}
+
+// fail affiche une erreur de flag et quitte avec le code 2
+func fail(msg string) {
+	fmt.Fprintf(os.Stderr, "Erreur: %s\n", msg)
+	os.Exit(2)
+}
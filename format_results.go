@@ -1,11 +1,21 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"html"
+	"io"
+	"math"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+
+	"mutex-benchmark/pkg/latency"
 )
 
 const (
@@ -19,68 +29,496 @@ const (
 	Bold        = "\033[1m"
 )
 
+// maxLineBytes borne la longueur d'une ligne de la sortie go test : bien au-delà
+// des 64 Ko par défaut de bufio.Scanner, qu'atteignent les lignes à nombreuses métriques
+const maxLineBytes = 16 << 20
+
+// ansiEscape reconnaît les séquences d'échappement ANSI (CSI : couleurs, styles)
+// d'une sortie go test colorée, retirées avant l'analyse
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]`)
+
+// BenchmarkResult est la mesure d'un serveur à un niveau de concurrence.
+// P99Ms est absent (0) des sorties et baselines qui ne le mesurent pas.
 type BenchmarkResult struct {
+	Name        string  `json:"name"`
+	Concurrency int     `json:"concurrency"`
+	ReqPerSec   float64 `json:"req_per_sec"`
+	MsPerReq    float64 `json:"ms_per_req"`
+	P99Ms       float64 `json:"p99_ms,omitempty"`
+}
+
+// Check compare une métrique à la baseline. Degradation est la dégradation
+// en pourcentage : la baisse pour req/s, la hausse pour p99 (négative si mieux).
+type Check struct {
+	Metric      string
+	Baseline    float64
+	Current     float64
+	Degradation float64
+	Threshold   float64
+}
+
+// Failed indique si la dégradation dépasse le seuil de la métrique.
+func (c Check) Failed() bool {
+	return c.Degradation > c.Threshold
+}
+
+// GateResult est le verdict d'un benchmark présent dans la baseline : une
+// vérification par métrique mesurée des deux côtés.
+type GateResult struct {
 	Name        string
 	Concurrency int
-	ReqPerSec   float64
-	MsPerReq    float64
+	Checks      []Check
+}
+
+// Failed indique si au moins une métrique du benchmark a régressé.
+func (g GateResult) Failed() bool {
+	for _, c := range g.Checks {
+		if c.Failed() {
+			return true
+		}
+	}
+	return false
 }
 
 func main() {
-	results := parseBenchmarkOutput()
+	// Sous-commande diff : comparaison de deux résultats enregistrés, sans lire de sortie go test
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		os.Exit(runDiff(os.Args[2:]))
+	}
+
+	baselinePath := flag.String("baseline", "", "Fichier JSON de référence pour détecter les régressions")
+	threshold := flag.Float64("threshold", 10, "Baisse de req/s tolérée (en %) avant d'échouer")
+	p99Threshold := flag.Float64("p99Threshold", 20, "Hausse de la latence p99 tolérée (en %) avant d'échouer")
+	savePath := flag.String("save", "", "Enregistre les résultats parsés dans un fichier JSON")
+	verbose := flag.Bool("verbose", false, "Affiche sur stderr les lignes de benchmark ignorées car mal formées")
+	reportPath := flag.String("report", "", "Lit les résultats depuis un rapport JSON de TestLatencyComparison (-out) au lieu de la sortie de go test")
+	svgPath := flag.String("svg", "", "Écrit un graphique SVG du throughput par niveau de concurrence, une courbe par serveur")
+	flag.Parse()
+
+	var results []BenchmarkResult
+	if *reportPath != "" {
+		var err error
+		if results, err = loadResults(*reportPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Impossible de charger le rapport %s: %v\n", *reportPath, err)
+			os.Exit(1)
+		}
+	} else {
+		// Lire depuis le fichier passé en argument, sinon depuis stdin
+		input := io.Reader(os.Stdin)
+		if flag.NArg() > 0 {
+			f, err := os.Open(flag.Arg(0))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Impossible d'ouvrir le fichier %s: %v\n", flag.Arg(0), err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			input = f
+		}
+
+		logw := io.Discard
+		if *verbose {
+			logw = os.Stderr
+		}
+		var err error
+		if results, err = parseBenchmarkOutput(input, logw); err != nil {
+			fmt.Fprintf(os.Stderr, "Erreur de lecture de la sortie des benchmarks: %v\n", err)
+			os.Exit(1)
+		}
+	}
 	if len(results) == 0 {
 		fmt.Println("Aucun résultat de benchmark trouvé")
 		return
 	}
 
 	printFormattedResults(results)
+
+	if *savePath != "" {
+		if err := saveResults(*savePath, results); err != nil {
+			fmt.Fprintf(os.Stderr, "Impossible d'enregistrer les résultats: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *svgPath != "" {
+		if err := os.WriteFile(*svgPath, []byte(renderSVG(results)), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "Impossible d'écrire le graphique %s: %v\n", *svgPath, err)
+			os.Exit(1)
+		}
+	}
+
+	if *baselinePath != "" {
+		baseline, err := loadResults(*baselinePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Impossible de charger la baseline %s: %v\n", *baselinePath, err)
+			os.Exit(1)
+		}
+
+		gate := gateResults(baseline, results, *threshold, *p99Threshold)
+		if printGate(gate, *threshold, *p99Threshold) > 0 {
+			os.Exit(1)
+		}
+	}
+}
+
+func saveResults(path string, results []BenchmarkResult) error {
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// loadResults lit des résultats enregistrés avec -save (tableau JSON), ou un
+// rapport de TestLatencyComparison (objet latency.ComparisonReport).
+func loadResults(path string) ([]BenchmarkResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		report, err := latency.ReadReport(bytes.NewReader(trimmed))
+		if err != nil {
+			return nil, err
+		}
+		return reportResults(report), nil
+	}
+	var results []BenchmarkResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// reportResults convertit un rapport de comparaison en résultats de benchmark,
+// un par serveur et par niveau de concurrence.
+func reportResults(report *latency.ComparisonReport) []BenchmarkResult {
+	results := make([]BenchmarkResult, 0, len(report.Results))
+	for _, r := range report.Results {
+		results = append(results, BenchmarkResult{
+			Name:        r.Server,
+			Concurrency: r.Concurrency,
+			ReqPerSec:   r.ReqPerSec,
+			MsPerReq:    r.MeanMs,
+			P99Ms:       r.P99Ms,
+		})
+	}
+	return results
+}
+
+// gateResults compare chaque résultat à son équivalent dans la baseline
+// (même serveur, même concurrence) : le throughput échoue au-delà de
+// threshold % de baisse, le p99 au-delà de p99Threshold % de hausse, même si
+// le throughput tient. Le p99 n'est vérifié que s'il est mesuré des deux
+// côtés, pour qu'une ancienne baseline sans p99 reste utilisable.
+func gateResults(baseline, current []BenchmarkResult, threshold, p99Threshold float64) []GateResult {
+	gate := []GateResult{}
+	for _, cur := range current {
+		for _, base := range baseline {
+			if base.Name != cur.Name || base.Concurrency != cur.Concurrency {
+				continue
+			}
+			result := GateResult{Name: cur.Name, Concurrency: cur.Concurrency}
+			if base.ReqPerSec > 0 {
+				result.Checks = append(result.Checks, Check{
+					Metric:      "req/s",
+					Baseline:    base.ReqPerSec,
+					Current:     cur.ReqPerSec,
+					Degradation: -percentChange(base.ReqPerSec, cur.ReqPerSec),
+					Threshold:   threshold,
+				})
+			}
+			if base.P99Ms > 0 && cur.P99Ms > 0 {
+				result.Checks = append(result.Checks, Check{
+					Metric:      "p99",
+					Baseline:    base.P99Ms,
+					Current:     cur.P99Ms,
+					Degradation: percentChange(base.P99Ms, cur.P99Ms),
+					Threshold:   p99Threshold,
+				})
+			}
+			if len(result.Checks) > 0 {
+				gate = append(gate, result)
+			}
+		}
+	}
+	return gate
+}
+
+// percentChange retourne la variation de current par rapport à base, en
+// pourcentage (positive si current est plus grand). base doit être non nul.
+func percentChange(base, current float64) float64 {
+	return (current - base) / base * 100
+}
+
+// printGate affiche une ligne PASS/FAIL par benchmark comparé, avec chaque
+// métrique vérifiée, puis le bilan. Retourne le nombre de benchmarks en échec.
+func printGate(gate []GateResult, threshold, p99Threshold float64) int {
+	fmt.Printf("\n%s🚦 Comparaison à la baseline (req/s -%.1f%%, p99 +%.1f%%):%s\n", Bold, threshold, p99Threshold, ColorReset)
+	failed := 0
+	for _, g := range gate {
+		verdict, color := "PASS", ColorGreen
+		if g.Failed() {
+			verdict, color = "FAIL", ColorRed
+			failed++
+		}
+		fmt.Printf("%s%s%s %-12s concurrence %-4d", color, verdict, ColorReset, g.Name, g.Concurrency)
+		for _, c := range g.Checks {
+			checkColor := ColorReset
+			if c.Failed() {
+				checkColor = ColorRed
+			}
+			unit := " req/s"
+			if c.Metric == "p99" {
+				unit = " ms"
+			}
+			// Une dégradation s'affiche dans le sens de la métrique : baisse de req/s, hausse du p99
+			change := -c.Degradation
+			if c.Metric == "p99" {
+				change = c.Degradation
+			}
+			fmt.Printf("  %s%s %.1f → %.1f%s (%+.1f%%)%s", checkColor, c.Metric, c.Baseline, c.Current, unit, change, ColorReset)
+		}
+		fmt.Println()
+	}
+
+	if failed == 0 {
+		fmt.Printf("\n%s%s✓ Aucune régression (%d benchmark(s) comparé(s))%s\n", Bold, ColorGreen, len(gate), ColorReset)
+	} else {
+		fmt.Printf("\n%s%s✗ %d benchmark(s) en régression sur %d%s\n", Bold, ColorRed, failed, len(gate), ColorReset)
+	}
+	return failed
+}
+
+// ResultDiff associe un benchmark (serveur, concurrence) à sa mesure dans
+// les deux fichiers comparés par diff. Old ou New est nil si le benchmark
+// n'est présent que dans l'autre fichier.
+type ResultDiff struct {
+	Name        string
+	Concurrency int
+	Old         *BenchmarkResult
+	New         *BenchmarkResult
+}
+
+// diffResults apparie les résultats des deux fichiers par serveur et
+// concurrence, triés par serveur puis par concurrence. Un benchmark présent
+// dans un seul fichier est gardé, avec l'autre côté à nil.
+func diffResults(old, cur []BenchmarkResult) []ResultDiff {
+	type key struct {
+		name        string
+		concurrency int
+	}
+	index := map[key]int{}
+	diffs := []ResultDiff{}
+	lookup := func(r BenchmarkResult) *ResultDiff {
+		k := key{r.Name, r.Concurrency}
+		if i, ok := index[k]; ok {
+			return &diffs[i]
+		}
+		index[k] = len(diffs)
+		diffs = append(diffs, ResultDiff{Name: r.Name, Concurrency: r.Concurrency})
+		return &diffs[len(diffs)-1]
+	}
+	for i := range old {
+		lookup(old[i]).Old = &old[i]
+	}
+	for i := range cur {
+		lookup(cur[i]).New = &cur[i]
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Name != diffs[j].Name {
+			return diffs[i].Name < diffs[j].Name
+		}
+		return diffs[i].Concurrency < diffs[j].Concurrency
+	})
+	return diffs
+}
+
+// formatDelta formate l'écart d'une métrique entre les deux fichiers, en
+// valeur et en pourcentage, complété à width caractères, vert s'il
+// l'améliore et rouge s'il la dégrade. higherIsBetter vaut true pour req/s,
+// false pour les latences.
+func formatDelta(base, current float64, higherIsBetter bool, width int) string {
+	if base == 0 {
+		return fmt.Sprintf("%-*s", width, fmt.Sprintf("%+.2f", current-base))
+	}
+	change := percentChange(base, current)
+	color := ColorReset
+	if change != 0 && (change > 0) == higherIsBetter {
+		color = ColorGreen
+	} else if change != 0 {
+		color = ColorRed
+	}
+	// Le remplissage porte sur le texte seul : les codes couleur n'ont pas de largeur
+	return fmt.Sprintf("%s%-*s%s", color, width, fmt.Sprintf("%+.2f (%+.1f%%)", current-base, change), ColorReset)
 }
 
-func parseBenchmarkOutput() []BenchmarkResult {
-	// Lire depuis stdin
-	input := ""
-	buf := make([]byte, 1024)
-	for {
-		n, err := os.Stdin.Read(buf)
-		if err != nil || n == 0 {
-			break
+// printDiff affiche les deux fichiers côte à côte, une ligne par benchmark :
+// req/s et ms/req de chaque côté et leur écart, puis le p99 s'il est mesuré
+// des deux côtés. Un benchmark absent d'un fichier est signalé en jaune.
+func printDiff(diffs []ResultDiff, oldName, newName string) {
+	fmt.Printf("\n%s🔀 Comparaison %s → %s:%s\n", Bold, oldName, newName, ColorReset)
+	fmt.Printf("%s%-12s %-6s │ %-22s │ %-22s │ %-24s │ %s%s\n",
+		Bold, "Serveur", "Conc.", "Avant", "Après", "Δ req/s", "Δ ms/req, p99", ColorReset)
+	fmt.Println("────────────────────┼────────────────────────┼────────────────────────┼──────────────────────────┼──────────────────")
+
+	onlyOld, onlyNew := 0, 0
+	for _, d := range diffs {
+		cell := func(r *BenchmarkResult) string {
+			return fmt.Sprintf("%7.1f req/s %6.2fms", r.ReqPerSec, r.MsPerReq)
+		}
+		switch {
+		case d.New == nil:
+			onlyOld++
+			fmt.Printf("%-12s %-6d │ %-22s │ %s%-22s%s │\n", d.Name, d.Concurrency, cell(d.Old), ColorYellow, "absent", ColorReset)
+		case d.Old == nil:
+			onlyNew++
+			fmt.Printf("%-12s %-6d │ %s%-22s%s │ %-22s │\n", d.Name, d.Concurrency, ColorYellow, "absent", ColorReset, cell(d.New))
+		default:
+			line := fmt.Sprintf("%-12s %-6d │ %-22s │ %-22s │ %s │ %s",
+				d.Name, d.Concurrency, cell(d.Old), cell(d.New),
+				formatDelta(d.Old.ReqPerSec, d.New.ReqPerSec, true, 24),
+				formatDelta(d.Old.MsPerReq, d.New.MsPerReq, false, 18))
+			if d.Old.P99Ms > 0 && d.New.P99Ms > 0 {
+				line += " p99 " + formatDelta(d.Old.P99Ms, d.New.P99Ms, false, 0)
+			}
+			fmt.Println(line)
 		}
-		input += string(buf[:n])
 	}
 
+	fmt.Printf("\n%d benchmark(s) comparé(s)", len(diffs)-onlyOld-onlyNew)
+	if onlyOld > 0 {
+		fmt.Printf(", %s%d seulement dans %s%s", ColorYellow, onlyOld, oldName, ColorReset)
+	}
+	if onlyNew > 0 {
+		fmt.Printf(", %s%d seulement dans %s%s", ColorYellow, onlyNew, newName, ColorReset)
+	}
+	fmt.Println()
+}
+
+// runDiff exécute go run format_results.go diff old.json new.json. Les deux
+// fichiers sont lus par loadResults : résultats -save ou rapports -out.
+// Retourne le code de sortie du programme : 0, ou 2 sur erreur d'usage ou de
+// lecture. Le diff informe sans juger : la barrière de régression reste -baseline.
+func runDiff(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: go run format_results.go diff old.json new.json")
+		return 2
+	}
+	old, err := loadResults(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Impossible de charger %s: %v\n", args[0], err)
+		return 2
+	}
+	cur, err := loadResults(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Impossible de charger %s: %v\n", args[1], err)
+		return 2
+	}
+	printDiff(diffResults(old, cur), args[0], args[1])
+	return 0
+}
+
+// parseBenchmarkOutput extrait les résultats de la sortie de go test -bench.
+// Un benchmark dont le nom ou une métrique ne se parse pas entièrement est
+// ignoré plutôt qu'ajouté avec des valeurs nulles ; la raison est écrite sur
+// logw (io.Discard pour ne rien afficher). Une erreur de lecture, ou une
+// ligne plus longue que maxLineBytes, est retournée au lieu de tronquer
+// silencieusement les résultats.
+func parseBenchmarkOutput(r io.Reader, logw io.Writer) ([]BenchmarkResult, error) {
 	results := []BenchmarkResult{}
-	lines := strings.Split(input, "\n")
 
-	// Patterns pour extraire les données
-	benchPattern := regexp.MustCompile(`Benchmark(Bad|Good)Server_Concurrency(\d+)`)
-	reqPerSecPattern := regexp.MustCompile(`(\d+\.?\d*)\s+req/s`)
-	msPerReqPattern := regexp.MustCompile(`(\d+\.?\d*)\s+ms/req`)
+	// Patterns pour extraire les données : la valeur est le champ complet qui
+	// précède l'unité, pour qu'un nombre corrompu soit rejeté et non tronqué
+	benchPattern := regexp.MustCompile(`Benchmark([A-Za-z]+)Server_Concurrency(\S*?)(?:-\d+)?(?:\s|$)`)
+	reqPerSecPattern := regexp.MustCompile(`(\S+)\s+req/s`)
+	msPerReqPattern := regexp.MustCompile(`(\S+)\s+ms/req`)
+	p99Pattern := regexp.MustCompile(`(\S+)\s+p99-ms(?:\s|$)`)
+
+	// Les métriques peuvent apparaître sur une ligne de continuation :
+	// elles sont rattachées au dernier benchmark rencontré.
+	var current *BenchmarkResult
+	invalid := false
+	flush := func() {
+		if current != nil && !invalid && (current.ReqPerSec > 0 || current.MsPerReq > 0) {
+			results = append(results, *current)
+		}
+		current = nil
+		invalid = false
+	}
+	reject := func(lineNo int, line, reason string) {
+		fmt.Fprintf(logw, "ligne %d ignorée (%s): %s\n", lineNo, reason, line)
+		invalid = true
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	lineNo := 0
+	for scanner.Scan() {
+		line := ansiEscape.ReplaceAllString(scanner.Text(), "")
+		lineNo++
 
-	for _, line := range lines {
 		if matches := benchPattern.FindStringSubmatch(line); matches != nil {
-			serverType := matches[1]
-			concurrency, _ := strconv.Atoi(matches[2])
+			// Avec -v, le nom seul est affiché avant la ligne de résultat :
+			// une entrée sans métrique est simplement remplacée.
+			flush()
+			current = &BenchmarkResult{Name: matches[1]}
+
+			concurrency, err := strconv.Atoi(matches[2])
+			if err != nil || concurrency <= 0 {
+				reject(lineNo, line, "concurrence invalide")
+				continue
+			}
+			current.Concurrency = concurrency
+		}
 
-			reqPerSec := 0.0
-			if m := reqPerSecPattern.FindStringSubmatch(line); m != nil {
-				reqPerSec, _ = strconv.ParseFloat(m[1], 64)
+		if current == nil || invalid {
+			continue
+		}
+
+		if m := reqPerSecPattern.FindStringSubmatch(line); m != nil {
+			v, ok := parseMetric(m[1])
+			if !ok {
+				reject(lineNo, line, "req/s invalide")
+				continue
 			}
+			current.ReqPerSec = v
+		}
 
-			msPerReq := 0.0
-			if m := msPerReqPattern.FindStringSubmatch(line); m != nil {
-				msPerReq, _ = strconv.ParseFloat(m[1], 64)
+		if m := msPerReqPattern.FindStringSubmatch(line); m != nil {
+			v, ok := parseMetric(m[1])
+			if !ok {
+				reject(lineNo, line, "ms/req invalide")
+				continue
 			}
+			current.MsPerReq = v
+		}
 
-			results = append(results, BenchmarkResult{
-				Name:        serverType,
-				Concurrency: concurrency,
-				ReqPerSec:   reqPerSec,
-				MsPerReq:    msPerReq,
-			})
+		if m := p99Pattern.FindStringSubmatch(line); m != nil {
+			v, ok := parseMetric(m[1])
+			if !ok {
+				reject(lineNo, line, "p99-ms invalide")
+				continue
+			}
+			current.P99Ms = v
 		}
 	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ligne %d: %w", lineNo+1, err)
+	}
+	flush()
 
-	return results
+	return results, nil
+}
+
+// parseMetric convertit la valeur d'une métrique ; seules les valeurs finies
+// et positives sont acceptées.
+func parseMetric(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+		return 0, false
+	}
+	return v, true
 }
 
 func printFormattedResults(results []BenchmarkResult) {
@@ -112,7 +550,7 @@ func printFormattedResults(results []BenchmarkResult) {
 		}
 
 		if badResult.ReqPerSec > 0 && goodResult.ReqPerSec > 0 {
-			improvement := ((goodResult.ReqPerSec - badResult.ReqPerSec) / badResult.ReqPerSec) * 100
+			improvement := percentChange(badResult.ReqPerSec, goodResult.ReqPerSec)
 			improvementColor := ColorGreen
 			if improvement < 0 {
 				improvementColor = ColorRed
@@ -126,8 +564,174 @@ func printFormattedResults(results []BenchmarkResult) {
 		}
 	}
 
+	fmt.Printf("\n%s📈 Moyenne géométrique du throughput vs Bad:%s", Bold, ColorReset)
+	for _, server := range []struct{ name, color string }{{"Good", ColorGreen}, {"SyncMap", ColorPurple}, {"RWMutex", ColorCyan}} {
+		if mean, levels := geometricMeanRatio(results, server.name, "Bad"); levels > 0 {
+			fmt.Printf("  %s%s ×%.2f%s (%d niveaux)", server.color, server.name, mean, ColorReset, levels)
+		} else {
+			fmt.Printf("  %s n/a", server.name)
+		}
+	}
+	fmt.Println()
+	// defer dans une fonction anonyme : un ratio proche de 1 montre que defer n'est pas en cause
+	if mean, levels := geometricMeanRatio(results, "Scoped", "Good"); levels > 0 {
+		fmt.Printf("%s⚖️  Scoped vs Good:%s ×%.2f (%d niveaux)\n", Bold, ColorReset, mean, levels)
+	}
+	// Même handler que le good, copie lourde sous le mutex : un ratio proche de Bad/Good montre l'avance effacée
+	if mean, levels := geometricMeanRatio(results, "BigCopy", "Good"); levels > 0 {
+		fmt.Printf("%s⚖️  BigCopy vs Good:%s ×%.2f (%d niveaux)\n", Bold, ColorReset, mean, levels)
+	}
+	// Lectures sans verrou contre RLock ; à lire avec -readRatio, car chaque écriture copie toute la map
+	if mean, levels := geometricMeanRatio(results, "AtomicValue", "RWMutex"); levels > 0 {
+		fmt.Printf("%s⚖️  AtomicValue vs RWMutex:%s ×%.2f (%d niveaux)\n", Bold, ColorReset, mean, levels)
+	}
+	// Plancher sans map ni verrou : un ratio proche de 1 montre que la synchronisation ne coûte plus rien
+	for _, name := range []string{"Good", "SyncMap"} {
+		if mean, levels := geometricMeanRatio(results, name, "Floor"); levels > 0 {
+			fmt.Printf("%s⚖️  %s vs Floor:%s ×%.2f (%d niveaux)\n", Bold, name, ColorReset, mean, levels)
+		}
+	}
+
 	fmt.Printf("\n%s💡 Interprétation:%s\n", Bold, ColorReset)
 	fmt.Println("• Le serveur GOOD est plus performant sous charge concurrente")
 	fmt.Println("• L'amélioration est plus marquée avec une concurrence élevée")
 	fmt.Println("• Le defer dans le mutex crée un goulot d'étranglement significatif")
-}
\ No newline at end of file
+}
+// geometricMeanRatio calcule la moyenne géométrique des ratios de throughput
+// name/reference sur tous les niveaux de concurrence où les deux serveurs ont
+// des req/s non nuls. Retourne aussi le nombre de niveaux pris en compte.
+func geometricMeanRatio(results []BenchmarkResult, name, reference string) (float64, int) {
+	sumLog := 0.0
+	levels := 0
+	for _, ref := range results {
+		if ref.Name != reference || ref.ReqPerSec <= 0 {
+			continue
+		}
+		for _, r := range results {
+			if r.Name == name && r.Concurrency == ref.Concurrency && r.ReqPerSec > 0 {
+				sumLog += math.Log(r.ReqPerSec / ref.ReqPerSec)
+				levels++
+				break
+			}
+		}
+	}
+	if levels == 0 {
+		return 0, 0
+	}
+	return math.Exp(sumLog / float64(levels)), levels
+}
+
+// Dimensions du graphique SVG, en pixels : la zone de tracé est entourée des
+// marges, la légende occupe la marge de droite
+const (
+	svgWidth        = 760
+	svgHeight       = 420
+	svgMarginLeft   = 70
+	svgMarginRight  = 150
+	svgMarginTop    = 40
+	svgMarginBottom = 50
+	svgYTicks       = 5
+)
+
+// svgColors sont les couleurs des courbes, attribuées aux serveurs dans leur ordre d'apparition
+var svgColors = []string{"#d62728", "#2ca02c", "#9467bd", "#1f77b4", "#ff7f0e", "#17becf", "#8c564b", "#e377c2", "#7f7f7f", "#bcbd22"}
+
+// niceCeil arrondit v au-dessus à 1, 2 ou 5 fois une puissance de 10, pour
+// des graduations lisibles (ex: 721 → 1000, 130 → 200)
+func niceCeil(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, step := range []float64{1, 2, 5, 10} {
+		if v <= step*magnitude {
+			return step * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// renderSVG trace le throughput (req/s) en fonction de la concurrence, une
+// polyligne par serveur, en SVG écrit à la main. L'axe des concurrences est
+// logarithmique entre le plus petit et le plus grand niveau mesuré (1 à 100
+// resteraient sinon tassés à gauche), l'axe des req/s part de 0 jusqu'au
+// maximum arrondi par niceCeil. Les résultats sans req/s sont ignorés.
+func renderSVG(results []BenchmarkResult) string {
+	var servers []string
+	points := map[string][]BenchmarkResult{}
+	levelSet := map[int]bool{}
+	maxReqPerSec := 0.0
+	for _, r := range results {
+		if r.ReqPerSec <= 0 || r.Concurrency <= 0 {
+			continue
+		}
+		if _, seen := points[r.Name]; !seen {
+			servers = append(servers, r.Name)
+		}
+		points[r.Name] = append(points[r.Name], r)
+		levelSet[r.Concurrency] = true
+		maxReqPerSec = math.Max(maxReqPerSec, r.ReqPerSec)
+	}
+	levels := make([]int, 0, len(levelSet))
+	for c := range levelSet {
+		levels = append(levels, c)
+	}
+	sort.Ints(levels)
+
+	plotW := float64(svgWidth - svgMarginLeft - svgMarginRight)
+	plotH := float64(svgHeight - svgMarginTop - svgMarginBottom)
+	yMax := niceCeil(maxReqPerSec)
+	x := func(c int) float64 {
+		if len(levels) < 2 {
+			return svgMarginLeft + plotW/2
+		}
+		low, high := math.Log(float64(levels[0])), math.Log(float64(levels[len(levels)-1]))
+		return svgMarginLeft + (math.Log(float64(c))-low)/(high-low)*plotW
+	}
+	y := func(v float64) float64 {
+		return svgMarginTop + plotH - v/yMax*plotH
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n",
+		svgWidth, svgHeight, svgWidth, svgHeight)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="white"/>`+"\n", svgWidth, svgHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="14" font-weight="bold">Throughput par niveau de concurrence</text>`+"\n", svgMarginLeft, svgMarginTop/2+5)
+
+	// Graduations et grille de l'axe des req/s
+	for i := 0; i <= svgYTicks; i++ {
+		v := yMax * float64(i) / svgYTicks
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#e0e0e0"/>`+"\n", svgMarginLeft, y(v), svgMarginLeft+plotW, y(v))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end">%g</text>`+"\n", svgMarginLeft-8, y(v)+4, v)
+	}
+	// Graduations de l'axe des concurrences, une par niveau mesuré
+	for _, c := range levels {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="black"/>`+"\n", x(c), svgMarginTop+plotH, x(c), svgMarginTop+plotH+5)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">%d</text>`+"\n", x(c), svgMarginTop+plotH+20, c)
+	}
+	fmt.Fprintf(&b, `<path d="M%d %d V%.1f H%.1f" fill="none" stroke="black"/>`+"\n", svgMarginLeft, svgMarginTop, svgMarginTop+plotH, svgMarginLeft+plotW)
+	fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">Concurrence (goroutines, échelle log)</text>`+"\n", svgMarginLeft+plotW/2, svgHeight-10)
+	fmt.Fprintf(&b, `<text transform="translate(18 %.1f) rotate(-90)" text-anchor="middle">req/s</text>`+"\n", svgMarginTop+plotH/2)
+
+	for i, name := range servers {
+		color := svgColors[i%len(svgColors)]
+		series := points[name]
+		sort.Slice(series, func(a, c int) bool { return series[a].Concurrency < series[c].Concurrency })
+		coords := make([]string, len(series))
+		for j, r := range series {
+			coords[j] = fmt.Sprintf("%.1f,%.1f", x(r.Concurrency), y(r.ReqPerSec))
+		}
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`+"\n", strings.Join(coords, " "), color)
+		for _, r := range series {
+			fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>%s, concurrence %d : %.0f req/s</title></circle>`+"\n",
+				x(r.Concurrency), y(r.ReqPerSec), color, html.EscapeString(name), r.Concurrency, r.ReqPerSec)
+		}
+
+		legendY := svgMarginTop + 10 + 20*i
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="%s" stroke-width="2"/>`+"\n",
+			svgMarginLeft+plotW+20, legendY, svgMarginLeft+plotW+40, legendY, color)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d">%s</text>`+"\n", svgMarginLeft+plotW+46, legendY+4, html.EscapeString(name))
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+/*
+TestParseBenchmarkOutput vérifie que le parser extrait les mêmes résultats
+que les métriques soient sur la ligne du benchmark ou sur une ligne de
+continuation.
+
+@fixtures:
+  - testdata/single_line.txt: nom et métriques sur une seule ligne
+  - testdata/split_line.txt: sortie -v avec métriques réparties sur plusieurs lignes
+  - testdata/colored.txt: sortie colorée (codes ANSI autour du nom et des valeurs)
+*/
+func TestParseBenchmarkOutput(t *testing.T) {
+	expected := []BenchmarkResult{
+		{Name: "Bad", Concurrency: 1, ReqPerSec: 88.08, MsPerReq: 11.35},
+		{Name: "Bad", Concurrency: 10, ReqPerSec: 89.00, MsPerReq: 11.24},
+		{Name: "Good", Concurrency: 1, ReqPerSec: 88.47, MsPerReq: 11.30},
+		{Name: "Good", Concurrency: 10, ReqPerSec: 687.0, MsPerReq: 1.455},
+	}
+
+	for _, fixture := range []string{"testdata/single_line.txt", "testdata/split_line.txt", "testdata/colored.txt"} {
+		t.Run(fixture, func(t *testing.T) {
+			f, err := os.Open(fixture)
+			if err != nil {
+				t.Fatalf("Impossible d'ouvrir %s: %v", fixture, err)
+			}
+			defer f.Close()
+
+			results, err := parseBenchmarkOutput(f, io.Discard)
+			if err != nil {
+				t.Fatalf("parseBenchmarkOutput: %v", err)
+			}
+			if len(results) != len(expected) {
+				t.Fatalf("got %d results, want %d: %+v", len(results), len(expected), results)
+			}
+			for i, want := range expected {
+				if results[i] != want {
+					t.Errorf("result %d = %+v, want %+v", i, results[i], want)
+				}
+			}
+		})
+	}
+}
+
+/*
+TestParseBenchmarkOutputDropsCorruptedLines vérifie qu'un benchmark dont une
+valeur ne se parse pas entièrement est ignoré, au lieu d'être inséré avec des
+métriques nulles ou tronquées, et que la raison est journalisée.
+
+@fixtures:
+  - testdata/corrupted.txt: req/s, concurrence et ms/req (ligne de continuation) corrompus
+*/
+func TestParseBenchmarkOutputDropsCorruptedLines(t *testing.T) {
+	f, err := os.Open("testdata/corrupted.txt")
+	if err != nil {
+		t.Fatalf("Impossible d'ouvrir testdata/corrupted.txt: %v", err)
+	}
+	defer f.Close()
+
+	var log strings.Builder
+	results, err := parseBenchmarkOutput(f, &log)
+	if err != nil {
+		t.Fatalf("parseBenchmarkOutput: %v", err)
+	}
+
+	expected := []BenchmarkResult{
+		{Name: "Bad", Concurrency: 1, ReqPerSec: 88.08, MsPerReq: 11.35},
+		{Name: "SyncMap", Concurrency: 10, ReqPerSec: 704.2, MsPerReq: 1.420},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(expected), results)
+	}
+	for i, want := range expected {
+		if results[i] != want {
+			t.Errorf("result %d = %+v, want %+v", i, results[i], want)
+		}
+	}
+
+	for _, reason := range []string{"ligne 5 ignorée (req/s invalide)", "ligne 6 ignorée (concurrence invalide)", "ligne 10 ignorée (ms/req invalide)"} {
+		if !strings.Contains(log.String(), reason) {
+			t.Errorf("log missing %q:\n%s", reason, log.String())
+		}
+	}
+}
+
+/*
+TestParseBenchmarkOutputLongLines vérifie qu'une ligne de plus de 64 Ko, la
+limite par défaut de bufio.Scanner, est analysée en entier, et qu'une ligne
+au-delà de maxLineBytes ou une erreur de lecture est signalée au lieu de
+tronquer les résultats.
+*/
+func TestParseBenchmarkOutputLongLines(t *testing.T) {
+	// Des métriques personnalisées en nombre allongent la ligne, ms/req et req/s en dernier
+	long := "BenchmarkGoodServer_Concurrency10-8  100  " + strings.Repeat("0.5 x/op  ", 10000) + "1.455 ms/req  687.0 req/s\n"
+	if len(long) <= 64*1024 {
+		t.Fatalf("fixture line is %d bytes, want more than 64KB", len(long))
+	}
+	input := "BenchmarkBadServer_Concurrency1-8  100  11.35 ms/req  88.08 req/s\n" + long
+
+	results, err := parseBenchmarkOutput(strings.NewReader(input), io.Discard)
+	if err != nil {
+		t.Fatalf("parseBenchmarkOutput: %v", err)
+	}
+	expected := []BenchmarkResult{
+		{Name: "Bad", Concurrency: 1, ReqPerSec: 88.08, MsPerReq: 11.35},
+		{Name: "Good", Concurrency: 10, ReqPerSec: 687.0, MsPerReq: 1.455},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(expected), results)
+	}
+	for i, want := range expected {
+		if results[i] != want {
+			t.Errorf("result %d = %+v, want %+v", i, results[i], want)
+		}
+	}
+
+	tooLong := strings.NewReader(strings.Repeat("x", maxLineBytes+1))
+	if _, err := parseBenchmarkOutput(tooLong, io.Discard); !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("line over maxLineBytes: err = %v, want bufio.ErrTooLong", err)
+	}
+
+	readErr := errors.New("connection reset")
+	broken := io.MultiReader(strings.NewReader(input), iotest.ErrReader(readErr))
+	if _, err := parseBenchmarkOutput(broken, io.Discard); !errors.Is(err, readErr) {
+		t.Errorf("read error: err = %v, want %v", err, readErr)
+	}
+}
+
+/*
+TestLoadResultsReport vérifie qu'un rapport de TestLatencyComparison (-out)
+se lit comme des résultats de benchmark, pour servir de baseline ou de
+résultats courants, et qu'une version inconnue est refusée.
+
+@fixtures:
+  - testdata/report.json: rapport latency.ComparisonReport version 1
+*/
+func TestLoadResultsReport(t *testing.T) {
+	results, err := loadResults("testdata/report.json")
+	if err != nil {
+		t.Fatalf("loadResults: %v", err)
+	}
+	expected := []BenchmarkResult{
+		{Name: "Bad", Concurrency: 10, ReqPerSec: 88, MsPerReq: 108.5, P99Ms: 113.4},
+		{Name: "Good", Concurrency: 10, ReqPerSec: 604, MsPerReq: 16.3, P99Ms: 24.8},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(expected), results)
+	}
+	for i, want := range expected {
+		if results[i] != want {
+			t.Errorf("result %d = %+v, want %+v", i, results[i], want)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "future.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "results": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadResults(path); err == nil {
+		t.Error("loadResults accepted report version 99, want error")
+	}
+}
+
+/*
+TestParseBenchmarkOutputP99 vérifie que la métrique p99-ms des benchmarks de
+charge est lue, sans la confondre avec ms/req.
+*/
+func TestParseBenchmarkOutputP99(t *testing.T) {
+	output := "BenchmarkGoodServer_Concurrency10-8   	     100	  14550000 ns/op	       687.0 req/s	         1.455 ms/req	        31.20 p99-ms\n"
+	results, err := parseBenchmarkOutput(strings.NewReader(output), io.Discard)
+	if err != nil {
+		t.Fatalf("parseBenchmarkOutput: %v", err)
+	}
+	want := BenchmarkResult{Name: "Good", Concurrency: 10, ReqPerSec: 687.0, MsPerReq: 1.455, P99Ms: 31.20}
+	if len(results) != 1 || results[0] != want {
+		t.Errorf("results = %+v, want [%+v]", results, want)
+	}
+}
+
+/*
+TestGateResults vérifie que seules les baisses de throughput supérieures au
+seuil sont signalées, qu'une hausse du p99 au-delà de son seuil fait échouer
+un benchmark dont le throughput tient, et que le p99 n'est vérifié que s'il
+est mesuré dans la baseline comme dans les résultats courants.
+*/
+func TestGateResults(t *testing.T) {
+	baseline := []BenchmarkResult{
+		{Name: "Good", Concurrency: 10, ReqPerSec: 700, P99Ms: 20},
+		{Name: "Good", Concurrency: 50, ReqPerSec: 400, P99Ms: 120},
+		{Name: "Bad", Concurrency: 10, ReqPerSec: 90},
+		{Name: "Scoped", Concurrency: 10, ReqPerSec: 690, P99Ms: 20},
+	}
+	current := []BenchmarkResult{
+		{Name: "Good", Concurrency: 10, ReqPerSec: 500, P99Ms: 21},
+		{Name: "Good", Concurrency: 50, ReqPerSec: 395, P99Ms: 180},
+		{Name: "Bad", Concurrency: 10, ReqPerSec: 120, P99Ms: 500},
+		{Name: "Bad", Concurrency: 100, ReqPerSec: 10},
+		{Name: "Scoped", Concurrency: 10, ReqPerSec: 700, P99Ms: 22},
+	}
+
+	gate := gateResults(baseline, current, 10, 20)
+	if len(gate) != 4 {
+		t.Fatalf("got %d gate results, want 4 (Bad/100 has no baseline): %+v", len(gate), gate)
+	}
+	for _, c := range []struct {
+		name        string
+		concurrency int
+		checks      int
+		failed      []string
+	}{
+		{"Good", 10, 2, []string{"req/s"}},
+		{"Good", 50, 2, []string{"p99"}}, // throughput stable, p99 +50%
+		{"Bad", 10, 1, nil},              // pas de p99 dans la baseline
+		{"Scoped", 10, 2, nil},           // p99 +10%, sous le seuil
+	} {
+		var g *GateResult
+		for i := range gate {
+			if gate[i].Name == c.name && gate[i].Concurrency == c.concurrency {
+				g = &gate[i]
+			}
+		}
+		if g == nil {
+			t.Errorf("%s/%d missing from gate", c.name, c.concurrency)
+			continue
+		}
+		if len(g.Checks) != c.checks {
+			t.Errorf("%s/%d: %d checks, want %d: %+v", c.name, c.concurrency, len(g.Checks), c.checks, g.Checks)
+		}
+		var failed []string
+		for _, check := range g.Checks {
+			if check.Failed() {
+				failed = append(failed, check.Metric)
+			}
+		}
+		if strings.Join(failed, ",") != strings.Join(c.failed, ",") || g.Failed() != (len(c.failed) > 0) {
+			t.Errorf("%s/%d: failed metrics %v (Failed=%v), want %v", c.name, c.concurrency, failed, g.Failed(), c.failed)
+		}
+	}
+}
+
+/*
+TestDiffResults vérifie que diffResults apparie les résultats par serveur et
+par concurrence, garde ceux présents d'un seul côté, et les trie par nom puis
+par concurrence, et que formatDelta colore l'écart selon le sens de la métrique.
+*/
+func TestDiffResults(t *testing.T) {
+	old := []BenchmarkResult{
+		{Name: "Good", Concurrency: 50, ReqPerSec: 720},
+		{Name: "SyncMap", Concurrency: 10, ReqPerSec: 690},
+		{Name: "Good", Concurrency: 10, ReqPerSec: 650},
+	}
+	cur := []BenchmarkResult{
+		{Name: "Good", Concurrency: 10, ReqPerSec: 700},
+		{Name: "Floor", Concurrency: 10, ReqPerSec: 698},
+		{Name: "Good", Concurrency: 50, ReqPerSec: 720},
+	}
+
+	diffs := diffResults(old, cur)
+	want := []struct {
+		name        string
+		concurrency int
+		old, new    bool
+	}{
+		{"Floor", 10, false, true},
+		{"Good", 10, true, true},
+		{"Good", 50, true, true},
+		{"SyncMap", 10, true, false},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("got %d diffs, want %d: %+v", len(diffs), len(want), diffs)
+	}
+	for i, w := range want {
+		d := diffs[i]
+		if d.Name != w.name || d.Concurrency != w.concurrency || (d.Old != nil) != w.old || (d.New != nil) != w.new {
+			t.Errorf("diff %d = %s/%d (old %v, new %v), want %s/%d (old %v, new %v)",
+				i, d.Name, d.Concurrency, d.Old != nil, d.New != nil, w.name, w.concurrency, w.old, w.new)
+		}
+	}
+	if d := diffs[1]; d.Old.ReqPerSec != 650 || d.New.ReqPerSec != 700 {
+		t.Errorf("Good/10 paired %v → %v req/s, want 650 → 700", d.Old.ReqPerSec, d.New.ReqPerSec)
+	}
+
+	for _, c := range []struct {
+		base, current  float64
+		higherIsBetter bool
+		color          string
+	}{
+		{650, 700, true, ColorGreen},
+		{650, 600, true, ColorRed},
+		{1.5, 1.4, false, ColorGreen},
+		{1.5, 1.6, false, ColorRed},
+		{720, 720, true, ColorReset},
+	} {
+		if got := formatDelta(c.base, c.current, c.higherIsBetter, 0); !strings.HasPrefix(got, c.color) {
+			t.Errorf("formatDelta(%v, %v, %v) = %q, want color %q", c.base, c.current, c.higherIsBetter, got, c.color)
+		}
+	}
+	if got := formatDelta(650, 700, true, 0); !strings.Contains(got, "+50.00 (+7.7%)") {
+		t.Errorf("formatDelta(650, 700) = %q, want +50.00 (+7.7%%)", got)
+	}
+}
+
+/*
+TestGeometricMeanRatio vérifie le calcul de la moyenne géométrique et
+l'exclusion des niveaux où l'un des serveurs a un throughput nul.
+*/
+func TestGeometricMeanRatio(t *testing.T) {
+	results := []BenchmarkResult{
+		{Name: "Bad", Concurrency: 1, ReqPerSec: 100},
+		{Name: "Bad", Concurrency: 10, ReqPerSec: 100},
+		{Name: "Bad", Concurrency: 50, ReqPerSec: 100},
+		{Name: "Good", Concurrency: 1, ReqPerSec: 200},
+		{Name: "Good", Concurrency: 10, ReqPerSec: 800},
+		{Name: "Good", Concurrency: 50, ReqPerSec: 0},
+	}
+
+	mean, levels := geometricMeanRatio(results, "Good", "Bad")
+	if levels != 2 {
+		t.Fatalf("levels = %d, want 2", levels)
+	}
+	if math.Abs(mean-4) > 1e-9 {
+		t.Errorf("mean = %f, want 4", mean)
+	}
+
+	if _, levels := geometricMeanRatio(results, "SyncMap", "Bad"); levels != 0 {
+		t.Errorf("levels = %d for missing server, want 0", levels)
+	}
+}
+
+/*
+TestNiceCeil vérifie l'arrondi des maximums d'axe à 1, 2 ou 5 fois une
+puissance de 10.
+*/
+func TestNiceCeil(t *testing.T) {
+	for _, c := range []struct{ v, want float64 }{{721, 1000}, {130, 200}, {88, 100}, {4.2, 5}, {500, 500}, {0, 1}} {
+		if got := niceCeil(c.v); got != c.want {
+			t.Errorf("niceCeil(%v) = %v, want %v", c.v, got, c.want)
+		}
+	}
+}
+
+/*
+TestRenderSVG vérifie que le graphique est un document XML bien formé, avec
+une polyligne par serveur ayant un point par niveau mesuré, tous dans le
+cadre du dessin, et que les noms de serveur sont échappés.
+*/
+func TestRenderSVG(t *testing.T) {
+	results := []BenchmarkResult{
+		{Name: "Bad", Concurrency: 1, ReqPerSec: 88},
+		{Name: "Bad", Concurrency: 100, ReqPerSec: 86},
+		{Name: "Bad", Concurrency: 10, ReqPerSec: 89},
+		{Name: "Good", Concurrency: 1, ReqPerSec: 90},
+		{Name: "Good", Concurrency: 10, ReqPerSec: 654},
+		{Name: "Good", Concurrency: 100, ReqPerSec: 721},
+		{Name: "A<B", Concurrency: 10, ReqPerSec: 300},
+		{Name: "Empty", Concurrency: 10, MsPerReq: 1},
+	}
+	svg := renderSVG(results)
+
+	var doc struct {
+		Polylines []struct {
+			Points string `xml:"points,attr"`
+		} `xml:"polyline"`
+		Texts []string `xml:"text"`
+	}
+	if err := xml.Unmarshal([]byte(svg), &doc); err != nil {
+		t.Fatalf("invalid SVG: %v\n%s", err, svg)
+	}
+	wantPoints := []int{3, 3, 1} // Bad, Good, A<B ; Empty n'a pas de req/s
+	if len(doc.Polylines) != len(wantPoints) {
+		t.Fatalf("got %d polylines, want %d", len(doc.Polylines), len(wantPoints))
+	}
+	for i, line := range doc.Polylines {
+		points := strings.Fields(line.Points)
+		if len(points) != wantPoints[i] {
+			t.Errorf("polyline %d has %d points, want %d", i, len(points), wantPoints[i])
+		}
+		lastX := -1.0
+		for _, p := range points {
+			var px, py float64
+			if _, err := fmt.Sscanf(p, "%f,%f", &px, &py); err != nil {
+				t.Fatalf("point %q: %v", p, err)
+			}
+			if px < svgMarginLeft || px > svgWidth-svgMarginRight || py < svgMarginTop || py > svgHeight-svgMarginBottom {
+				t.Errorf("point %q outside the plot area", p)
+			}
+			if px <= lastX {
+				t.Errorf("polyline %d not ordered by concurrency: %s", i, line.Points)
+			}
+			lastX = px
+		}
+	}
+	texts := strings.Join(doc.Texts, "|")
+	for _, want := range []string{"|1000|", "A<B"} {
+		if !strings.Contains(texts, want) {
+			t.Errorf("texts %q lack %q", texts, want)
+		}
+	}
+}
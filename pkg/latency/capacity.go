@@ -0,0 +1,152 @@
+package latency
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// maxSearchConcurrency borne la recherche de FindMaxConcurrency : au-delà, la mesure coûte plus qu'elle n'apprend
+const maxSearchConcurrency = 512
+
+// maxSearchSteps borne le nombre de mesures d'une recherche, dichotomie de [1, maxSearchConcurrency] comprise
+const maxSearchSteps = 12
+
+// capacityRequests est le nombre minimum de requêtes d'une mesure de FindMaxConcurrency
+const capacityRequests = 100
+
+/*
+CapacityResult est le résultat d'une recherche de concurrence maximum.
+
+@fields:
+  - MaxConcurrency: Plus haut niveau mesuré dont le p99 reste sous la cible (0 = aucun)
+  - P99Ms: p99 mesuré à ce niveau
+  - Probes: Nombre de mesures effectuées
+  - Capped: La borne haute de recherche a été atteinte sans dépasser la cible
+*/
+type CapacityResult struct {
+	MaxConcurrency int
+	P99Ms          float64
+	Probes         int
+	Capped         bool
+}
+
+/*
+FindMaxConcurrency cherche la plus haute concurrence que le serveur url
+soutient avec un p99 sous p99Target : le nombre unique de capacité d'un
+serveur. La recherche est dichotomique sur [1, maxSearchConcurrency], en au
+plus maxSearchSteps mesures d'au moins capacityRequests requêtes chacune.
+
+@params:
+  - url: string URL du serveur à mesurer
+  - p99Target: time.Duration p99 maximum accepté (ex: 100ms)
+
+@returns: int - Concurrence maximum sûre, 0 si une seule requête en vol dépasse déjà la cible
+*/
+func FindMaxConcurrency(url string, p99Target time.Duration) int {
+	return FindCapacity(context.Background(), url, p99Target).MaxConcurrency
+}
+
+/*
+FindCapacity est FindMaxConcurrency avec un contexte et le détail de la recherche.
+
+@params:
+  - ctx: context.Context annule les mesures en cours
+  - url: string URL du serveur à mesurer
+  - p99Target: time.Duration p99 maximum accepté
+
+@returns: CapacityResult - Résultat de la recherche
+*/
+func FindCapacity(ctx context.Context, url string, p99Target time.Duration) CapacityResult {
+	return searchCapacity(func(concurrency int) Stats {
+		return MeasureContext(ctx, url, concurrency, max(capacityRequests, concurrency), 0)
+	}, p99Target, maxSearchConcurrency, maxSearchSteps)
+}
+
+/*
+searchCapacity est la recherche de FindCapacity sur une fonction de mesure
+quelconque. Un niveau est soutenu si toutes ses requêtes réussissent avec un
+p99 sous target. La charge est supposée monotone : si un niveau est soutenu,
+les niveaux inférieurs le sont aussi.
+
+@params:
+  - measure: func(int) Stats mesure à un niveau de concurrence
+  - target: time.Duration p99 maximum accepté
+  - hi: int borne haute de la recherche
+  - steps: int nombre maximum de mesures
+
+@returns: CapacityResult - Plus haut niveau soutenu trouvé ; si les mesures
+s'épuisent avant la fin de la dichotomie, le meilleur niveau déjà vérifié
+*/
+func searchCapacity(measure func(int) Stats, target time.Duration, hi, steps int) CapacityResult {
+	var result CapacityResult
+	targetMs := float64(target) / float64(time.Millisecond)
+	sustained := func(concurrency int) bool {
+		result.Probes++
+		stats := measure(concurrency)
+		ok := stats.Success == max(capacityRequests, concurrency) && stats.P99Ms <= targetMs
+		if ok {
+			result.MaxConcurrency, result.P99Ms = concurrency, stats.P99Ms
+		}
+		return ok
+	}
+
+	if steps <= 0 || !sustained(1) {
+		return result
+	}
+	lo := 1
+	if result.Probes < steps && sustained(hi) {
+		result.Capped = true
+		return result
+	}
+	hi--
+	// Invariant : lo est soutenu, hi+1 ne l'est pas
+	for lo < hi && result.Probes < steps {
+		mid := (lo + hi + 1) / 2
+		if sustained(mid) {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	result.MaxConcurrency = lo
+	return result
+}
+
+/*
+WriteCapacity écrit la ligne de capacité d'un serveur.
+
+@params:
+  - w: io.Writer destination de la ligne
+  - name: string nom du serveur
+  - result: CapacityResult résultat de FindCapacity
+  - target: time.Duration cible de p99 de la recherche
+*/
+func WriteCapacity(w io.Writer, name string, result CapacityResult, target time.Duration) {
+	if result.MaxConcurrency == 0 {
+		fmt.Fprintf(w, "%-20s %saucune%s : p99 > %s dès 1 requête en vol\n", name, ColorRed, ColorReset, target)
+		return
+	}
+	capped := ""
+	if result.Capped {
+		capped = " (borne de recherche atteinte)"
+	}
+	fmt.Fprintf(w, "%-20s %s%4d%s en vol  p99 %.1f ms ≤ %s  %d mesures%s\n",
+		name, Bold, result.MaxConcurrency, ColorReset, result.P99Ms, target, result.Probes, capped)
+}
+
+/*
+CompareCapacity cherche la concurrence maximum sûre de chaque serveur l'un
+après l'autre et écrit une ligne par serveur.
+
+@params:
+  - w: io.Writer destination des lignes
+  - servers: []Server serveurs à mesurer, une ligne chacun
+  - target: time.Duration p99 maximum accepté
+*/
+func CompareCapacity(w io.Writer, servers []Server, target time.Duration) {
+	for _, srv := range servers {
+		WriteCapacity(w, srv.Name, FindCapacity(context.Background(), srv.URL, target), target)
+	}
+}
@@ -0,0 +1,105 @@
+package latency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+queueMeasure simule un serveur qui sérialise ses requêtes : à concurrency en
+vol, la dernière attend concurrency fois hold, d'où un p99 linéaire.
+*/
+func queueMeasure(hold time.Duration, probed *[]int) func(int) Stats {
+	return func(concurrency int) Stats {
+		*probed = append(*probed, concurrency)
+		return Stats{
+			Success: max(capacityRequests, concurrency),
+			P99Ms:   float64(concurrency) * float64(hold) / float64(time.Millisecond),
+		}
+	}
+}
+
+/*
+TestSearchCapacity vérifie la dichotomie : le plus haut niveau sous la cible
+est trouvé en au plus maxSearchSteps mesures, un serveur hors cible dès 1
+requête donne 0, un serveur qui tient la borne haute s'arrête là, et des
+échecs rendent un niveau insoutenable même avec un p99 bas.
+*/
+func TestSearchCapacity(t *testing.T) {
+	tests := []struct {
+		name    string
+		hold    time.Duration
+		target  time.Duration
+		want    int
+		capped  bool
+		maxProb int
+	}{
+		{"bad-like", 11 * time.Millisecond, 100 * time.Millisecond, 9, false, maxSearchSteps},
+		{"good-like", time.Millisecond, 100 * time.Millisecond, 100, false, maxSearchSteps},
+		{"exact boundary", 10 * time.Millisecond, 100 * time.Millisecond, 10, false, maxSearchSteps},
+		{"never", 200 * time.Millisecond, 100 * time.Millisecond, 0, false, 1},
+		{"capped", time.Microsecond, 100 * time.Millisecond, maxSearchConcurrency, true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var probed []int
+			got := searchCapacity(queueMeasure(tt.hold, &probed), tt.target, maxSearchConcurrency, maxSearchSteps)
+			if got.MaxConcurrency != tt.want || got.Capped != tt.capped {
+				t.Errorf("got %d (capped %v), want %d (capped %v); probed %v",
+					got.MaxConcurrency, got.Capped, tt.want, tt.capped, probed)
+			}
+			if got.Probes != len(probed) || got.Probes > tt.maxProb {
+				t.Errorf("probes = %d (%v), want at most %d", got.Probes, probed, tt.maxProb)
+			}
+			for _, c := range probed {
+				if c < 1 || c > maxSearchConcurrency {
+					t.Errorf("probed concurrency %d outside [1, %d]", c, maxSearchConcurrency)
+				}
+			}
+		})
+	}
+
+	failing := func(concurrency int) Stats {
+		stats := Stats{Success: max(capacityRequests, concurrency), P99Ms: 1}
+		if concurrency > 20 {
+			stats.Success--
+			stats.Rejected = 1
+		}
+		return stats
+	}
+	if got := searchCapacity(failing, 100*time.Millisecond, maxSearchConcurrency, maxSearchSteps); got.MaxConcurrency != 20 {
+		t.Errorf("with rejections above 20: got %d, want 20", got.MaxConcurrency)
+	}
+
+	var probed []int
+	got := searchCapacity(queueMeasure(time.Millisecond, &probed), 100*time.Millisecond, maxSearchConcurrency, 4)
+	if len(probed) != 4 || got.MaxConcurrency < 1 || got.MaxConcurrency > 100 {
+		t.Errorf("with 4 steps: got %d after %v, want a verified level at most 100 after 4 probes", got.MaxConcurrency, probed)
+	}
+}
+
+/*
+TestFindMaxConcurrency mesure un vrai serveur qui sérialise ses requêtes
+sous un mutex pendant 1ms : avec une cible de 10ms, la capacité trouvée doit
+rester de l'ordre de la dizaine.
+*/
+func TestFindMaxConcurrency(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mesure réelle, ignorée avec -short")
+	}
+	var mu sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		time.Sleep(time.Millisecond)
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	got := FindMaxConcurrency(ts.URL, 10*time.Millisecond)
+	if got < 1 || got > 40 {
+		t.Errorf("FindMaxConcurrency = %d, want between 1 and 40 for a 1ms serialized handler under a 10ms p99", got)
+	}
+}
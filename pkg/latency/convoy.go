@@ -0,0 +1,112 @@
+package latency
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// DefaultConvoyTolerance est l'écart relatif toléré entre la latence mesurée et celle d'un convoi parfait
+const DefaultConvoyTolerance = 0.25
+
+/*
+ConvoyDiagnosis est le diagnostic de convoi d'un serveur à un niveau de
+concurrence.
+
+Dans un convoi, les requêtes passent une à une dans une section critique de
+durée fixe : chacune attend que toutes celles qui la précèdent en file aient
+fini. Avec concurrency requêtes en vol, la latence d'une requête vaut alors
+la durée de détention du verrou multipliée par sa position dans la file,
+quel que soit le travail fait hors du verrou : concurrency en régime établi,
+1 à concurrency pour la première vague, partie d'un coup (voir queueDepth).
+
+@fields:
+  - Server: Nom du serveur
+  - Concurrency: Nombre de requêtes en vol
+  - MeanMs: Latence moyenne mesurée
+  - HoldMs: Durée moyenne de détention du verrou (avg_lock_hold_us de /stats)
+  - Depth: Position moyenne dans la file d'un convoi parfait (voir queueDepth)
+  - ExpectedMs: Latence moyenne d'un convoi parfait, HoldMs × Depth
+  - Ratio: MeanMs / ExpectedMs (1 = convoi parfait)
+  - Detected: Ratio à moins de la tolérance de 1, à partir de 2 requêtes en vol
+*/
+type ConvoyDiagnosis struct {
+	Server      string
+	Concurrency int
+	MeanMs      float64
+	HoldMs      float64
+	Depth       float64
+	ExpectedMs  float64
+	Ratio       float64
+	Detected    bool
+}
+
+/*
+queueDepth retourne la position moyenne des requêtes dans la file d'un
+convoi parfait, pour une mesure en boucle fermée de total requêtes avec
+concurrency en vol. Les concurrency premières partent ensemble et occupent
+les positions 1 à concurrency ; chacune des suivantes part quand une
+requête sort et trouve concurrency-1 requêtes devant elle.
+
+@params:
+  - concurrency: int nombre de requêtes en vol
+  - total: int nombre total de requêtes (au moins concurrency, voir Measure)
+
+@returns: float64 - Position moyenne, de (concurrency+1)/2 à concurrency
+*/
+func queueDepth(concurrency, total int) float64 {
+	c, n := float64(concurrency), float64(max(total, concurrency))
+	return (c*(c+1)/2 + (n-c)*c) / n
+}
+
+/*
+DiagnoseConvoy compare une latence moyenne à celle d'un convoi sur un verrou
+tenu holdMs par requête.
+
+@params:
+  - server: string nom du serveur
+  - concurrency: int nombre de requêtes en vol pendant la mesure
+  - total: int nombre de requêtes de la mesure
+  - meanMs: float64 latence moyenne mesurée (Stats.AvgMs)
+  - holdMs: float64 durée moyenne de détention du verrou
+  - tolerance: float64 écart relatif toléré entre Ratio et 1 (ex: 0.25)
+
+@returns: ConvoyDiagnosis - Diagnostic ; jamais détecté sans mesure, sans
+verrou ou avec une seule requête en vol, où rien ne peut faire la queue
+*/
+func DiagnoseConvoy(server string, concurrency, total int, meanMs, holdMs, tolerance float64) ConvoyDiagnosis {
+	d := ConvoyDiagnosis{Server: server, Concurrency: concurrency, MeanMs: meanMs, HoldMs: holdMs}
+	if concurrency < 2 || meanMs <= 0 || holdMs <= 0 {
+		return d
+	}
+	d.Depth = queueDepth(concurrency, total)
+	d.ExpectedMs = holdMs * d.Depth
+	d.Ratio = meanMs / d.ExpectedMs
+	d.Detected = math.Abs(d.Ratio-1) <= tolerance
+	return d
+}
+
+/*
+WriteConvoy écrit une ligne par diagnostic, avec "CONVOY DETECTED" quand
+la latence suit la file du verrou.
+
+@params:
+  - w: io.Writer destination des lignes
+  - diagnoses: []ConvoyDiagnosis diagnostics à écrire (ceux sans mesure sont ignorés)
+  - tolerance: float64 tolérance utilisée, rappelée dans le titre
+*/
+func WriteConvoy(w io.Writer, diagnoses []ConvoyDiagnosis, tolerance float64) {
+	fmt.Fprintf(w, "\n%s%sDiagnostic de convoi (latence ≈ détention du verrou × position en file, à ±%.0f%%):%s\n",
+		Bold, ColorBlue, tolerance*100, ColorReset)
+	for _, d := range diagnoses {
+		if d.ExpectedMs == 0 {
+			continue
+		}
+		verdict := fmt.Sprintf("%spas de convoi%s", ColorGreen, ColorReset)
+		if d.Detected {
+			verdict = fmt.Sprintf("%s%sCONVOY DETECTED%s : les requêtes traversent le verrou une à une", Bold, ColorRed, ColorReset)
+		}
+		fmt.Fprintf(w, "• %-10s c=%-4d %8.2f ms mesurés, %8.2f ms attendus (%.3f ms × %.1f en file), ratio %.2f  %s\n",
+			d.Server, d.Concurrency, d.MeanMs, d.ExpectedMs, d.HoldMs, d.Depth, d.Ratio, verdict)
+	}
+}
@@ -0,0 +1,72 @@
+package latency
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+/*
+TestDiagnoseConvoy vérifie la détection sur des mesures typiques : le
+serveur bad à 10 requêtes en vol sur 100 (9,55 × 11,4 ms de verrou pour
+111 ms de latence) est un convoi, le good (verrou de quelques µs) ne l'est
+pas, et une seule requête en vol ne peut pas former de file.
+*/
+func TestDiagnoseConvoy(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		concurrency int
+		meanMs      float64
+		holdMs      float64
+		tolerance   float64
+		want        bool
+	}{
+		{"bad", 10, 111.3, 11.4, 0.25, true},
+		{"bad-loose", 10, 70, 11.4, 0.25, false},
+		{"bad-loose-tolerant", 10, 70, 11.4, 0.5, true},
+		{"good", 10, 17.2, 0.025, 0.25, false},
+		{"single", 1, 11, 11, 0.25, false},
+		{"no-hold", 10, 111.3, 0, 0.25, false},
+	} {
+		d := DiagnoseConvoy(c.name, c.concurrency, 100, c.meanMs, c.holdMs, c.tolerance)
+		if d.Detected != c.want {
+			t.Errorf("%s: Detected = %v (ratio %.2f), want %v", c.name, d.Detected, d.Ratio, c.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	WriteConvoy(&buf, []ConvoyDiagnosis{
+		DiagnoseConvoy("Bad", 10, 100, 111.3, 11.4, 0.25),
+		DiagnoseConvoy("Good", 10, 100, 17.2, 0.025, 0.25),
+		DiagnoseConvoy("Bad", 1, 100, 11, 11, 0.25),
+	}, 0.25)
+	out := buf.String()
+	if strings.Count(out, "CONVOY DETECTED") != 1 || strings.Count(out, "pas de convoi") != 1 {
+		t.Errorf("unexpected convoy report:\n%s", out)
+	}
+	if strings.Contains(out, "c=1 ") {
+		t.Errorf("single-request level should be omitted:\n%s", out)
+	}
+}
+
+/*
+TestQueueDepth vérifie la position moyenne en file : (c+1)/2 quand toutes
+les requêtes partent dans la première vague, vers c en régime établi.
+*/
+func TestQueueDepth(t *testing.T) {
+	for _, c := range []struct {
+		concurrency, total int
+		want               float64
+	}{
+		{10, 100, 9.55},
+		{50, 100, 37.75},
+		{100, 100, 50.5},
+		{100, 10, 50.5}, // Measure envoie au moins une requête par worker
+		{1, 100, 1},
+	} {
+		if got := queueDepth(c.concurrency, c.total); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("queueDepth(%d, %d) = %v, want %v", c.concurrency, c.total, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,477 @@
+package latency
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+Stats regroupe le résultat d'une mesure de latence.
+
+@fields:
+  - AvgMs: Latence moyenne des requêtes réussies (ms)
+  - P95Ms, P99Ms: 95e et 99e percentiles de latence des requêtes réussies (ms)
+  - AvgTTFBMs: Temps moyen jusqu'au premier octet de réponse (ms)
+  - Success: Nombre de requêtes ayant reçu un 200
+  - Rejected: Nombre de requêtes rejetées par le serveur (503 backpressure, 429 fail-fast)
+  - Failed: Nombre de requêtes en erreur de transport ou d'un autre code HTTP ;
+    Success + Rejected + Failed vaut toujours le nombre de requêtes demandé
+  - TTFBSamples: Nombre de requêtes pour lesquelles le premier octet a été observé
+  - Elapsed: Durée totale de la mesure (horloge murale)
+*/
+type Stats struct {
+	AvgMs       float64
+	P95Ms       float64
+	P99Ms       float64
+	AvgTTFBMs   float64
+	Success     int
+	Rejected    int
+	Failed      int
+	TTFBSamples int
+	Elapsed     time.Duration
+}
+
+/*
+ReqPerSec calcule le throughput : requêtes réussies par seconde de mesure.
+
+@returns: float64 req/s, 0 si rien n'a été mesuré
+*/
+func (s Stats) ReqPerSec() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Success) / s.Elapsed.Seconds()
+}
+
+/*
+requestTiming contient les mesures d'une requête réussie.
+ttfb vaut 0 si le callback GotFirstResponseByte n'a pas été appelé.
+*/
+type requestTiming struct {
+	total time.Duration
+	ttfb  time.Duration
+}
+
+/*
+requestResult est le résultat d'une requête de Measure, rangé à son indice.
+
+@fields:
+  - timing: Mesures de la requête (valides si status vaut 200)
+  - status: Code HTTP reçu, 0 en cas d'erreur de transport
+*/
+type requestResult struct {
+	timing requestTiming
+	status int
+}
+
+/*
+MeasureAverage calcule la latence moyenne pour un serveur donné.
+
+@params:
+  - url: string URL du serveur à mesurer
+  - concurrency: int nombre de clients concurrents
+  - totalRequests: int nombre total de requêtes à effectuer
+
+@returns: float64 latence moyenne en millisecondes
+
+@behavior:
+  - Distribue les requêtes équitablement entre les goroutines
+  - Mesure le temps de chaque requête individuellement
+  - Calcule la moyenne sur toutes les requêtes réussies
+*/
+func MeasureAverage(url string, concurrency int, totalRequests int) float64 {
+	return Measure(url, concurrency, totalRequests, 0).AvgMs
+}
+
+/*
+Measure mesure la latence d'un serveur et compte les rejets.
+Seules les réponses 200 entrent dans la moyenne : un rejet rapide (503)
+ferait sinon baisser artificiellement la latence mesurée.
+
+La concurrence est celle des requêtes en vol, pas un nombre de boucles :
+concurrency workers fixes tirent les requêtes d'un canal, et partagent un
+transport limité à concurrency connexions, gardées ouvertes entre requêtes.
+Le client n'ouvre donc jamais plus de sockets que de requêtes en vol, et
+exactement totalRequests requêtes sont envoyées. Chaque worker range le
+résultat de la requête j à l'indice j d'un tableau préalloué : les compteurs
+ne dépendent ni de la répartition entre workers ni du nombre de réussites.
+
+Avec rate = 0, la latence couvre l'envoi jusqu'à la fin de la lecture ; un
+serveur lent ralentit alors la cadence d'envoi et la moyenne sous-estime
+la latence réellement subie (coordinated omission). Avec rate = R, chaque
+requête a un instant d'envoi prévu (cadence globale R req/s) et sa latence
+est comptée depuis cet instant : une requête bloquée derrière le mutex du
+serveur "bad" compte toute son attente en file.
+
+@params:
+  - url: string URL du serveur à mesurer
+  - concurrency: int nombre maximum de requêtes en vol
+  - totalRequests: int nombre total de requêtes à effectuer
+  - rate: float64 cadence prévue en req/s (0 = envoi au plus tôt)
+
+@returns: Stats latence moyenne, succès, rejets, échecs et durée totale
+*/
+func Measure(url string, concurrency int, totalRequests int, rate float64) Stats {
+	return MeasureContext(context.Background(), url, concurrency, totalRequests, rate)
+}
+
+/*
+MeasureContext est Measure interrompue par l'annulation de ctx : les
+requêtes en vol sont abandonnées, aucune autre n'est envoyée, et les
+workers sont arrêtés avant le retour. Un serveur bloqué n'impose donc pas
+d'attendre le timeout du client pour chacune des requêtes restantes.
+
+@params:
+  - ctx: context.Context contexte de la mesure (ex: dérivé de l'échéance du test)
+  - url: string URL du serveur à mesurer
+  - concurrency: int nombre maximum de requêtes en vol
+  - totalRequests: int nombre total de requêtes à effectuer
+  - rate: float64 cadence prévue en req/s (0 = envoi au plus tôt)
+
+@returns: Stats mesure partielle si ctx est annulé : les requêtes abandonnées
+ou jamais envoyées comptent comme échecs (ctx.Err() dit si la mesure est complète)
+*/
+func MeasureContext(ctx context.Context, url string, concurrency int, totalRequests int, rate float64) Stats {
+	return measure(ctx, func() (string, func()) { return url, nil }, concurrency, totalRequests, rate)
+}
+
+/*
+measure est le cœur de Measure : l'URL de chaque requête est tirée de
+next, appelée par les workers de façon concurrente. Les résultats sont
+rangés dans un tableau de totalRequests cases, agrégé une fois la mesure
+terminée (voir measureAggregated pour l'alternative en flux).
+
+@params:
+  - ctx: context.Context contexte de la mesure (voir MeasureContext)
+  - next: func() (string, func()) URL de la prochaine requête (sûre en
+    concurrence), et fonction appelée une fois la réponse lue (nil = aucune)
+  - concurrency: int nombre maximum de requêtes en vol
+  - totalRequests: int nombre total de requêtes à effectuer
+  - rate: float64 cadence prévue en req/s (0 = envoi au plus tôt)
+
+@returns: Stats latence moyenne, succès, rejets, échecs et durée totale
+*/
+func measure(ctx context.Context, next func() (string, func()), concurrency int, totalRequests int, rate float64) Stats {
+	results := make([]requestResult, totalRequests)
+	elapsed := runRequests(ctx, next, concurrency, totalRequests, rate, func(j int, result requestResult) {
+		results[j] = result
+	})
+
+	acc := newStatsAccumulator(totalRequests)
+	for _, result := range results {
+		acc.add(result)
+	}
+	return acc.stats(elapsed)
+}
+
+/*
+measureAggregated mesure comme measure, sans tableau de totalRequests
+résultats : les workers envoient chaque résultat dans un canal de
+concurrency places, lu pendant la mesure par une goroutine d'agrégation.
+Seules les latences des réussites sont gardées, pour les percentiles.
+
+BenchmarkMeasureResults compare les deux formes : une case du tableau fait
+24 octets, invisible devant les ~6,8 Ko que le client HTTP alloue par
+requête (écart sous le bruit, à 1 000 comme à 10 000 requêtes), et le canal
+ajoute un passage de goroutine par requête, sans gain mesurable. measure
+reste donc la forme de Measure ; celle-ci sert de référence pour le vérifier.
+
+@params:
+  - ctx: context.Context contexte de la mesure (voir MeasureContext)
+  - next: func() (string, func()) URL de la prochaine requête et fonction de fin (voir measure)
+  - concurrency: int nombre maximum de requêtes en vol
+  - totalRequests: int nombre total de requêtes à effectuer
+  - rate: float64 cadence prévue en req/s (0 = envoi au plus tôt)
+
+@returns: Stats identiques à celles de measure pour les mêmes réponses
+*/
+func measureAggregated(ctx context.Context, next func() (string, func()), concurrency int, totalRequests int, rate float64) Stats {
+	results := make(chan requestResult, concurrency)
+	aggregated := make(chan *statsAccumulator)
+	go func() {
+		acc := newStatsAccumulator(0)
+		for result := range results {
+			acc.add(result)
+		}
+		aggregated <- acc
+	}()
+
+	elapsed := runRequests(ctx, next, concurrency, totalRequests, rate, func(_ int, result requestResult) {
+		results <- result
+	})
+	close(results)
+	return (<-aggregated).stats(elapsed)
+}
+
+/*
+runRequests envoie exactement totalRequests requêtes avec concurrency
+workers fixes, qui tirent les indices d'un canal et partagent un transport
+limité à concurrency connexions (voir Measure), et passe chaque résultat à
+record. À l'annulation de ctx, les requêtes en vol échouent, les workers
+s'arrêtent, et les requêtes jamais envoyées sont passées à record comme
+échecs : record est toujours appelée totalRequests fois.
+
+@params:
+  - ctx: context.Context contexte de la mesure (voir MeasureContext)
+  - next: func() (string, func()) URL de la prochaine requête et fonction de fin (voir measure)
+  - concurrency: int nombre maximum de requêtes en vol
+  - totalRequests: int nombre total de requêtes à effectuer
+  - rate: float64 cadence prévue en req/s (0 = envoi au plus tôt)
+  - record: func(j int, result requestResult) appelée une fois par requête,
+    depuis les workers de façon concurrente ; status vaut 0 en cas d'erreur de transport
+
+@returns: time.Duration - Durée totale de la mesure (horloge murale)
+*/
+func runRequests(ctx context.Context, next func() (string, func()), concurrency int, totalRequests int, rate float64, record func(j int, result requestResult)) time.Duration {
+	var wg sync.WaitGroup
+
+	client, closeIdle := limitedClient(concurrency)
+	defer closeIdle()
+
+	// Avec une cadence, la requête j est prévue à start + j × 1/rate ; sinon
+	// l'instant prévu reste nul et la requête part dès qu'un worker est libre
+	var interval time.Duration
+	if rate > 0 {
+		interval = time.Duration(float64(time.Second) / rate)
+	}
+	jobs := make(chan int)
+	start := time.Now()
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				scheduled := start.Add(time.Duration(j) * interval)
+				if wait := time.Until(scheduled); interval > 0 && wait > 0 {
+					timer := time.NewTimer(wait)
+					select {
+					case <-timer.C:
+					case <-ctx.Done():
+						timer.Stop()
+					}
+				}
+				if ctx.Err() != nil {
+					record(j, requestResult{})
+					continue
+				}
+
+				url, done := next()
+				timing, status, err := timedGet(ctx, client, url)
+				if done != nil {
+					done()
+				}
+				if err != nil {
+					// Une erreur de transport est un résultat de status 0 (échec)
+					record(j, requestResult{})
+					continue
+				}
+				// Une requête partie en retard compte son attente depuis l'envoi prévu
+				if interval > 0 {
+					timing.total = time.Since(scheduled)
+				}
+				record(j, requestResult{timing: timing, status: status})
+			}
+		}()
+	}
+
+	sent := 0
+feed:
+	for ; sent < totalRequests; sent++ {
+		select {
+		case jobs <- sent:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+
+	wg.Wait()
+	for j := sent; j < totalRequests; j++ {
+		record(j, requestResult{})
+	}
+	return time.Since(start)
+}
+
+/*
+statsAccumulator agrège des résultats de requêtes en Stats, dans n'importe
+quel ordre : les sommes sont des entiers et les latences sont triées avant
+le calcul des percentiles.
+
+@fields:
+  - counts: Compteurs de succès, rejets, échecs et TTFB observés
+  - totalLatency, totalTTFB: Sommes des latences et des TTFB des réussites
+  - totals: Latences des réussites, pour les percentiles
+*/
+type statsAccumulator struct {
+	counts                  Stats
+	totalLatency, totalTTFB time.Duration
+	totals                  []time.Duration
+}
+
+/*
+newStatsAccumulator crée un accumulateur vide.
+
+@params:
+  - capacity: int capacité initiale des latences (nombre de réussites attendu au plus)
+
+@returns: *statsAccumulator - Accumulateur prêt à recevoir des résultats
+*/
+func newStatsAccumulator(capacity int) *statsAccumulator {
+	return &statsAccumulator{totals: make([]time.Duration, 0, capacity)}
+}
+
+/*
+add compte un résultat : seules les réponses 200 entrent dans les latences.
+
+@params:
+  - result: requestResult résultat d'une requête
+*/
+func (a *statsAccumulator) add(result requestResult) {
+	switch result.status {
+	case http.StatusOK:
+		a.counts.Success++
+	case http.StatusServiceUnavailable, http.StatusTooManyRequests:
+		a.counts.Rejected++
+		return
+	default:
+		a.counts.Failed++
+		return
+	}
+	a.totalLatency += result.timing.total
+	a.totals = append(a.totals, result.timing.total)
+	if result.timing.ttfb > 0 {
+		a.totalTTFB += result.timing.ttfb
+		a.counts.TTFBSamples++
+	}
+}
+
+/*
+stats calcule les moyennes et les percentiles des résultats ajoutés.
+
+@params:
+  - elapsed: time.Duration durée totale de la mesure
+
+@returns: Stats résultat de la mesure
+*/
+func (a *statsAccumulator) stats(elapsed time.Duration) Stats {
+	stats := a.counts
+	stats.Elapsed = elapsed
+	if stats.Success > 0 {
+		stats.AvgMs = float64(a.totalLatency.Milliseconds()) / float64(stats.Success)
+		sort.Slice(a.totals, func(i, j int) bool { return a.totals[i] < a.totals[j] })
+		stats.P95Ms = percentileMs(a.totals, 95)
+		stats.P99Ms = percentileMs(a.totals, 99)
+	}
+	if stats.TTFBSamples > 0 {
+		stats.AvgTTFBMs = float64(a.totalTTFB) / float64(stats.TTFBSamples) / float64(time.Millisecond)
+	}
+	return stats
+}
+
+/*
+percentileMs retourne le percentile p d'une série triée, par la méthode du
+rang le plus proche : la plus petite valeur dont au moins p % des
+échantillons sont inférieurs ou égaux.
+
+@params:
+  - sorted: []time.Duration latences triées par ordre croissant (non vide)
+  - p: float64 percentile entre 0 et 100
+
+@returns: float64 percentile en millisecondes
+*/
+func percentileMs(sorted []time.Duration, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return float64(sorted[rank-1]) / float64(time.Millisecond)
+}
+
+/*
+Percentile trie latencies et retourne leur percentile p (voir percentileMs),
+pour les mesures faites hors de Measure, comme les benchmarks de charge.
+
+@params:
+  - latencies: []time.Duration latences dans n'importe quel ordre, triées sur place
+  - p: float64 percentile entre 0 et 100
+
+@returns: float64 percentile en millisecondes, 0 pour une série vide
+*/
+func Percentile(latencies []time.Duration, p float64) float64 {
+	if len(latencies) == 0 {
+		return 0
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return percentileMs(latencies, p)
+}
+
+/*
+limitedClient crée le client partagé par les workers de Measure. Le
+transport part d'un clone de http.DefaultTransport (une socket Unix
+configurée par les tests est conservée) et garde jusqu'à concurrency
+connexions ouvertes, sans jamais en ouvrir davantage.
+
+@params:
+  - concurrency: int nombre maximum de requêtes en vol
+
+@returns: (*http.Client, func()) - Client, et fermeture des connexions inactives
+*/
+func limitedClient(concurrency int) (*http.Client, func()) {
+	transport := &http.Transport{}
+	if base, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport = base.Clone()
+	}
+	transport.MaxConnsPerHost = concurrency
+	transport.MaxIdleConnsPerHost = concurrency
+	if transport.MaxIdleConns < concurrency {
+		transport.MaxIdleConns = concurrency
+	}
+	return &http.Client{Timeout: 30 * time.Second, Transport: transport}, transport.CloseIdleConnections
+}
+
+/*
+timedGet effectue une requête GET et mesure sa durée totale ainsi que son
+TTFB (time-to-first-byte) via httptrace. Le TTFB approxime le temps de
+traitement côté serveur, indépendamment de la lecture du corps.
+
+@params:
+  - ctx: context.Context annule la requête, lecture du corps comprise
+  - client: *http.Client client HTTP à utiliser
+  - url: string URL à interroger
+
+@returns: (requestTiming, int, error) - Mesures, code HTTP et erreur éventuelle
+*/
+func timedGet(ctx context.Context, client *http.Client, url string) (requestTiming, int, error) {
+	var timing requestTiming
+	var firstByte time.Time
+	trace := &httptrace.ClientTrace{
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, url, nil)
+	if err != nil {
+		return timing, 0, err
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return timing, 0, err
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+	timing.total = time.Since(start)
+
+	// Le callback peut ne jamais être appelé (erreur de transport) : ttfb reste à 0
+	if !firstByte.IsZero() {
+		timing.ttfb = firstByte.Sub(start)
+	}
+	return timing, resp.StatusCode, nil
+}
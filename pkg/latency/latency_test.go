@@ -0,0 +1,194 @@
+package latency
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+/*
+TestMeasureCounts vérifie que Measure compte chaque requête exactement une
+fois quand totalRequests n'est pas divisible par concurrency, y compris
+quand le serveur rejette une partie des requêtes. measureAggregated, qui
+agrège en flux, doit donner les mêmes comptes.
+
+@fixtures: le serveur répond 503 à une requête sur quatre et 500 à une sur
+dix, selon son propre compteur : les comptes attendus ne dépendent pas de
+l'ordre d'arrivée
+*/
+func TestMeasureCounts(t *testing.T) {
+	cases := []struct {
+		concurrency, total int
+	}{
+		{3, 10},
+		{4, 7},
+		{8, 3},
+		{1, 1},
+	}
+
+	impls := []struct {
+		name    string
+		measure func(ctx context.Context, next func() (string, func()), concurrency, total int, rate float64) Stats
+	}{
+		{"slice", measure},
+		{"aggregated", measureAggregated},
+	}
+
+	for _, impl := range impls {
+		for _, c := range cases {
+			var served atomic.Int64
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := served.Add(1)
+				switch {
+				case n%4 == 0:
+					w.WriteHeader(http.StatusServiceUnavailable)
+				case n%10 == 0:
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}))
+
+			stats := impl.measure(context.Background(), func() (string, func()) { return srv.URL, nil }, c.concurrency, c.total, 0)
+			srv.Close()
+
+			wantRejected := c.total / 4
+			wantFailed := c.total/10 - c.total/20
+			wantSuccess := c.total - wantRejected - wantFailed
+			if served.Load() != int64(c.total) {
+				t.Errorf("%s concurrency=%d total=%d: server saw %d requests", impl.name, c.concurrency, c.total, served.Load())
+			}
+			if stats.Success != wantSuccess || stats.Rejected != wantRejected || stats.Failed != wantFailed {
+				t.Errorf("%s concurrency=%d total=%d: success=%d rejected=%d failed=%d, want %d/%d/%d",
+					impl.name, c.concurrency, c.total, stats.Success, stats.Rejected, stats.Failed, wantSuccess, wantRejected, wantFailed)
+			}
+		}
+	}
+}
+
+/*
+TestMeasureContextCanceled vérifie qu'une mesure contre un serveur bloqué
+s'arrête à l'annulation de son contexte, sans attendre le timeout du client,
+que les requêtes en vol comme celles jamais envoyées comptent comme échecs,
+et qu'aucun worker ne reste en vie après le retour.
+*/
+func TestMeasureContextCanceled(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	for _, rate := range []float64{0, 1} {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		start := time.Now()
+		goroutines := runtime.NumGoroutine()
+		stats := MeasureContext(ctx, srv.URL, 4, 20, rate)
+		elapsed := time.Since(start)
+		cancel()
+
+		if elapsed > time.Second {
+			t.Errorf("rate=%v: measure returned after %v, want prompt return on cancellation", rate, elapsed)
+		}
+		if stats.Success != 0 || stats.Failed != 20 {
+			t.Errorf("rate=%v: success=%d failed=%d, want 0/20", rate, stats.Success, stats.Failed)
+		}
+		// Les connexions se ferment de façon asynchrone, côté client comme
+		// côté serveur httptest : on attend qu'elles aient disparu
+		leaked := 0
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if leaked = runtime.NumGoroutine() - goroutines; leaked <= 0 {
+				break
+			}
+		}
+		if leaked > 0 {
+			t.Errorf("rate=%v: %d goroutines still running after measure", rate, leaked)
+		}
+	}
+}
+
+/*
+TestStatsAccumulatorOrder vérifie que l'agrégation ne dépend pas de l'ordre
+des résultats : measure les agrège dans l'ordre des requêtes, measureAggregated
+dans leur ordre d'arrivée, et les Stats doivent être identiques, moyennes et
+percentiles compris.
+*/
+func TestStatsAccumulatorOrder(t *testing.T) {
+	statuses := []int{http.StatusOK, http.StatusServiceUnavailable, http.StatusOK, 0, http.StatusTooManyRequests, http.StatusInternalServerError}
+	results := make([]requestResult, 200)
+	for i := range results {
+		results[i] = requestResult{
+			timing: requestTiming{total: time.Duration(i*7919%1000) * time.Microsecond, ttfb: time.Duration(i%3) * time.Millisecond},
+			status: statuses[i%len(statuses)],
+		}
+	}
+
+	aggregate := func(order []requestResult) Stats {
+		acc := newStatsAccumulator(len(order))
+		for _, result := range order {
+			acc.add(result)
+		}
+		return acc.stats(time.Second)
+	}
+	reversed := make([]requestResult, len(results))
+	for i, result := range results {
+		reversed[len(results)-1-i] = result
+	}
+
+	inOrder, outOfOrder := aggregate(results), aggregate(reversed)
+	if inOrder != outOfOrder {
+		t.Errorf("stats depend on the order of results:\n in order: %+v\nreversed: %+v", inOrder, outOfOrder)
+	}
+	if inOrder.Success+inOrder.Rejected+inOrder.Failed != len(results) {
+		t.Errorf("stats %+v do not account for %d results", inOrder, len(results))
+	}
+}
+
+/*
+BenchmarkMeasureResults compare le coût du harnais de mesure lui-même,
+contre un serveur httptest qui répond immédiatement : measure range les
+résultats dans un tableau de totalRequests cases, measureAggregated les
+envoie dans un petit canal lu par une goroutine d'agrégation. B/op et
+allocs/op sont dominés par le client HTTP ; l'écart entre les deux formes
+est ce que coûte le tableau.
+
+	go test ./pkg/latency -run '^$' -bench MeasureResults -benchmem
+
+@metrics:
+  - harness-B/req: Octets alloués par requête mesurée, client HTTP compris
+*/
+func BenchmarkMeasureResults(b *testing.B) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	next := func() (string, func()) { return srv.URL, nil }
+
+	for _, total := range []int{1000, 10000} {
+		for _, impl := range []struct {
+			name    string
+			measure func(ctx context.Context, next func() (string, func()), concurrency, total int, rate float64) Stats
+		}{
+			{"slice", measure},
+			{"aggregated", measureAggregated},
+		} {
+			b.Run(fmt.Sprintf("%s/requests=%d", impl.name, total), func(b *testing.B) {
+				var before, after runtime.MemStats
+				runtime.ReadMemStats(&before)
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if stats := impl.measure(context.Background(), next, 8, total, 0); stats.Success != total {
+						b.Fatalf("success = %d, want %d", stats.Success, total)
+					}
+				}
+				runtime.ReadMemStats(&after)
+				b.ReportMetric(float64(after.TotalAlloc-before.TotalAlloc)/float64(b.N*total), "harness-B/req")
+			})
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package latency
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ReportVersion est la version du format de ComparisonReport, incrémentée à chaque changement incompatible
+const ReportVersion = 1
+
+/*
+ComparisonReport est le résumé JSON d'une comparaison de latence, stable
+pour être archivé et comparé à une baseline (go run format_results.go
+-report=... -baseline=...).
+
+@fields:
+  - Version: Version du format (ReportVersion)
+  - Generated: Date de la mesure
+  - Requests: Requêtes par serveur et par niveau de concurrence
+  - LatencyRate: Cadence prévue en req/s (0 = envoi au plus tôt)
+  - Results: Une entrée par serveur et par niveau de concurrence
+*/
+type ComparisonReport struct {
+	Version     int                `json:"version"`
+	Generated   time.Time          `json:"generated"`
+	Requests    int                `json:"requests"`
+	LatencyRate float64            `json:"latency_rate"`
+	Results     []ComparisonResult `json:"results"`
+}
+
+/*
+ComparisonResult est la mesure d'un serveur à un niveau de concurrence.
+
+@fields:
+  - Server: Nom du serveur, tel qu'il apparaît dans les noms de benchmark (Bad, Good, SyncMap, ...)
+  - Concurrency: Nombre de requêtes en vol
+  - MeanMs, P95Ms, P99Ms: Latence moyenne et percentiles des requêtes réussies (ms)
+  - ReqPerSec: Requêtes réussies par seconde de mesure
+  - Success: Requêtes ayant reçu un 200
+  - Rejected: Requêtes rejetées (503, 429)
+  - Failed: Requêtes en erreur de transport ou d'un autre code HTTP
+*/
+type ComparisonResult struct {
+	Server      string  `json:"server"`
+	Concurrency int     `json:"concurrency"`
+	MeanMs      float64 `json:"mean_ms"`
+	P95Ms       float64 `json:"p95_ms"`
+	P99Ms       float64 `json:"p99_ms"`
+	ReqPerSec   float64 `json:"req_per_sec"`
+	Success     int     `json:"success"`
+	Rejected    int     `json:"rejected"`
+	Failed      int     `json:"failed"`
+}
+
+/*
+NewReport crée un rapport vide pour une comparaison.
+
+@params:
+  - requests: int requêtes par serveur et par niveau
+  - rate: float64 cadence prévue en req/s (0 = envoi au plus tôt)
+
+@returns: *ComparisonReport - Rapport daté, sans résultat
+*/
+func NewReport(requests int, rate float64) *ComparisonReport {
+	return &ComparisonReport{
+		Version:     ReportVersion,
+		Generated:   time.Now().UTC(),
+		Requests:    requests,
+		LatencyRate: rate,
+		Results:     []ComparisonResult{},
+	}
+}
+
+/*
+Add ajoute la mesure d'un serveur à un niveau de concurrence.
+
+@params:
+  - server: string nom du serveur
+  - concurrency: int niveau de concurrence
+  - stats: Stats mesure du serveur
+*/
+func (r *ComparisonReport) Add(server string, concurrency int, stats Stats) {
+	r.Results = append(r.Results, ComparisonResult{
+		Server:      server,
+		Concurrency: concurrency,
+		MeanMs:      stats.AvgMs,
+		P95Ms:       stats.P95Ms,
+		P99Ms:       stats.P99Ms,
+		ReqPerSec:   stats.ReqPerSec(),
+		Success:     stats.Success,
+		Rejected:    stats.Rejected,
+		Failed:      stats.Failed,
+	})
+}
+
+/*
+WriteFile écrit le rapport en JSON indenté.
+
+@params:
+  - path: string fichier de destination (écrasé s'il existe)
+
+@returns: error - Erreur d'écriture
+*/
+func (r *ComparisonReport) WriteFile(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+/*
+ReadReport lit un rapport JSON et vérifie sa version.
+
+@params:
+  - rd: io.Reader contenant le rapport
+
+@returns: (*ComparisonReport, error) - Rapport lu, ou erreur de lecture ou de version
+*/
+func ReadReport(rd io.Reader) (*ComparisonReport, error) {
+	var report ComparisonReport
+	if err := json.NewDecoder(rd).Decode(&report); err != nil {
+		return nil, err
+	}
+	if report.Version != ReportVersion {
+		return nil, fmt.Errorf("unsupported report version %d (want %d)", report.Version, ReportVersion)
+	}
+	return &report, nil
+}
@@ -0,0 +1,70 @@
+package latency
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+/*
+TestPercentileMs vérifie la méthode du rang le plus proche sur une série de
+1 à 100ms, et sur une série d'un seul échantillon.
+*/
+func TestPercentileMs(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+	for _, tc := range []struct{ p, want float64 }{{50, 50}, {95, 95}, {99, 99}, {100, 100}, {0, 1}} {
+		if got := percentileMs(sorted, tc.p); got != tc.want {
+			t.Errorf("p%v = %vms, want %vms", tc.p, got, tc.want)
+		}
+	}
+	if got := percentileMs([]time.Duration{7 * time.Millisecond}, 99); got != 7 {
+		t.Errorf("single sample p99 = %vms, want 7ms", got)
+	}
+
+	// Percentile trie lui-même une série dans le désordre
+	shuffled := make([]time.Duration, len(sorted))
+	for i := range shuffled {
+		shuffled[i] = sorted[i*37%len(sorted)]
+	}
+	if got := Percentile(shuffled, 99); got != 99 {
+		t.Errorf("unsorted p99 = %vms, want 99ms", got)
+	}
+	if got := Percentile(nil, 99); got != 0 {
+		t.Errorf("empty p99 = %vms, want 0", got)
+	}
+}
+
+/*
+TestReportRoundTrip vérifie qu'un rapport écrit par WriteFile se relit à
+l'identique avec ReadReport.
+*/
+func TestReportRoundTrip(t *testing.T) {
+	report := NewReport(100, 0)
+	report.Add("Bad", 10, Stats{AvgMs: 108.5, P95Ms: 112, P99Ms: 113, Success: 100, Elapsed: time.Second})
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := report.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got, err := ReadReport(f)
+	if err != nil {
+		t.Fatalf("ReadReport: %v", err)
+	}
+
+	want := ComparisonResult{Server: "Bad", Concurrency: 10, MeanMs: 108.5, P95Ms: 112, P99Ms: 113, ReqPerSec: 100, Success: 100}
+	if len(got.Results) != 1 || got.Results[0] != want {
+		t.Errorf("results = %+v, want [%+v]", got.Results, want)
+	}
+	if got.Version != ReportVersion || got.Requests != 100 || !got.Generated.Equal(report.Generated) {
+		t.Errorf("report header = %+v, want version %d, 100 requests, generated %v", got, ReportVersion, report.Generated)
+	}
+}
@@ -0,0 +1,321 @@
+package latency
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// scenarioColumns sont les colonnes d'un fichier de scénarios, dans l'ordre ; la ligne d'en-tête est facultative
+var scenarioColumns = []string{"url", "concurrency", "total", "readRatio"}
+
+/*
+Scenario est une ligne d'un fichier de scénarios : une mesure à effectuer.
+
+@fields:
+  - URL: Endpoint d'écriture mesuré (ex: http://localhost:8081/process)
+  - Concurrency: Nombre maximum de requêtes en vol
+  - Total: Nombre total de requêtes
+  - ReadRatio: Pourcentage de lectures GET /data/{id} mêlées aux écritures (0-100)
+*/
+type Scenario struct {
+	URL         string
+	Concurrency int
+	Total       int
+	ReadRatio   float64
+}
+
+/*
+ScenarioResult est la mesure d'un scénario.
+
+@fields:
+  - Scenario: Scénario mesuré
+  - Stats: Résultat de la mesure
+*/
+type ScenarioResult struct {
+	Scenario
+	Stats Stats
+}
+
+/*
+RunScenarios lit des scénarios au format CSV (url,concurrency,total,readRatio)
+et les mesure l'un après l'autre, pour balayer des configurations sans
+modifier le code. Une ligne d'en-tête et les lignes commençant par # sont
+ignorées ; une ligne invalide est signalée dans le log puis sautée, de même
+qu'un scénario dont le serveur ne répond pas sur /stats.
+
+	url,concurrency,total,readRatio
+	http://localhost:8081/process,10,100,0
+	http://localhost:8088/process,50,500,90
+
+@params:
+  - r: io.Reader contenant le CSV
+
+@returns: ([]ScenarioResult, error) - Une mesure par scénario valide, dans
+l'ordre du fichier ; erreur si la lecture échoue ou si aucune ligne n'est valide
+*/
+func RunScenarios(r io.Reader) ([]ScenarioResult, error) {
+	scenarios, err := parseScenarios(r)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ScenarioResult, 0, len(scenarios))
+	for _, sc := range scenarios {
+		stats, err := MeasureMixed(sc.URL, sc.Concurrency, sc.Total, 0, sc.ReadRatio)
+		if err != nil {
+			log.Printf("scenarios: %s: %v, skipped", sc.URL, err)
+			continue
+		}
+		results = append(results, ScenarioResult{Scenario: sc, Stats: stats})
+	}
+	return results, nil
+}
+
+/*
+parseScenarios lit et valide les lignes d'un fichier de scénarios.
+
+@params:
+  - r: io.Reader contenant le CSV
+
+@returns: ([]Scenario, error) - Scénarios valides ; erreur de lecture, ou si
+aucune ligne n'est valide
+*/
+func parseScenarios(r io.Reader) ([]Scenario, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1 // Le nombre de colonnes est vérifié par parseScenario
+	cr.TrimLeadingSpace = true
+
+	var scenarios []Scenario
+	for first := true; ; first = false {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			log.Printf("scenarios: line %d: %v, skipped", parseErr.Line, parseErr.Err)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("scenarios: %w", err)
+		}
+		if first && isScenarioHeader(record) {
+			continue
+		}
+
+		line, _ := cr.FieldPos(0)
+		sc, err := parseScenario(record)
+		if err != nil {
+			log.Printf("scenarios: line %d: %v, skipped", line, err)
+			continue
+		}
+		scenarios = append(scenarios, sc)
+	}
+
+	if len(scenarios) == 0 {
+		return nil, errors.New("scenarios: no valid scenario")
+	}
+	return scenarios, nil
+}
+
+/*
+isScenarioHeader indique si une ligne est l'en-tête des colonnes.
+
+@params:
+  - record: []string champs de la ligne
+
+@returns: bool - true si les champs sont les noms de scenarioColumns (casse ignorée)
+*/
+func isScenarioHeader(record []string) bool {
+	if len(record) != len(scenarioColumns) {
+		return false
+	}
+	for i, name := range scenarioColumns {
+		if !strings.EqualFold(strings.TrimSpace(record[i]), name) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+parseScenario valide une ligne de scénario.
+
+@params:
+  - record: []string champs url, concurrency, total et readRatio
+
+@returns: (Scenario, error) - Scénario, ou la première valeur invalide rencontrée
+*/
+func parseScenario(record []string) (Scenario, error) {
+	if len(record) != len(scenarioColumns) {
+		return Scenario{}, fmt.Errorf("want %d fields (%s), got %d", len(scenarioColumns), strings.Join(scenarioColumns, ","), len(record))
+	}
+	for i := range record {
+		record[i] = strings.TrimSpace(record[i])
+	}
+
+	var sc Scenario
+	u, err := url.Parse(record[0])
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Scenario{}, fmt.Errorf("url %q: want an absolute http(s) URL", record[0])
+	}
+	sc.URL = record[0]
+	if sc.Concurrency, err = strconv.Atoi(record[1]); err != nil || sc.Concurrency <= 0 {
+		return Scenario{}, fmt.Errorf("concurrency %q: want a positive integer", record[1])
+	}
+	if sc.Total, err = strconv.Atoi(record[2]); err != nil || sc.Total <= 0 {
+		return Scenario{}, fmt.Errorf("total %q: want a positive integer", record[2])
+	}
+	sc.ReadRatio, err = strconv.ParseFloat(record[3], 64)
+	if err != nil || math.IsNaN(sc.ReadRatio) || sc.ReadRatio < 0 || sc.ReadRatio > 100 {
+		return Scenario{}, fmt.Errorf("readRatio %q: want a percentage between 0 and 100", record[3])
+	}
+	return sc, nil
+}
+
+/*
+MeasureMixed mesure comme Measure, en mêlant aux écritures sur url une part
+readRatio de lectures GET /data/{id}. Les lectures visent une clé déjà
+écrite : celles présentes sur le serveur au démarrage ou une écriture dont
+la réponse a été lue ; tant qu'il n'y en a aucune, seules des écritures partent.
+
+@params:
+  - url: string URL de l'endpoint d'écriture (/process)
+  - concurrency: int nombre maximum de requêtes en vol
+  - totalRequests: int nombre total de requêtes, lectures comprises
+  - rate: float64 cadence prévue en req/s (0 = envoi au plus tôt)
+  - readRatio: float64 pourcentage de lectures (0 = écritures seules, comme Measure)
+
+@returns: (Stats, error) - Mesure des lectures et écritures confondues, ou
+erreur si l'espace des clés n'a pas pu être lu sur /stats
+*/
+func MeasureMixed(url string, concurrency int, totalRequests int, rate float64, readRatio float64) (Stats, error) {
+	if readRatio <= 0 {
+		return Measure(url, concurrency, totalRequests, rate), nil
+	}
+	next, err := mixedRequests(url, readRatio)
+	if err != nil {
+		return Stats{}, err
+	}
+	return measure(context.Background(), next, concurrency, totalRequests, rate), nil
+}
+
+/*
+MixedURLs construit un générateur d'URL mêlant lectures et écritures, pour
+un appelant qui ne signale pas la fin des requêtes (benchmarks HTTP).
+Les lectures visent une clé request_N tirée uniformément dans l'espace des
+clés existantes : celles déjà écrites sur le serveur (total_requests de
+/stats au démarrage) plus les écritures envoyées depuis.
+
+@params:
+  - writeURL: string URL de l'endpoint d'écriture (/process)
+  - ratio: float64 pourcentage de lectures (0-100)
+
+@returns: (func() string, error) - Générateur d'URL sûr en concurrence, ou
+erreur si /stats est illisible
+
+@note: Une lecture peut viser une écriture encore en vol et recevoir un 404 ;
+elle exerce le même chemin de verrouillage qu'une lecture réussie.
+*/
+func MixedURLs(writeURL string, ratio float64) (func() string, error) {
+	next, err := mixedRequests(writeURL, ratio)
+	if err != nil {
+		return nil, err
+	}
+	return func() string {
+		url, done := next()
+		if done != nil {
+			done() // Une écriture compte dès son envoi
+		}
+		return url
+	}, nil
+}
+
+/*
+mixedRequests construit le générateur commun à MeasureMixed et MixedURLs.
+Une écriture n'entre dans l'espace des clés lisibles qu'à l'appel de la
+fonction retournée avec son URL.
+
+@params:
+  - writeURL: string URL de l'endpoint d'écriture (/process)
+  - ratio: float64 pourcentage de lectures (0-100)
+
+@returns: (func() (string, func()), error) - Générateur sûr en concurrence,
+ou erreur si /stats est illisible
+*/
+func mixedRequests(writeURL string, ratio float64) (func() (string, func()), error) {
+	u, err := url.Parse(writeURL)
+	if err != nil {
+		return nil, err
+	}
+	base := u.Scheme + "://" + u.Host
+	existing, err := fetchTotalRequests(base + "/stats")
+	if err != nil {
+		return nil, fmt.Errorf("reading key space from %s/stats: %w", base, err)
+	}
+
+	var writes atomic.Int64
+	wrote := func() { writes.Add(1) }
+	return func() (string, func()) {
+		keys := existing + writes.Load()
+		if keys == 0 || rand.Float64()*100 >= ratio {
+			return writeURL, wrote
+		}
+		return fmt.Sprintf("%s/data/request_%d", base, rand.Int63n(keys)+1), nil
+	}, nil
+}
+
+/*
+fetchTotalRequests lit le compteur total_requests d'un serveur.
+
+@params:
+  - url: string URL de l'endpoint /stats
+
+@returns: (int64, error) - Nombre de requêtes /process déjà traitées
+*/
+func fetchTotalRequests(url string) (int64, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	var stats struct {
+		TotalRequests int64 `json:"total_requests"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&stats)
+	return stats.TotalRequests, err
+}
+
+/*
+WriteScenarios écrit le tableau combiné des mesures de scénarios.
+
+@params:
+  - w: io.Writer destination du tableau
+  - results: []ScenarioResult mesures, une ligne chacune
+*/
+func WriteScenarios(w io.Writer, results []ScenarioResult) {
+	fmt.Fprintf(w, "%s%-40s %6s %7s %6s %9s %9s %9s %9s %8s %6s%s\n", Bold,
+		"URL", "Conc", "Total", "Read%", "Avg ms", "P95 ms", "P99 ms", "req/s", "Rejected", "Failed", ColorReset)
+	for _, res := range results {
+		s := res.Stats
+		fmt.Fprintf(w, "%-40s %6d %7d %6.0f %9.2f %9.2f %9.2f %9.0f %8d %6d\n",
+			res.URL, res.Concurrency, res.Total, res.ReadRatio,
+			s.AvgMs, s.P95Ms, s.P99Ms, s.ReqPerSec(), s.Rejected, s.Failed)
+	}
+}
@@ -0,0 +1,114 @@
+package latency
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+/*
+TestParseScenarios vérifie la lecture d'un fichier de scénarios : l'en-tête
+et les commentaires sont ignorés, chaque ligne invalide est signalée avec son
+numéro puis sautée, et un fichier sans ligne valide est une erreur.
+*/
+func TestParseScenarios(t *testing.T) {
+	const input = `url,concurrency,total,readRatio
+# bad puis rwmutex
+http://localhost:8081/process,10,100,0
+http://localhost:8088/process, 50, 500, 90
+http://localhost:8088/process,ten,100,90
+http://localhost:8082/process,10,100
+ftp://localhost/process,1,1,0
+http://localhost:8082/process,10,0,0
+http://localhost:8082/process,10,100,101
+http://localhost:8082/"process,1,1,0
+`
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	scenarios, err := parseScenarios(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parseScenarios: %v", err)
+	}
+	want := []Scenario{
+		{URL: "http://localhost:8081/process", Concurrency: 10, Total: 100, ReadRatio: 0},
+		{URL: "http://localhost:8088/process", Concurrency: 50, Total: 500, ReadRatio: 90},
+	}
+	if fmt.Sprint(scenarios) != fmt.Sprint(want) {
+		t.Errorf("scenarios = %v, want %v", scenarios, want)
+	}
+	for line := 5; line <= 10; line++ {
+		if !strings.Contains(logs.String(), fmt.Sprintf("line %d:", line)) {
+			t.Errorf("no warning for line %d in log:\n%s", line, logs.String())
+		}
+	}
+
+	for _, input := range []string{"", "url,concurrency,total,readRatio\n", "http://x/process,0,1,0\n"} {
+		if _, err := parseScenarios(strings.NewReader(input)); err == nil {
+			t.Errorf("parseScenarios(%q) succeeded, want error", input)
+		}
+	}
+}
+
+/*
+TestRunScenarios mesure deux scénarios sur un serveur factice et vérifie la
+répartition lectures/écritures : les lectures ne visent que des clés déjà
+écrites, aucune ne reçoit donc de 404.
+
+@fixtures: le serveur compte ses écritures /process et répond 404 à
+/data/request_N tant que N n'a pas été écrit
+*/
+func TestRunScenarios(t *testing.T) {
+	var writes, reads atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/stats":
+			fmt.Fprint(w, `{"total_requests": 0}`)
+		case r.URL.Path == "/process":
+			writes.Add(1)
+		case strings.HasPrefix(r.URL.Path, "/data/request_"):
+			reads.Add(1)
+			n, _ := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/data/request_"), 10, 64)
+			if n < 1 || n > writes.Load() {
+				http.NotFound(w, r)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	input := fmt.Sprintf("%[1]s/process,4,40,0\n%[1]s/process,4,200,80\n", srv.URL)
+	results, err := RunScenarios(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("RunScenarios: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("%d results, want 2", len(results))
+	}
+	for _, res := range results {
+		if res.Stats.Success != res.Total || res.Stats.Failed != 0 {
+			t.Errorf("%v: success = %d, failed = %d, want %d and 0", res.Scenario, res.Stats.Success, res.Stats.Failed, res.Total)
+		}
+	}
+	if got := writes.Load() + reads.Load(); got != 240 {
+		t.Errorf("server saw %d requests, want 240", got)
+	}
+	if reads.Load() == 0 {
+		t.Error("no read sent with readRatio 80")
+	}
+
+	var table bytes.Buffer
+	WriteScenarios(&table, results)
+	if lines := strings.Count(table.String(), "\n"); lines != 3 {
+		t.Errorf("table has %d lines, want header + 2:\n%s", lines, table.String())
+	}
+}
@@ -0,0 +1,350 @@
+package latency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultSoakTolerance est la dégradation relative tolérée entre le début et la fin d'un soak test
+const DefaultSoakTolerance = 0.2
+
+// minSoakSamples est le nombre d'échantillons sous lequel JudgeSoak ne peut pas conclure
+const minSoakSamples = 3
+
+/*
+SoakSample est une fenêtre d'un soak test.
+
+@fields:
+  - Elapsed: Fin de la fenêtre, depuis le début du test
+  - Stats: Requêtes terminées dans la fenêtre (Elapsed de Stats = durée de la fenêtre)
+  - DataSize: data_size de /stats à la fin de la fenêtre (-1 si non publié)
+  - HeapBytes: heap_alloc_bytes de /stats à la fin de la fenêtre (-1 si non publié)
+*/
+type SoakSample struct {
+	Elapsed   time.Duration
+	Stats     Stats
+	DataSize  int64
+	HeapBytes int64
+}
+
+/*
+Soak charge url à concurrence fixe pendant duration et découpe la charge en
+fenêtres de interval. Chaque worker enchaîne ses requêtes dans une boucle,
+comme sampleThroughput ; la fenêtre courante est un statsAccumulator,
+remplacé à chaque tick, et les latences de la fenêtre close donnent son
+throughput et ses percentiles. À chaque tick, /stats du serveur est lu pour
+suivre la taille de la map et du tas.
+
+@params:
+  - ctx: context.Context annulation anticipée (Ctrl-C) ; les fenêtres closes sont rendues
+  - url: string URL chargée (ex: http://localhost:8082/process)
+  - statsURL: string URL de /stats du serveur ("" = taille non suivie)
+  - concurrency: int nombre de requêtes en vol
+  - duration: time.Duration durée totale, arrondie à la fenêtre supérieure
+  - interval: time.Duration durée d'une fenêtre
+  - onSample: func(SoakSample) appelée à la fin de chaque fenêtre (nil = aucune)
+
+@returns: []SoakSample - Fenêtres closes, dans l'ordre chronologique
+
+@note: Les requêtes en vol à la fin d'une fenêtre comptent dans la
+suivante, celles en vol à la fin du test sont annulées et ne sont pas
+comptées.
+*/
+func Soak(ctx context.Context, url, statsURL string, concurrency int, duration, interval time.Duration, onSample func(SoakSample)) []SoakSample {
+	windows := int((duration + interval - 1) / interval)
+	if windows <= 0 || concurrency <= 0 || interval <= 0 {
+		return nil
+	}
+
+	client, closeIdle := limitedClient(concurrency)
+	defer closeIdle()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	window := newStatsAccumulator(0)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				timing, status, err := timedGet(ctx, client, url)
+				if ctx.Err() != nil {
+					return
+				}
+				mu.Lock()
+				window.add(requestResult{timing: timing, status: status})
+				mu.Unlock()
+				if err != nil {
+					// Serveur injoignable : pas de boucle active en attendant la fenêtre suivante
+					time.Sleep(interval / 100)
+				}
+			}
+		}()
+	}
+
+	// /stats passe par son propre client : sur celui des workers, il attendrait une connexion libre
+	statsClient := &http.Client{Timeout: interval}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	start := time.Now()
+	windowStart := start
+	samples := make([]SoakSample, 0, windows)
+	for len(samples) < windows {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return samples
+		case now := <-ticker.C:
+			mu.Lock()
+			closed := window
+			window = newStatsAccumulator(0)
+			mu.Unlock()
+
+			sample := SoakSample{Elapsed: now.Sub(start), Stats: closed.stats(now.Sub(windowStart)), DataSize: -1, HeapBytes: -1}
+			windowStart = now
+			if statsURL != "" {
+				sample.DataSize, sample.HeapBytes = fetchSoakGauges(ctx, statsClient, statsURL)
+			}
+			samples = append(samples, sample)
+			if onSample != nil {
+				onSample(sample)
+			}
+		}
+	}
+
+	cancel()
+	wg.Wait()
+	return samples
+}
+
+/*
+fetchSoakGauges lit data_size et heap_alloc_bytes dans /stats.
+
+@params:
+  - ctx: context.Context du soak test
+  - client: *http.Client client de /stats, distinct de celui des workers
+  - statsURL: string URL de /stats
+
+@returns: (int64, int64) - data_size et heap_alloc_bytes, -1 pour un champ
+absent ou un /stats injoignable
+*/
+func fetchSoakGauges(ctx context.Context, client *http.Client, statsURL string) (int64, int64) {
+	dataSize, heap := int64(-1), int64(-1)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, statsURL, nil)
+	if err != nil {
+		return dataSize, heap
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return dataSize, heap
+	}
+	defer resp.Body.Close()
+	var stats map[string]any
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&stats) != nil {
+		return dataSize, heap
+	}
+	if v, ok := stats["data_size"].(float64); ok {
+		dataSize = int64(v)
+	}
+	if v, ok := stats["heap_alloc_bytes"].(float64); ok {
+		heap = int64(v)
+	}
+	return dataSize, heap
+}
+
+/*
+SoakVerdict compare le début et la fin d'un soak test. Chaque grandeur est
+la moyenne du premier et du dernier quart des fenêtres, la toute première
+exclue : elle contient la montée en charge.
+
+@fields:
+  - Samples: Nombre de fenêtres jugées
+  - StartReqPerSec, EndReqPerSec: Throughput moyen du début et de la fin
+  - StartP99Ms, EndP99Ms: p99 moyen du début et de la fin (ms)
+  - StartDataSize, EndDataSize: data_size moyen du début et de la fin (-1 si non publié)
+  - StartHeapBytes, EndHeapBytes: heap_alloc_bytes moyen du début et de la fin (-1 si non publié)
+  - Failed: Requêtes en erreur sur tout le test (transport ou code inattendu)
+  - Reasons: Dégradations constatées, vide si le serveur est resté stable
+*/
+type SoakVerdict struct {
+	Samples                      int
+	StartReqPerSec, EndReqPerSec float64
+	StartP99Ms, EndP99Ms         float64
+	StartDataSize, EndDataSize   int64
+	StartHeapBytes, EndHeapBytes int64
+	Failed                       int
+	Reasons                      []string
+}
+
+// Stable indique si aucune dégradation n'a été constatée
+func (v SoakVerdict) Stable() bool {
+	return len(v.Reasons) == 0
+}
+
+/*
+JudgeSoak juge la stabilité d'un soak test : le throughput ne doit pas
+baisser, ni le p99, la taille de la map ou le tas augmenter, de plus de
+tolerance entre le début et la fin, et aucune requête ne doit échouer.
+Une map non bornée (serveur lancé sans -ttl) se voit à data_size, le coût
+de sa copie au throughput, les pauses du GC au p99.
+
+@params:
+  - samples: []SoakSample fenêtres rendues par Soak
+  - tolerance: float64 variation relative tolérée (ex: 0.2 pour ±20%)
+
+@returns: SoakVerdict - Verdict ; instable avec moins de 3 fenêtres, faute
+de pouvoir séparer le début de la fin
+*/
+func JudgeSoak(samples []SoakSample, tolerance float64) SoakVerdict {
+	v := SoakVerdict{Samples: len(samples), StartDataSize: -1, EndDataSize: -1, StartHeapBytes: -1, EndHeapBytes: -1}
+	for _, s := range samples {
+		v.Failed += s.Stats.Failed
+	}
+	if len(samples) < minSoakSamples {
+		v.Reasons = append(v.Reasons, fmt.Sprintf("%d fenêtres, il en faut au moins %d : allongez -duration ou réduisez -interval", len(samples), minSoakSamples))
+		return v
+	}
+
+	judged := samples[1:]
+	quarter := max(1, len(judged)/4)
+	first, last := judged[:quarter], judged[len(judged)-quarter:]
+	start, end := summarizeSoak(first), summarizeSoak(last)
+	v.StartReqPerSec, v.EndReqPerSec = start.reqPerSec, end.reqPerSec
+	v.StartP99Ms, v.EndP99Ms = start.p99Ms, end.p99Ms
+	v.StartDataSize, v.EndDataSize = start.dataSize, end.dataSize
+	v.StartHeapBytes, v.EndHeapBytes = start.heapBytes, end.heapBytes
+
+	if v.EndReqPerSec < v.StartReqPerSec*(1-tolerance) {
+		v.Reasons = append(v.Reasons, fmt.Sprintf("throughput en baisse : %.1f → %.1f req/s", v.StartReqPerSec, v.EndReqPerSec))
+	}
+	if v.EndP99Ms > v.StartP99Ms*(1+tolerance) {
+		v.Reasons = append(v.Reasons, fmt.Sprintf("p99 en hausse : %.1f → %.1f ms", v.StartP99Ms, v.EndP99Ms))
+	}
+	if v.StartDataSize >= 0 && v.EndDataSize >= 0 && float64(v.EndDataSize) > float64(v.StartDataSize)*(1+tolerance) {
+		v.Reasons = append(v.Reasons, fmt.Sprintf("map sans borne : data_size %d → %d", v.StartDataSize, v.EndDataSize))
+	}
+	if v.StartHeapBytes >= 0 && v.EndHeapBytes >= 0 && float64(v.EndHeapBytes) > float64(v.StartHeapBytes)*(1+tolerance) {
+		v.Reasons = append(v.Reasons, fmt.Sprintf("tas en hausse : %.1f → %.1f Mio", mib(v.StartHeapBytes), mib(v.EndHeapBytes)))
+	}
+	if v.Failed > 0 {
+		v.Reasons = append(v.Reasons, fmt.Sprintf("%d requêtes en erreur", v.Failed))
+	}
+	return v
+}
+
+/*
+soakWindow résume des fenêtres consécutives d'un soak test.
+
+@fields:
+  - reqPerSec, p99Ms: Moyennes du throughput et du p99
+  - dataSize, heapBytes: Moyennes des jauges de /stats, -1 si une fenêtre ne les a pas relevées
+*/
+type soakWindow struct {
+	reqPerSec, p99Ms    float64
+	dataSize, heapBytes int64
+}
+
+/*
+summarizeSoak calcule les moyennes de fenêtres consécutives.
+
+@params:
+  - samples: []SoakSample fenêtres à résumer (non vide)
+
+@returns: soakWindow - Moyennes des fenêtres
+*/
+func summarizeSoak(samples []SoakSample) soakWindow {
+	var w soakWindow
+	for _, s := range samples {
+		w.reqPerSec += s.Stats.ReqPerSec()
+		w.p99Ms += s.Stats.P99Ms
+		if w.dataSize >= 0 {
+			w.dataSize = gaugeSum(w.dataSize, s.DataSize)
+		}
+		if w.heapBytes >= 0 {
+			w.heapBytes = gaugeSum(w.heapBytes, s.HeapBytes)
+		}
+	}
+	n := len(samples)
+	w.reqPerSec /= float64(n)
+	w.p99Ms /= float64(n)
+	if w.dataSize > 0 {
+		w.dataSize /= int64(n)
+	}
+	if w.heapBytes > 0 {
+		w.heapBytes /= int64(n)
+	}
+	return w
+}
+
+// gaugeSum ajoute une jauge à une somme, -1 dès qu'une fenêtre ne l'a pas relevée
+func gaugeSum(sum, gauge int64) int64 {
+	if gauge < 0 {
+		return -1
+	}
+	return sum + gauge
+}
+
+// mib convertit des octets en Mio
+func mib(bytes int64) float64 {
+	return float64(bytes) / (1 << 20)
+}
+
+/*
+WriteSoakSample écrit une ligne par fenêtre : throughput, latence moyenne et
+p99, erreurs, puis data_size et tas quand /stats les publie.
+
+	[   10s]  612.4 req/s  moy   16.0 ms  p99   31.2 ms  erreurs 0  data_size 6124  tas 9.8 Mio
+
+@params:
+  - w: io.Writer destination de la ligne
+  - s: SoakSample fenêtre à écrire
+*/
+func WriteSoakSample(w io.Writer, s SoakSample) {
+	fmt.Fprintf(w, "[%6s] %6.1f req/s  moy %6.1f ms  p99 %6.1f ms  erreurs %d",
+		s.Elapsed.Round(time.Second), s.Stats.ReqPerSec(), s.Stats.AvgMs, s.Stats.P99Ms, s.Stats.Failed)
+	if s.DataSize >= 0 {
+		fmt.Fprintf(w, "  data_size %d", s.DataSize)
+	}
+	if s.HeapBytes >= 0 {
+		fmt.Fprintf(w, "  tas %.1f Mio", mib(s.HeapBytes))
+	}
+	fmt.Fprintln(w)
+}
+
+/*
+WriteSoakVerdict écrit le bilan d'un soak test : grandeurs du début et de
+la fin, puis STABLE ou DÉGRADÉ avec les dégradations constatées.
+
+@params:
+  - w: io.Writer destination du bilan
+  - v: SoakVerdict verdict de JudgeSoak
+  - tolerance: float64 tolérance utilisée, rappelée dans le titre
+*/
+func WriteSoakVerdict(w io.Writer, v SoakVerdict, tolerance float64) {
+	fmt.Fprintf(w, "\n%s%sBilan sur %d fenêtres (début → fin, tolérance ±%.0f%%):%s\n", Bold, ColorBlue, v.Samples, tolerance*100, ColorReset)
+	if v.Samples >= minSoakSamples {
+		fmt.Fprintf(w, "• throughput  %.1f → %.1f req/s\n", v.StartReqPerSec, v.EndReqPerSec)
+		fmt.Fprintf(w, "• p99         %.1f → %.1f ms\n", v.StartP99Ms, v.EndP99Ms)
+		if v.StartDataSize >= 0 && v.EndDataSize >= 0 {
+			fmt.Fprintf(w, "• data_size   %d → %d\n", v.StartDataSize, v.EndDataSize)
+		}
+		if v.StartHeapBytes >= 0 && v.EndHeapBytes >= 0 {
+			fmt.Fprintf(w, "• tas         %.1f → %.1f Mio\n", mib(v.StartHeapBytes), mib(v.EndHeapBytes))
+		}
+	}
+	if v.Stable() {
+		fmt.Fprintf(w, "%s%sSTABLE%s\n", Bold, ColorGreen, ColorReset)
+		return
+	}
+	fmt.Fprintf(w, "%s%sDÉGRADÉ%s\n", Bold, ColorRed, ColorReset)
+	for _, reason := range v.Reasons {
+		fmt.Fprintf(w, "  - %s\n", reason)
+	}
+}
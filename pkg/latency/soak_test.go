@@ -0,0 +1,116 @@
+package latency
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+/*
+soakSamples construit des fenêtres de 10s dont le throughput, le p99 et
+data_size suivent les séries données.
+*/
+func soakSamples(reqPerSec, p99Ms []float64, dataSize []int64) []SoakSample {
+	samples := make([]SoakSample, len(reqPerSec))
+	for i := range samples {
+		samples[i] = SoakSample{
+			Elapsed:   time.Duration(i+1) * 10 * time.Second,
+			Stats:     Stats{Success: int(reqPerSec[i] * 10), P99Ms: p99Ms[i], Elapsed: 10 * time.Second},
+			DataSize:  dataSize[i],
+			HeapBytes: -1,
+		}
+	}
+	return samples
+}
+
+/*
+TestJudgeSoak vérifie le verdict : un serveur au régime constant est
+stable, même après une première fenêtre de montée en charge ; une baisse de
+throughput, une hausse du p99 ou une map qui grandit le rendent dégradé.
+*/
+func TestJudgeSoak(t *testing.T) {
+	tests := []struct {
+		name      string
+		reqPerSec []float64
+		p99Ms     []float64
+		dataSize  []int64
+		reasons   []string
+	}{
+		{"stable", []float64{50, 600, 610, 590, 600, 605}, []float64{90, 30, 31, 29, 30, 32},
+			[]int64{500, 6000, 6000, 6000, 6000, 6000}, nil},
+		{"throughput drop", []float64{600, 600, 550, 500, 450, 400}, []float64{30, 30, 30, 30, 30, 30},
+			[]int64{-1, -1, -1, -1, -1, -1}, []string{"throughput en baisse"}},
+		{"unbounded map", []float64{600, 600, 580, 560, 540, 520}, []float64{30, 30, 35, 40, 45, 50},
+			[]int64{6000, 12000, 18000, 24000, 30000, 36000}, []string{"p99 en hausse", "map sans borne"}},
+		{"too short", []float64{600, 600}, []float64{30, 30}, []int64{6000, 12000}, []string{"au moins 3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := JudgeSoak(soakSamples(tt.reqPerSec, tt.p99Ms, tt.dataSize), DefaultSoakTolerance)
+			if len(v.Reasons) != len(tt.reasons) {
+				t.Fatalf("reasons = %q, want %d matching %q", v.Reasons, len(tt.reasons), tt.reasons)
+			}
+			for i, want := range tt.reasons {
+				if !strings.Contains(v.Reasons[i], want) {
+					t.Errorf("reason %d = %q, want %q", i, v.Reasons[i], want)
+				}
+			}
+		})
+	}
+
+	v := JudgeSoak(soakSamples([]float64{50, 600, 600, 600}, []float64{90, 30, 30, 30}, []int64{-1, -1, -1, -1}), DefaultSoakTolerance)
+	var out bytes.Buffer
+	WriteSoakVerdict(&out, v, DefaultSoakTolerance)
+	if !strings.Contains(out.String(), "STABLE") || strings.Contains(out.String(), "data_size") {
+		t.Errorf("verdict without gauges:\n%s", out.String())
+	}
+}
+
+/*
+TestSoak charge un serveur de test pendant 3 fenêtres de 100ms : chaque
+fenêtre compte ses requêtes et relève data_size et heap_alloc_bytes de
+/stats.
+*/
+func TestSoak(t *testing.T) {
+	var served atomic.Int64
+	mux := http.NewServeMux()
+	mux.HandleFunc("/process", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		served.Add(1)
+	})
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"data_size": %d, "heap_alloc_bytes": 1048576}`, served.Load())
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var lines bytes.Buffer
+	samples := Soak(context.Background(), srv.URL+"/process", srv.URL+"/stats", 2, 300*time.Millisecond, 100*time.Millisecond,
+		func(s SoakSample) { WriteSoakSample(&lines, s) })
+	if len(samples) != 3 {
+		t.Fatalf("%d samples, want 3", len(samples))
+	}
+	for i, s := range samples {
+		if s.Stats.Success == 0 || s.Stats.Failed != 0 {
+			t.Errorf("sample %d: %+v, want successes and no failure", i, s.Stats)
+		}
+		if s.DataSize <= 0 || s.HeapBytes != 1<<20 {
+			t.Errorf("sample %d: data_size %d, heap %d", i, s.DataSize, s.HeapBytes)
+		}
+	}
+	if got := strings.Count(lines.String(), "\n"); got != 3 || !strings.Contains(lines.String(), "tas 1.0 Mio") {
+		t.Errorf("sample lines:\n%s", lines.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if samples := Soak(ctx, srv.URL+"/process", "", 2, time.Second, 100*time.Millisecond, nil); len(samples) != 0 {
+		t.Errorf("canceled soak returned %d samples", len(samples))
+	}
+}
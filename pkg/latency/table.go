@@ -0,0 +1,157 @@
+package latency
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Couleurs ANSI du tableau de comparaison
+const (
+	ColorReset  = "\033[0m"
+	ColorRed    = "\033[31m"
+	ColorGreen  = "\033[32m"
+	ColorYellow = "\033[33m"
+	ColorBlue   = "\033[34m"
+	ColorCyan   = "\033[36m"
+	ColorWhite  = "\033[37m"
+	Bold        = "\033[1m"
+)
+
+/*
+RankColor choisit la couleur d'une latence relativement aux autres latences
+de la même ligne, indépendamment de la vitesse absolue de la machine.
+
+@params:
+  - value: float64 latence à colorer
+  - row: []float64 latences de la ligne, value comprise
+
+@returns: string ColorGreen pour la plus faible, ColorRed pour la plus forte,
+ColorYellow sinon (et si toute la ligne est égale, ColorGreen)
+*/
+func RankColor(value float64, row []float64) string {
+	best, worst := value, value
+	for _, v := range row {
+		best = math.Min(best, v)
+		worst = math.Max(worst, v)
+	}
+	switch {
+	case value == best:
+		return ColorGreen
+	case value == worst:
+		return ColorRed
+	default:
+		return ColorYellow
+	}
+}
+
+/*
+Cell formate une cellule du tableau de comparaison.
+
+@params:
+  - stats: Stats mesure d'un serveur à un niveau de concurrence
+
+@returns: string "latence ms / throughput req/s"
+*/
+func Cell(stats Stats) string {
+	return fmt.Sprintf("%.2f / %.0f r/s", stats.AvgMs, stats.ReqPerSec())
+}
+
+/*
+ParseLevels convertit une liste de niveaux de concurrence.
+
+@params:
+  - raw: string liste séparée par des virgules (ex: "1,25,250,1000")
+
+@returns: ([]int, error) - Niveaux, erreur s'ils ne sont pas positifs et strictement croissants
+*/
+func ParseLevels(raw string) ([]int, error) {
+	var levels []int
+	for _, field := range strings.Split(raw, ",") {
+		level, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("invalid level %q", field)
+		}
+		if level <= 0 {
+			return nil, fmt.Errorf("level %d must be positive", level)
+		}
+		if n := len(levels); n > 0 && level <= levels[n-1] {
+			return nil, fmt.Errorf("levels must be sorted in increasing order, got %d after %d", level, levels[n-1])
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+/*
+Server désigne une colonne du tableau de comparaison.
+
+@fields:
+  - Name: Titre de la colonne (ex: "Bad (defer)")
+  - Label: Nom court affiché dans la meilleure amélioration (ex: "GOOD")
+  - URL: Endpoint mesuré
+*/
+type Server struct {
+	Name  string
+	Label string
+	URL   string
+}
+
+/*
+Compare mesure chaque serveur à chaque niveau de concurrence et écrit le
+tableau comparatif. Le premier serveur sert de référence : la dernière
+colonne donne la meilleure amélioration de latence par rapport à lui.
+
+@params:
+  - w: io.Writer destination du tableau
+  - servers: []Server colonnes, la référence en premier
+  - levels: []int niveaux de concurrence, un par ligne
+  - requests: int requêtes par serveur et par niveau (au moins une par goroutine)
+*/
+func Compare(w io.Writer, servers []Server, levels []int, requests int) {
+	fmt.Fprintf(w, "%s%-12s", Bold, "Concurrency")
+	for _, srv := range servers {
+		fmt.Fprintf(w, " | %-18s", srv.Name)
+	}
+	fmt.Fprintf(w, " | %s%s\n", "Best Improvement", ColorReset)
+	fmt.Fprintln(w, "━━━━━━━━━━━━━"+strings.Repeat("┳━━━━━━━━━━━━━━━━━━━━", len(servers))+"┳━━━━━━━━━━━━━━━━")
+
+	for _, concurrency := range levels {
+		totalRequests := requests
+		if concurrency > totalRequests {
+			totalRequests = concurrency
+		}
+		stats := make([]Stats, len(servers))
+		row := make([]float64, len(servers))
+		for i, srv := range servers {
+			stats[i] = Measure(srv.URL, concurrency, totalRequests, 0)
+			row[i] = stats[i].AvgMs
+		}
+
+		fmt.Fprintf(w, "%s%-12d%s", ColorWhite, concurrency, ColorReset)
+		for i := range servers {
+			fmt.Fprintf(w, " ┃ %s%-18s%s", RankColor(row[i], row), Cell(stats[i]), ColorReset)
+		}
+
+		best, bestLabel := math.Inf(-1), ""
+		for i := 1; i < len(servers); i++ {
+			if improvement := (row[0] - row[i]) / row[0] * 100; stats[i].Success > 0 && improvement > best {
+				best, bestLabel = improvement, servers[i].Label
+			}
+		}
+		switch {
+		case bestLabel == "":
+			fmt.Fprintln(w, " ┃ n/a")
+		case best > 0:
+			fmt.Fprintf(w, " ┃ %s%s+%.1f%% (%s)%s\n", ColorGreen, Bold, best, bestLabel, ColorReset)
+		default:
+			fmt.Fprintf(w, " ┃ %s%.1f%%%s\n", ColorRed, best, ColorReset)
+		}
+	}
+
+	fmt.Fprintln(w, "\n• Chaque cellule : latence moyenne (ms) / throughput (requêtes réussies par seconde de mesure)")
+	fmt.Fprintf(w, "• Couleur des cellules, par ligne : %splus faible latence%s, %splus forte%s, %sentre les deux%s\n",
+		ColorGreen, ColorReset, ColorRed, ColorReset, ColorYellow, ColorReset)
+}
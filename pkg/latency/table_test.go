@@ -0,0 +1,43 @@
+package latency
+
+import (
+	"fmt"
+	"testing"
+)
+
+/*
+TestRankColor vérifie la coloration relative d'une ligne du tableau.
+*/
+func TestRankColor(t *testing.T) {
+	row := []float64{112.3, 14.2, 16.4, 109.3}
+	want := []string{ColorRed, ColorGreen, ColorYellow, ColorYellow}
+	for i, v := range row {
+		if got := RankColor(v, row); got != want[i] {
+			t.Errorf("RankColor(%v) = %q, want %q", v, got, want[i])
+		}
+	}
+
+	// Ligne uniforme : pas de pire serveur
+	if got := RankColor(10, []float64{10, 10}); got != ColorGreen {
+		t.Errorf("uniform row: RankColor = %q, want green", got)
+	}
+}
+
+/*
+TestParseLevels vérifie l'analyse et la validation des niveaux de concurrence (flag -levels).
+*/
+func TestParseLevels(t *testing.T) {
+	levels, err := ParseLevels("1, 25,250,1000")
+	if err != nil {
+		t.Fatalf("ParseLevels: %v", err)
+	}
+	if fmt.Sprint(levels) != "[1 25 250 1000]" {
+		t.Errorf("levels = %v, want [1 25 250 1000]", levels)
+	}
+
+	for _, raw := range []string{"", "1,,10", "0,10", "-5", "10,1", "10,10", "1,x"} {
+		if _, err := ParseLevels(raw); err == nil {
+			t.Errorf("ParseLevels(%q) succeeded, want error", raw)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package latency
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// throughputBucket est la largeur d'un intervalle de sampleThroughput
+const throughputBucket = 100 * time.Millisecond
+
+// sparkLevels sont les caractères d'une sparkline, du bucket vide au maximum
+var sparkLevels = []rune(" ▁▂▃▄▅▆▇█")
+
+/*
+sampleThroughput charge url pendant duration et compte les requêtes
+terminées (200) par intervalle de throughputBucket. Là où Measure ne donne
+qu'un total, la série montre la montée en charge, le régime établi et un
+éventuel effondrement en cours de mesure, par exemple un serveur bad qui
+ralentit à mesure que sa map grandit.
+
+Un compteur atomique est incrémenté par les workers et relevé à chaque tick
+d'un ticker : les workers ne partagent aucun autre état. À la fin, les
+requêtes encore en vol sont annulées et ne sont pas comptées.
+
+@params:
+  - url: string URL du serveur à charger
+  - concurrency: int nombre de requêtes en vol (une boucle par worker)
+  - duration: time.Duration durée de la mesure, arrondie au bucket supérieur
+
+@returns: []int requêtes terminées par bucket, dans l'ordre chronologique
+*/
+func sampleThroughput(url string, concurrency int, duration time.Duration) []int {
+	buckets := int((duration + throughputBucket - 1) / throughputBucket)
+	if buckets <= 0 || concurrency <= 0 {
+		return nil
+	}
+
+	client, closeIdle := limitedClient(concurrency)
+	defer closeIdle()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var completed atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				status, err := contextGet(ctx, client, url)
+				switch {
+				case err == nil && status == http.StatusOK:
+					completed.Add(1)
+				case err != nil && ctx.Err() == nil:
+					// Serveur injoignable : pas de boucle active en attendant la fin
+					time.Sleep(throughputBucket / 10)
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(throughputBucket)
+	defer ticker.Stop()
+	counts := make([]int, 0, buckets)
+	var last int64
+	for len(counts) < buckets {
+		<-ticker.C
+		now := completed.Load()
+		counts = append(counts, int(now-last))
+		last = now
+	}
+
+	cancel()
+	wg.Wait()
+	return counts
+}
+
+/*
+contextGet effectue une requête GET annulable et lit tout le corps.
+
+@params:
+  - ctx: context.Context annulé à la fin de la mesure
+  - client: *http.Client client HTTP à utiliser
+  - url: string URL à interroger
+
+@returns: (int, error) - Code HTTP et erreur éventuelle (annulation comprise)
+*/
+func contextGet(ctx context.Context, client *http.Client, url string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return 0, err
+	}
+	return resp.StatusCode, nil
+}
+
+/*
+sparkline représente une série de comptes sur une ligne de texte, à
+l'échelle de son maximum. Un bucket vide est une espace : un arrêt complet
+du serveur apparaît comme un trou dans la ligne.
+
+@params:
+  - counts: []int valeurs à représenter, positives ou nulles
+
+@returns: string un caractère par valeur
+*/
+func sparkline(counts []int) string {
+	peak := 0
+	for _, c := range counts {
+		if c > peak {
+			peak = c
+		}
+	}
+	top := len(sparkLevels) - 1
+	var b strings.Builder
+	for _, c := range counts {
+		level := 0
+		if c > 0 {
+			// Arrondi supérieur : toute valeur non nulle reste visible
+			level = (c*top + peak - 1) / peak
+		}
+		b.WriteRune(sparkLevels[level])
+	}
+	return b.String()
+}
+
+/*
+WriteSparkline écrit une série de sampleThroughput : son nom, sa sparkline
+et les comptes minimum, moyen et maximum par bucket.
+
+@params:
+  - w: io.Writer destination de la ligne
+  - name: string nom de la série (ex: "Bad (defer)")
+  - counts: []int requêtes terminées par bucket
+*/
+func WriteSparkline(w io.Writer, name string, counts []int) {
+	if len(counts) == 0 {
+		fmt.Fprintf(w, "%-20s (no sample)\n", name)
+		return
+	}
+	low, high, sum := counts[0], counts[0], 0
+	for _, c := range counts {
+		if c < low {
+			low = c
+		}
+		if c > high {
+			high = c
+		}
+		sum += c
+	}
+	fmt.Fprintf(w, "%-20s |%s| min %d  avg %.1f  max %d req/%s\n",
+		name, sparkline(counts), low, float64(sum)/float64(len(counts)), high, throughputBucket)
+}
+
+/*
+CompareThroughput charge chaque serveur l'un après l'autre pendant duration
+et écrit sa série de throughput sous forme de sparkline.
+
+@params:
+  - w: io.Writer destination des lignes
+  - servers: []Server serveurs à charger, une ligne chacun
+  - concurrency: int nombre de requêtes en vol
+  - duration: time.Duration durée de mesure par serveur
+*/
+func CompareThroughput(w io.Writer, servers []Server, concurrency int, duration time.Duration) {
+	for _, srv := range servers {
+		WriteSparkline(w, srv.Name, sampleThroughput(srv.URL, concurrency, duration))
+	}
+}
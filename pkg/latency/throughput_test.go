@@ -0,0 +1,60 @@
+package latency
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+TestSparkline vérifie la mise à l'échelle d'une sparkline : le maximum est le
+plus haut caractère, un bucket vide une espace, et toute valeur non nulle
+reste visible.
+*/
+func TestSparkline(t *testing.T) {
+	if got, want := sparkline([]int{0, 1, 50, 100}), " ▁▄█"; got != want {
+		t.Errorf("sparkline = %q, want %q", got, want)
+	}
+	if got := sparkline([]int{0, 0}); got != "  " {
+		t.Errorf("sparkline of empty buckets = %q, want spaces", got)
+	}
+
+	var out bytes.Buffer
+	WriteSparkline(&out, "bad", []int{2, 4, 6})
+	if line := out.String(); !strings.Contains(line, "min 2  avg 4.0  max 6 req/100ms") {
+		t.Errorf("WriteSparkline = %q", line)
+	}
+}
+
+/*
+TestSampleThroughput charge un serveur qui répond en 10ms avec un seul
+worker : au plus une requête se termine par 10ms, et la série a un bucket
+par 100ms de mesure.
+*/
+func TestSampleThroughput(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	counts := sampleThroughput(srv.URL, 1, 450*time.Millisecond)
+	elapsed := time.Since(start)
+	if len(counts) != 5 {
+		t.Fatalf("%d buckets, want 5 (450ms rounded up): %v", len(counts), counts)
+	}
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	// Un seul worker : au plus une requête terminée par 10ms de mesure
+	if max := int(elapsed/(10*time.Millisecond)) + 1; total > max {
+		t.Errorf("%d requests completed in %v, want at most %d: %v", total, elapsed, max, counts)
+	}
+	if total == 0 {
+		t.Errorf("no request completed: %v", counts)
+	}
+}
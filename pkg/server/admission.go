@@ -0,0 +1,328 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/semaphore"
+)
+
+// admissionRetryAfter est le délai (en secondes) annoncé par Retry-After sur un rejet
+const admissionRetryAfter = 1
+
+/*
+AdmissionRepository combine les sections critiques courtes du serveur good avec
+un contrôle d'admission : une requête attend au plus budget un jeton du
+sémaphore, puis est rejetée (503 + Retry-After) au lieu de rejoindre une file
+sans fin. Il modélise un service qui se déleste sous surcharge.
+
+@fields:
+  - mu: Mutex protégeant les données, tenu le temps des seules sections critiques
+  - counter: Compteur global des requêtes traitées
+  - data: Map simulant des données métier partagées avec structure complexe
+  - copyData: Active la copie des données avant traitement (workload "lecture puis traitement")
+  - work: Traitement lourd des requêtes admises, hors mutex
+  - mapCap: Nombre maximum d'entrées de data (0 = illimité)
+  - payload: Taille en octets du blob Payload de chaque entrée écrite
+  - enc: Encodeur des réponses de /process (voir -encoder)
+  - sem: Sémaphore bornant le nombre de requêtes admises simultanément
+  - budget: Attente maximum d'un jeton avant rejet (0 = fail-fast)
+  - admitted: Nombre de requêtes admises sur /process
+  - rejected: Nombre de requêtes rejetées faute de jeton dans le budget
+*/
+type AdmissionRepository struct {
+	mu       sync.Mutex
+	counter  int
+	data     map[string]*DataStruct
+	copyData bool
+	work     WorkFunc
+	mapCap   int
+	payload  int
+	enc      Encoder
+	sem      *semaphore.Weighted
+	budget   time.Duration
+	admitted atomic.Int64
+	rejected atomic.Int64
+}
+
+/*
+NewAdmissionRepository crée et initialise un nouveau repository à admission bornée.
+
+@params:
+  - copyData: bool active la copie des données partagées à chaque requête
+  - limit: int64 nombre maximum de requêtes admises simultanément
+  - budget: time.Duration attente maximum d'un jeton (0 = rejet immédiat si aucun n'est libre)
+
+@returns: *AdmissionRepository - Nouvelle instance avec la map et le sémaphore initialisés
+*/
+func NewAdmissionRepository(copyData bool, limit int64, budget time.Duration) *AdmissionRepository {
+	return newAdmissionRepository(newRepoOptions(WithCopyData(copyData)), limit, budget)
+}
+
+/*
+newAdmissionRepository crée le repository à partir d'options déjà appliquées,
+pour que le délestage porte sur le même traitement que les autres variantes.
+
+@params:
+  - o: repoOptions configuration (copie, borne de la map, traitement, payload, encodeur)
+  - limit: int64 nombre maximum de requêtes admises simultanément
+  - budget: time.Duration attente maximum d'un jeton (0 = rejet immédiat si aucun n'est libre)
+
+@returns: *AdmissionRepository - Nouvelle instance avec la map et le sémaphore initialisés
+*/
+func newAdmissionRepository(o repoOptions, limit int64, budget time.Duration) *AdmissionRepository {
+	return &AdmissionRepository{
+		data:     make(map[string]*DataStruct),
+		copyData: o.copyData,
+		work:     o.work,
+		mapCap:   o.mapCap,
+		payload:  o.payload,
+		enc:      o.encoder,
+		sem:      semaphore.NewWeighted(limit),
+		budget:   budget,
+	}
+}
+
+/*
+admit attend un jeton du sémaphore pendant au plus budget.
+
+@params:
+  - ctx: context.Context contexte de la requête
+
+@returns: bool - true si un jeton a été obtenu (à rendre avec sem.Release)
+*/
+func (r *AdmissionRepository) admit(ctx context.Context) bool {
+	if r.budget <= 0 {
+		return r.sem.TryAcquire(1)
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.budget)
+	defer cancel()
+	return r.sem.Acquire(ctx, 1) == nil
+}
+
+/*
+AdmissionHandler traite /process si la requête est admise dans le budget.
+La latence d'une requête admise est bornée par budget plus un traitement ;
+au-delà de la capacité, le client reçoit un refus rapide qu'il peut rejouer.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@behavior:
+  1. Attend un jeton au plus budget : sinon 503 avec Retry-After, compté dans rejected
+  2. Verrouille le mutex pour la lecture/copie des données, puis le libère
+  3. Effectue le traitement lourd SANS le mutex
+  4. Re-verrouille uniquement pour l'écriture finale, puis rend le jeton
+
+@performance: Latence bornée sous surcharge, throughput plafonné par la limite
+*/
+func (r *AdmissionRepository) AdmissionHandler(w http.ResponseWriter, req *http.Request) {
+	includeData, ok := parseIncludeData(w, req)
+	if !ok {
+		return
+	}
+	start := time.Now()
+	ctx, span := startSpan(req.Context(), "admission.process")
+	defer span.End()
+
+	admitSpan := startPhase(ctx, "admission.wait")
+	admitted := r.admit(ctx)
+	admitSpan.End()
+	if !admitted {
+		// Un client parti ne compte pas comme un rejet de surcharge
+		if req.Context().Err() != nil {
+			writeError(w, http.StatusServiceUnavailable, "request cancelled while waiting for admission")
+			return
+		}
+		r.rejected.Add(1)
+		span.SetAttributes(attribute.Bool("admission.rejected", true))
+		w.Header().Set("Retry-After", strconv.Itoa(admissionRetryAfter))
+		writeError(w, http.StatusServiceUnavailable, "overloaded, retry later")
+		return
+	}
+	r.admitted.Add(1)
+
+	// Première acquisition du mutex pour lecture
+	tracedLock(ctx, "mu.read", r.mu.Lock)
+	r.counter++
+	currentCounter := r.counter
+	// La copie modélise un workload "lecture puis traitement" : son coût
+	// croît avec la taille de la map, elle est donc désactivable (-copy=false)
+	dataCopy := make(map[string]*DataStruct)
+	if r.copyData {
+		for k, v := range r.data {
+			dataCopy[k] = &DataStruct{
+				Identifier:   v.Identifier,
+				Name:         v.Name,
+				IsActive:     v.IsActive,
+				Counter:      v.Counter,
+				LastModified: v.LastModified,
+				Payload:      bytes.Clone(v.Payload),
+			}
+		}
+	}
+	r.mu.Unlock() // Libération immédiate après la lecture
+
+	// Traitement lourd SANS le mutex
+	heavySpan := startPhase(ctx, "process.heavy")
+	result := r.work(ctx) // Attente simulée puis calcul intensif
+	heavySpan.End()
+
+	// Deuxième acquisition du mutex uniquement pour l'écriture
+	key := fmt.Sprintf("request_%d", currentCounter)
+	payload := newPayload(r.payload) // Allouée avant de reprendre le mutex
+	writeSpan := startPhase(ctx, "write")
+	tracedLock(ctx, "mu.write", r.mu.Lock)
+	makeRoom(r.data, key, r.mapCap)
+	r.data[key] = &DataStruct{
+		Identifier:   key,
+		Name:         fmt.Sprintf("Request %d", currentCounter),
+		IsActive:     true,
+		Counter:      result,
+		LastModified: time.Now(),
+		Payload:      payload,
+	}
+	r.mu.Unlock() // Libération immédiate après l'écriture
+	writeSpan.End()
+	r.sem.Release(1)
+
+	response := newResponse(req, "admission", start)
+	response.Counter = currentCounter
+	response.Result = result
+	response.SnapshotSize = len(dataCopy)
+
+	if includeData {
+		addSnapshot(response, dataCopy)
+	}
+	writeEncoded(w, http.StatusOK, response, r.enc)
+}
+
+/*
+evictExpired supprime les entrées dont LastModified précède cutoff.
+Appelée par le janitor, sous le mutex le temps du seul balayage.
+
+@params:
+  - cutoff: time.Time date limite de dernière modification
+
+@returns: int - Nombre d'entrées supprimées
+*/
+func (r *AdmissionRepository) evictExpired(cutoff time.Time) int {
+	r.mu.Lock()
+	evicted := 0
+	for key, entry := range r.data {
+		if entry.LastModified.Before(cutoff) {
+			delete(r.data, key)
+			evicted++
+		}
+	}
+	r.mu.Unlock()
+	return evicted
+}
+
+/*
+GetDataHandler retourne l'entrée {id} en JSON, ou 404 si elle est absente.
+Les lectures ne passent pas par l'admission : seul /process est délesté.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+
+@returns: JSON DataStruct
+*/
+func (r *AdmissionRepository) GetDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.mu.Lock()
+	var entry DataStruct
+	v, found := r.data[id]
+	if found {
+		entry = *v
+	}
+	r.mu.Unlock()
+
+	writeData(w, id, entry, found)
+}
+
+/*
+DeleteDataHandler supprime l'entrée {id} : 204 si supprimée, 404 si absente.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+*/
+func (r *AdmissionRepository) DeleteDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.mu.Lock()
+	_, found := r.data[id]
+	delete(r.data, id)
+	r.mu.Unlock()
+
+	writeDeleted(w, id, found)
+}
+
+/*
+StatsHandler retourne les statistiques actuelles du serveur.
+Les compteurs d'admission sont atomiques et lus sans le mutex.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON contenant total_requests, data_size, admitted, rejected,
+rejection_rate et budget_ms
+*/
+func (r *AdmissionRepository) StatsHandler(w http.ResponseWriter, req *http.Request) {
+	admitted := r.admitted.Load()
+	rejected := r.rejected.Load()
+	rejectionRate := 0.0
+	if attempts := admitted + rejected; attempts > 0 {
+		rejectionRate = float64(rejected) / float64(attempts)
+	}
+
+	r.mu.Lock()
+	stats := map[string]interface{}{
+		"total_requests": r.counter,
+		"data_size":      len(r.data),
+		"admitted":       admitted,
+		"rejected":       rejected,
+		"rejection_rate": rejectionRate,
+		"budget_ms":      float64(r.budget) / float64(time.Millisecond),
+	}
+	r.mu.Unlock()
+
+	writeStats(w, stats)
+}
+
+/*
+Router configure les routes du serveur avec gorilla/mux.
+
+@returns: *mux.Router - Routeur prêt à être servi
+
+@endpoints:
+  - GET /process : Admission bornée par -budget, 503 + Retry-After au-delà
+  - GET /data/{id} : Lecture d'une entrée (404 si absente)
+  - DELETE /data/{id} : Suppression d'une entrée
+  - GET /stats : Statistiques du serveur (dont admitted et rejected)
+  - GET /debug/allocs : Compteurs d'allocation du processus
+  - GET /debug/contention : Résumé du profil de contention des mutex
+*/
+func (r *AdmissionRepository) Router() *mux.Router {
+	router := newRouter()
+	router.HandleFunc("/process", r.AdmissionHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.GetDataHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.DeleteDataHandler).Methods("DELETE")
+	router.HandleFunc("/stats", r.StatsHandler).Methods("GET")
+	router.HandleFunc("/debug/allocs", AllocsHandler).Methods("GET")
+	router.HandleFunc("/debug/contention", ContentionHandler).Methods("GET")
+	return router
+}
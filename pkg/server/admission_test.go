@@ -0,0 +1,57 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+/*
+TestAdmissionRejectsAfterBudget occupe le seul jeton du sémaphore comme le
+ferait une requête en cours, puis vérifie que /process attend le budget avant
+de répondre 503 avec Retry-After, et que /stats compte admissions et rejets.
+*/
+func TestAdmissionRejectsAfterBudget(t *testing.T) {
+	const budget = 20 * time.Millisecond
+	repo := NewAdmissionRepository(true, 1, budget)
+	handler := repo.Router()
+	process := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/process", nil))
+		return rec
+	}
+
+	repo.sem.TryAcquire(1)
+	start := time.Now()
+	rec := process()
+	if elapsed := time.Since(start); elapsed < budget {
+		t.Errorf("rejected after %v, want to wait the %v budget", elapsed, budget)
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("saturated status = %d, want 503", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want \"1\"", got)
+	}
+	repo.sem.Release(1)
+
+	if rec := process(); rec.Code != http.StatusOK {
+		t.Errorf("admitted status = %d, want 200", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	var stats struct {
+		Admitted      int64   `json:"admitted"`
+		Rejected      int64   `json:"rejected"`
+		RejectionRate float64 `json:"rejection_rate"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if stats.Admitted != 1 || stats.Rejected != 1 || stats.RejectionRate != 0.5 {
+		t.Errorf("stats = %+v, want 1 admitted, 1 rejected, rate 0.5", stats)
+	}
+}
@@ -0,0 +1,320 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+/*
+AtomicValueRepository publie la map par copy-on-write : la map courante
+n'est jamais modifiée, les lecteurs chargent son pointeur sans aucun verrou,
+et chaque écriture construit une nouvelle map qu'elle publie par Store.
+Le pointeur est un atomic.Pointer, la forme typée d'atomic.Value.
+
+Le compromis est l'amplification d'écriture : une écriture copie toute la
+map, O(n) en temps et en allocations, là où le RWMutex n'ajoute qu'une clé.
+Le modèle convient aux données lues en permanence et rarement modifiées
+(configuration, tables de routage), pas à une map qui grandit à chaque requête.
+
+@fields:
+  - current: Map publiée, immuable une fois stockée (chargée sans verrou)
+  - writeMu: Sérialise les écritures, pour qu'aucune copie n'en écrase une autre
+  - counter: Compteur atomique des requêtes /process
+  - mapCopies: Nombre de maps construites et publiées par les écritures
+  - entriesCopied: Nombre total d'entrées recopiées par ces écritures
+  - copyData: Renvoie la map courante comme copie (workload "lecture puis traitement")
+  - mapCap: Nombre maximum d'entrées de la map (0 = illimité)
+  - work: Traitement lourd de /process
+  - payload: Taille en octets du blob Payload de chaque entrée écrite
+  - enc: Encodeur des réponses de /process (voir -encoder)
+*/
+type AtomicValueRepository struct {
+	current       atomic.Pointer[map[string]*DataStruct]
+	writeMu       sync.Mutex
+	counter       atomic.Int64
+	mapCopies     atomic.Int64
+	entriesCopied atomic.Int64
+	copyData      bool
+	mapCap        int
+	work          WorkFunc
+	payload       int
+	enc           Encoder
+}
+
+/*
+NewAtomicValueRepository crée et initialise un nouveau repository copy-on-write.
+
+@params:
+  - copyData: bool renvoie la map courante comme copie à chaque requête
+
+@returns: *AtomicValueRepository - Nouvelle instance publiant une map vide
+*/
+func NewAtomicValueRepository(copyData bool) *AtomicValueRepository {
+	return newAtomicValueRepository(newRepoOptions(WithCopyData(copyData)))
+}
+
+/*
+newAtomicValueRepository crée un repository à partir d'options déjà appliquées.
+
+@params:
+  - o: repoOptions configuration (copie, borne de la map, traitement, payload, encodeur)
+
+@returns: *AtomicValueRepository - Nouvelle instance publiant une map vide
+*/
+func newAtomicValueRepository(o repoOptions) *AtomicValueRepository {
+	r := &AtomicValueRepository{copyData: o.copyData, mapCap: o.mapCap, work: o.work, payload: o.payload, enc: o.encoder}
+	empty := make(map[string]*DataStruct)
+	r.current.Store(&empty)
+	return r
+}
+
+/*
+load retourne la map publiée. Elle ne doit pas être modifiée : un écrivain
+construit une nouvelle map avec update.
+
+@returns: map[string]*DataStruct - Map courante, immuable
+*/
+func (r *AtomicValueRepository) load() map[string]*DataStruct {
+	return *r.current.Load()
+}
+
+/*
+update recopie la map courante, applique change à la copie puis la publie.
+writeMu sérialise les écritures : sans lui, deux écrivains partant de la
+même map publieraient chacun leur copie et l'une des écritures serait perdue.
+
+@params:
+  - change: func(map[string]*DataStruct) modification de la nouvelle map, sous writeMu
+*/
+func (r *AtomicValueRepository) update(change func(next map[string]*DataStruct)) {
+	r.writeMu.Lock()
+	old := r.load()
+	next := make(map[string]*DataStruct, len(old)+1)
+	for k, v := range old {
+		next[k] = v
+	}
+	change(next)
+	r.current.Store(&next)
+	r.writeMu.Unlock()
+
+	r.mapCopies.Add(1)
+	r.entriesCopied.Add(int64(len(old)))
+}
+
+/*
+AtomicValueHandler traite une requête avec la map copy-on-write.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@behavior:
+  1. Incrémente le compteur atomiquement
+  2. Charge la map courante sans verrou : immuable, elle sert de copie telle quelle
+  3. Effectue le traitement lourd sans verrou
+  4. Recopie la map avec la nouvelle entrée et la publie (update, sous writeMu)
+
+@performance: Lectures sans verrou ni copie ; chaque écriture copie toute la map
+*/
+func (r *AtomicValueRepository) AtomicValueHandler(w http.ResponseWriter, req *http.Request) {
+	includeData, ok := parseIncludeData(w, req)
+	if !ok {
+		return
+	}
+	start := time.Now()
+	currentCounter := int(r.counter.Add(1))
+	ctx, span := startSpan(req.Context(), "atomicvalue.process")
+	defer span.End()
+
+	// La map publiée n'est jamais modifiée : la lire ne demande ni verrou
+	// ni copie, quelle que soit sa taille
+	var dataCopy map[string]*DataStruct
+	if r.copyData {
+		dataCopy = r.load()
+	}
+
+	heavySpan := startPhase(ctx, "process.heavy")
+	result := r.work(ctx) // Attente simulée puis calcul intensif
+	heavySpan.End()
+
+	key := fmt.Sprintf("request_%d", currentCounter)
+	entry := &DataStruct{
+		Identifier:   key,
+		Name:         fmt.Sprintf("Request %d", currentCounter),
+		IsActive:     true,
+		Counter:      result,
+		LastModified: time.Now(),
+		Payload:      newPayload(r.payload),
+	}
+	writeSpan := startPhase(ctx, "write")
+	r.update(func(next map[string]*DataStruct) {
+		makeRoom(next, key, r.mapCap)
+		next[key] = entry
+	})
+	writeSpan.End()
+
+	response := newResponse(req, "atomic_value", start)
+	response.Counter = currentCounter
+	response.Result = result
+	response.SnapshotSize = len(dataCopy)
+
+	if includeData {
+		addSnapshot(response, dataCopy)
+	}
+	writeEncoded(w, http.StatusOK, response, r.enc)
+}
+
+/*
+seed ajoute n entrées synthétiques (seed_0 à seed_n-1) en une seule copie
+de la map : insérées une par une, elles coûteraient n copies.
+
+@params:
+  - n: int nombre d'entrées à insérer
+*/
+func (r *AtomicValueRepository) seed(n int) {
+	r.update(func(next map[string]*DataStruct) {
+		for i := 0; i < n; i++ {
+			entry := seedEntry(i, r.payload)
+			makeRoom(next, entry.Identifier, r.mapCap)
+			next[entry.Identifier] = entry
+		}
+	})
+}
+
+/*
+evictExpired supprime les entrées dont LastModified précède cutoff.
+Le balayage lit la map courante sans verrou ; une copie n'est publiée que
+s'il y a des entrées à supprimer.
+
+@params:
+  - cutoff: time.Time date limite de dernière modification
+
+@returns: int - Nombre d'entrées supprimées
+*/
+func (r *AtomicValueRepository) evictExpired(cutoff time.Time) int {
+	expired := false
+	for _, entry := range r.load() {
+		if entry.LastModified.Before(cutoff) {
+			expired = true
+			break
+		}
+	}
+	if !expired {
+		return 0
+	}
+
+	evicted := 0
+	r.update(func(next map[string]*DataStruct) {
+		for key, entry := range next {
+			if entry.LastModified.Before(cutoff) {
+				delete(next, key)
+				evicted++
+			}
+		}
+	})
+	return evicted
+}
+
+/*
+GetDataHandler retourne l'entrée {id} en JSON, ou 404 si elle est absente.
+La lecture charge la map courante : aucun verrou sur le chemin de lecture.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+
+@returns: JSON DataStruct
+*/
+func (r *AtomicValueRepository) GetDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	var entry DataStruct
+	v, found := r.load()[id]
+	if found {
+		entry = *v
+	}
+
+	writeData(w, id, entry, found)
+}
+
+/*
+DeleteDataHandler supprime l'entrée {id} : 204 si supprimée, 404 si absente.
+Une clé absente ne coûte pas de copie de la map.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+*/
+func (r *AtomicValueRepository) DeleteDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	found := false
+	if _, exists := r.load()[id]; exists {
+		r.update(func(next map[string]*DataStruct) {
+			_, found = next[id]
+			delete(next, id)
+		})
+	}
+
+	writeDeleted(w, id, found)
+}
+
+/*
+StatsHandler retourne les statistiques actuelles du serveur, avec la mesure
+de l'amplification d'écriture.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON contenant total_requests, data_size, map_copies,
+entries_copied et copied_per_write (entrées recopiées par écriture)
+*/
+func (r *AtomicValueRepository) StatsHandler(w http.ResponseWriter, req *http.Request) {
+	copies, copied := r.mapCopies.Load(), r.entriesCopied.Load()
+	perWrite := 0.0
+	if copies > 0 {
+		perWrite = float64(copied) / float64(copies)
+	}
+
+	stats := map[string]interface{}{
+		"total_requests":   r.counter.Load(),
+		"data_size":        len(r.load()),
+		"map_copies":       copies,
+		"entries_copied":   copied,
+		"copied_per_write": perWrite,
+	}
+
+	writeStats(w, stats)
+}
+
+/*
+Router configure les routes du serveur avec gorilla/mux.
+
+@returns: *mux.Router - Routeur prêt à être servi
+
+@endpoints:
+  - GET /process : Lecture sans verrou, écriture par copie de la map
+  - GET /data/{id} : Lecture d'une entrée sans verrou (404 si absente)
+  - DELETE /data/{id} : Suppression d'une entrée
+  - GET /stats : Statistiques du serveur (amplification d'écriture)
+  - GET /debug/allocs : Compteurs d'allocation du processus
+  - GET /debug/contention : Résumé du profil de contention des mutex
+  - POST /seed?n=N : Pré-remplissage avec N entrées synthétiques
+*/
+func (r *AtomicValueRepository) Router() *mux.Router {
+	router := newRouter()
+	router.HandleFunc("/process", r.AtomicValueHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.GetDataHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.DeleteDataHandler).Methods("DELETE")
+	router.HandleFunc("/stats", r.StatsHandler).Methods("GET")
+	router.HandleFunc("/debug/allocs", AllocsHandler).Methods("GET")
+	router.HandleFunc("/debug/contention", ContentionHandler).Methods("GET")
+	router.HandleFunc("/seed", seedHandler(r.seed)).Methods("POST")
+	return router
+}
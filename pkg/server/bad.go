@@ -0,0 +1,505 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+/*
+BadRepository contient les données partagées protégées par un mutex.
+Cette structure simule un état partagé typique dans une application Go.
+
+@fields:
+  - mu: Mutex pour protéger l'accès concurrent aux données
+  - counter: Compteur global des requêtes traitées
+  - data: Map simulant des données métier partagées avec structure complexe
+  - copyData: Active la copie des données avant traitement (workload "lecture puis traitement")
+  - lockHold: Durées de détention du mutex sur /process, le flux, l'upstream et les lots
+  - lockWait: Histogramme des attentes de TOUTES les acquisitions du mutex
+  - mapCap: Nombre maximum d'entrées de data (0 = illimité)
+  - work: Traitement lourd de /process et de chaque élément d'un lot, exécuté mutex verrouillé
+  - payload: Taille en octets du blob Payload de chaque entrée écrite, copié sous le mutex
+  - upstream: URL appelée par /process/upstream, mutex verrouillé ("" = non configuré)
+  - enc: Encodeur des réponses de /process, des lots et de l'upstream (voir -encoder)
+*/
+type BadRepository struct {
+	mu       sync.Mutex
+	counter  int
+	data     map[string]*DataStruct
+	copyData bool
+	lockHold lockHoldStats
+	lockWait lockWaitHistogram
+	mapCap   int
+	work     WorkFunc
+	payload  int
+	upstream string
+	enc      Encoder
+}
+
+/*
+NewBadRepository crée et initialise un nouveau repository.
+
+@params:
+  - copyData: bool active la copie des données partagées à chaque requête
+
+@returns: *BadRepository - Nouvelle instance avec la map initialisée
+*/
+func NewBadRepository(copyData bool) *BadRepository {
+	return newBadRepository(newRepoOptions(WithCopyData(copyData)))
+}
+
+/*
+newBadRepository crée un repository à partir d'options déjà appliquées.
+
+@params:
+  - o: repoOptions configuration (copie, borne de la map, traitement, payload, upstream, encodeur)
+
+@returns: *BadRepository - Nouvelle instance avec la map initialisée
+*/
+func newBadRepository(o repoOptions) *BadRepository {
+	return &BadRepository{
+		data:     make(map[string]*DataStruct),
+		copyData: o.copyData,
+		mapCap:   o.mapCap,
+		work:     o.work,
+		payload:  o.payload,
+		upstream: o.upstream,
+		enc:      o.encoder,
+	}
+}
+
+/*
+BadHandler démontre la MAUVAISE PRATIQUE d'utilisation des mutex avec defer.
+Le mutex reste verrouillé pendant toute la durée du traitement, incluant
+les opérations coûteuses qui n'ont pas besoin de protection.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@behavior:
+  1. Verrouille le mutex avec defer (reste verrouillé jusqu'à la fin)
+  2. Effectue des opérations sur les données partagées
+  3. Effectue un traitement lourd AVEC le mutex verrouillé
+  4. Le mutex n'est libéré qu'à la fin de la fonction
+
+@performance: Cette approche crée un goulot d'étranglement majeur
+*/
+func (r *BadRepository) BadHandler(w http.ResponseWriter, req *http.Request) {
+	includeData, ok := parseIncludeData(w, req)
+	if !ok {
+		return
+	}
+	start := time.Now()
+	ctx, span := startSpan(req.Context(), "bad.process")
+	defer span.End()
+
+	// Mauvaise pratique: le mutex reste verrouillé pendant TOUT le traitement
+	r.lockWait.record(tracedLock(ctx, "mu", r.mu.Lock))
+	defer r.mu.Unlock()
+	// Les defer s'exécutent en ordre inverse : la mesure précède le Unlock
+	lockStart := time.Now()
+	defer func() { r.lockHold.record(time.Since(lockStart)) }()
+
+	// Lecture et copie des données
+	r.counter++
+	currentCounter := r.counter
+	// La copie modélise un workload "lecture puis traitement" : son coût
+	// croît avec la taille de la map, elle est donc désactivable (-copy=false)
+	dataCopy := make(map[string]*DataStruct)
+	if r.copyData {
+		for k, v := range r.data {
+			dataCopy[k] = &DataStruct{
+				Identifier:   v.Identifier,
+				Name:         v.Name,
+				IsActive:     v.IsActive,
+				Counter:      v.Counter,
+				LastModified: v.LastModified,
+				Payload:      bytes.Clone(v.Payload),
+			}
+		}
+	}
+
+	// Simulation d'un traitement lourd (calcul, appel API, etc.)
+	// Le mutex reste verrouillé pendant ce temps !
+	heavySpan := startPhase(ctx, "process.heavy")
+	result := r.work(ctx) // Attente simulée puis calcul intensif
+	heavySpan.End()
+
+	// Écriture des résultats
+	writeSpan := startPhase(ctx, "write")
+	key := fmt.Sprintf("request_%d", currentCounter)
+	makeRoom(r.data, key, r.mapCap)
+	r.data[key] = &DataStruct{
+		Identifier:   key,
+		Name:         fmt.Sprintf("Request %d", currentCounter),
+		IsActive:     true,
+		Counter:      result,
+		LastModified: time.Now(),
+		Payload:      newPayload(r.payload),
+	}
+	writeSpan.End()
+
+	response := newResponse(req, "bad_defer", start)
+	response.Counter = currentCounter
+	response.Result = result
+	response.SnapshotSize = len(dataCopy)
+
+	if includeData {
+		addSnapshot(response, dataCopy)
+	}
+	writeEncoded(w, http.StatusOK, response, r.enc)
+}
+
+/*
+StreamHandler diffuse la progression du traitement lourd en NDJSON, mutex
+verrouillé avec defer : le verrou est tenu pendant TOUS les Flush, donc
+pendant le transfert réseau vers le client, en plus du calcul.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@behavior:
+  1. Verrouille le mutex avec defer
+  2. Envoie une ligne {"step", "steps", "partial"} après chaque étape du calcul
+  3. Écrit le résultat et envoie une dernière ligne {"done": true, ...}
+
+@performance: Un client lent à lire le flux bloque toutes les autres requêtes
+*/
+func (r *BadRepository) StreamHandler(w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+
+	// Mauvaise pratique: le mutex reste verrouillé pendant tout le flux
+	r.lockWait.record(tracedLock(req.Context(), "mu", r.mu.Lock))
+	defer r.mu.Unlock()
+	lockStart := time.Now()
+	defer func() { r.lockHold.record(time.Since(lockStart)) }()
+
+	flusher, ok := startStream(w)
+	if !ok {
+		return
+	}
+
+	r.counter++
+	currentCounter := r.counter
+	result := streamHeavyWork(req.Context(), w, flusher, r.work)
+
+	key := fmt.Sprintf("request_%d", currentCounter)
+	makeRoom(r.data, key, r.mapCap)
+	r.data[key] = &DataStruct{
+		Identifier:   key,
+		Name:         fmt.Sprintf("Request %d", currentCounter),
+		IsActive:     true,
+		Counter:      result,
+		LastModified: time.Now(),
+		Payload:      newPayload(r.payload),
+	}
+
+	response := newResponse(req, "bad_defer_stream", start)
+	response.Counter = currentCounter
+	response.Result = result
+	response.Done = true
+	json.NewEncoder(w).Encode(response)
+}
+
+/*
+UpstreamHandler appelle l'upstream configuré (-upstream) mutex verrouillé
+avec defer : le bug courant du verrou tenu pendant une entrée/sortie réseau.
+Toutes les requêtes attendent l'aller-retour de celle qui tient le mutex,
+la latence de l'upstream est donc payée en série.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@behavior:
+  1. Verrouille le mutex avec defer
+  2. Appelle l'upstream (GET) et lit sa réponse, mutex TOUJOURS verrouillé
+  3. Écrit le résultat ; 502 si l'upstream échoue, 503 s'il n'est pas configuré
+
+@performance: Throughput plafonné à 1 / latence de l'upstream, quelle que soit la concurrence
+*/
+func (r *BadRepository) UpstreamHandler(w http.ResponseWriter, req *http.Request) {
+	if !upstreamConfigured(w, r.upstream) {
+		return
+	}
+	start := time.Now()
+	ctx, span := startSpan(req.Context(), "bad.upstream")
+	defer span.End()
+
+	// Mauvaise pratique: le mutex reste verrouillé pendant l'appel réseau
+	r.lockWait.record(tracedLock(ctx, "mu", r.mu.Lock))
+	defer r.mu.Unlock()
+	lockStart := time.Now()
+	defer func() { r.lockHold.record(time.Since(lockStart)) }()
+
+	r.counter++
+	currentCounter := r.counter
+
+	upstreamSpan := startPhase(ctx, "upstream.get")
+	upstream, err := callUpstream(ctx, r.upstream)
+	upstreamSpan.End()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "upstream: "+err.Error())
+		return
+	}
+
+	key := fmt.Sprintf("request_%d", currentCounter)
+	makeRoom(r.data, key, r.mapCap)
+	r.data[key] = &DataStruct{
+		Identifier:   key,
+		Name:         fmt.Sprintf("Upstream %d", currentCounter),
+		IsActive:     true,
+		Counter:      int(upstream.bytes),
+		LastModified: time.Now(),
+		Payload:      newPayload(r.payload),
+	}
+
+	response := newResponse(req, "bad_defer_upstream", start)
+	response.Counter = currentCounter
+	response.UpstreamStatus = upstream.status
+	response.UpstreamBytes = upstream.bytes
+	writeEncoded(w, http.StatusOK, response, r.enc)
+}
+
+/*
+BatchHandler traite un lot d'éléments en verrouillant UNE fois pour tout le lot,
+mais avec defer : le mutex reste verrouillé pendant le traitement lourd de
+CHAQUE élément, le coût du verrou croît donc avec la taille du lot.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant un tableau JSON de BatchItem
+
+@behavior:
+  1. Décode le lot (400 si invalide)
+  2. Verrouille le mutex avec defer pour tout le lot
+  3. Pour chaque élément : traitement lourd puis écriture, mutex verrouillé
+
+@performance: Lot de N éléments = N traitements lourds sérialisés sous le mutex
+*/
+func (r *BadRepository) BatchHandler(w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+
+	items, err := decodeBatch(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid batch: "+err.Error())
+		return
+	}
+
+	// Mauvaise pratique: le mutex reste verrouillé pendant TOUT le lot
+	r.lockWait.record(tracedLock(req.Context(), "mu", r.mu.Lock))
+	defer r.mu.Unlock()
+	lockStart := time.Now()
+	defer func() { r.lockHold.record(time.Since(lockStart)) }()
+
+	counters := make([]int, 0, len(items))
+	for _, item := range items {
+		r.counter++
+		currentCounter := r.counter
+
+		// Traitement lourd de l'élément, mutex verrouillé !
+		result := r.work(req.Context())
+
+		key := fmt.Sprintf("request_%d", currentCounter)
+		makeRoom(r.data, key, r.mapCap)
+		r.data[key] = &DataStruct{
+			Identifier:   key,
+			Name:         item.Name,
+			IsActive:     true,
+			Counter:      result,
+			LastModified: time.Now(),
+			Payload:      newPayload(r.payload),
+		}
+		counters = append(counters, currentCounter)
+	}
+
+	response := newResponse(req, "bad_defer_batch", start)
+	response.BatchSize = len(items)
+	response.Counters = counters
+
+	writeEncoded(w, http.StatusOK, response, r.enc)
+}
+
+/*
+seed ajoute n entrées synthétiques (seed_0 à seed_n-1) en une seule section
+critique, pour mesurer l'effet de la taille de la map sur la copie et le GC.
+
+@params:
+  - n: int nombre d'entrées à insérer
+*/
+func (r *BadRepository) seed(n int) {
+	r.lockWait.record(tracedLock(context.Background(), "mu", r.mu.Lock))
+	for i := 0; i < n; i++ {
+		entry := seedEntry(i, r.payload)
+		makeRoom(r.data, entry.Identifier, r.mapCap)
+		r.data[entry.Identifier] = entry
+	}
+	r.mu.Unlock()
+}
+
+/*
+evictExpired supprime les entrées dont LastModified précède cutoff.
+Appelée par le janitor, sous le mutex le temps du seul balayage.
+
+@params:
+  - cutoff: time.Time date limite de dernière modification
+
+@returns: int - Nombre d'entrées supprimées
+*/
+func (r *BadRepository) evictExpired(cutoff time.Time) int {
+	r.lockWait.record(tracedLock(context.Background(), "mu", r.mu.Lock))
+	evicted := 0
+	for key, entry := range r.data {
+		if entry.LastModified.Before(cutoff) {
+			delete(r.data, key)
+			evicted++
+		}
+	}
+	r.mu.Unlock()
+	return evicted
+}
+
+/*
+GetDataHandler retourne l'entrée {id} en JSON, ou 404 si elle est absente.
+Fidèle au style du serveur bad : defer garde le mutex jusqu'à la fin de la
+fonction, encodage JSON de la réponse compris.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+
+@returns: JSON DataStruct
+*/
+func (r *BadRepository) GetDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.lockWait.record(tracedLock(req.Context(), "mu", r.mu.Lock))
+	defer r.mu.Unlock()
+
+	entry, found := r.data[id]
+	if !found {
+		writeData(w, id, DataStruct{}, false)
+		return
+	}
+	writeData(w, id, *entry, true)
+}
+
+/*
+DeleteDataHandler supprime l'entrée {id} : 204 si supprimée, 404 si absente.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+*/
+func (r *BadRepository) DeleteDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.lockWait.record(tracedLock(req.Context(), "mu", r.mu.Lock))
+	defer r.mu.Unlock()
+
+	_, found := r.data[id]
+	delete(r.data, id)
+	writeDeleted(w, id, found)
+}
+
+/*
+StatsHandler retourne les statistiques actuelles du serveur.
+Utilise correctement le mutex uniquement pour la lecture des données.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON contenant total_requests, data_size, avg_lock_hold_us,
+max_lock_hold_us, goroutines et heap_alloc_bytes
+*/
+func (r *BadRepository) StatsHandler(w http.ResponseWriter, req *http.Request) {
+	avgHold, maxHold := r.lockHold.snapshot()
+
+	r.lockWait.record(tracedLock(req.Context(), "mu", r.mu.Lock))
+	stats := map[string]interface{}{
+		"total_requests":   r.counter,
+		"data_size":        len(r.data),
+		"avg_lock_hold_us": avgHold,
+		"max_lock_hold_us": maxHold,
+	}
+	r.mu.Unlock()
+	addRuntimeGauges(stats)
+
+	writeStats(w, stats)
+}
+
+/*
+LockWaitHandler expose l'histogramme des temps d'attente de chaque acquisition du mutex.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON LockWaitSummary (count, avg_ms, max_ms, buckets, overflow)
+*/
+func (r *BadRepository) LockWaitHandler(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, r.lockWait.snapshot())
+}
+
+/*
+ResetHandler remet à zéro les mesures de verrouillage (attente et détention),
+pour démarrer une expérience sans redémarrer le serveur. Les données et le
+compteur de requêtes sont conservés.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON {"status": "reset"}
+*/
+func (r *BadRepository) ResetHandler(w http.ResponseWriter, req *http.Request) {
+	r.lockWait.reset()
+	r.lockHold.reset()
+	writeJSON(w, http.StatusOK, map[string]string{"status": "reset"})
+}
+
+/*
+Router configure les routes du serveur avec gorilla/mux.
+
+@returns: *mux.Router - Routeur prêt à être servi
+
+@endpoints:
+  - GET /process : Handler avec mauvaise utilisation du mutex
+  - POST /process/batch : Lot traité entièrement sous le mutex
+  - GET /process/stream : Progression NDJSON, mutex tenu pendant tous les Flush
+  - GET /process/upstream : Appel à -upstream, mutex tenu pendant l'appel réseau
+  - GET /data/{id} : Lecture d'une entrée (404 si absente)
+  - DELETE /data/{id} : Suppression d'une entrée
+  - GET /stats : Statistiques du serveur
+  - GET /debug/allocs : Compteurs d'allocation du processus
+  - GET /debug/contention : Résumé du profil de contention des mutex
+  - GET /debug/lockwait : Histogramme des attentes du mutex
+  - POST /reset : Remise à zéro des mesures de verrouillage
+  - POST /seed?n=N : Pré-remplissage avec N entrées synthétiques
+*/
+func (r *BadRepository) Router() *mux.Router {
+	router := newRouter()
+	router.HandleFunc("/process", r.BadHandler).Methods("GET")
+	router.HandleFunc("/process/batch", r.BatchHandler).Methods("POST")
+	router.HandleFunc("/process/stream", r.StreamHandler).Methods("GET")
+	router.HandleFunc("/process/upstream", r.UpstreamHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.GetDataHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.DeleteDataHandler).Methods("DELETE")
+	router.HandleFunc("/stats", r.StatsHandler).Methods("GET")
+	router.HandleFunc("/debug/allocs", AllocsHandler).Methods("GET")
+	router.HandleFunc("/debug/contention", ContentionHandler).Methods("GET")
+	router.HandleFunc("/debug/lockwait", r.LockWaitHandler).Methods("GET")
+	router.HandleFunc("/reset", r.ResetHandler).Methods("POST")
+	router.HandleFunc("/seed", seedHandler(r.seed)).Methods("POST")
+	return router
+}
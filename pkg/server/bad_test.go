@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+/*
+TestBadServerFairness montre que les requêtes du serveur bad sont servies à
+peu près dans leur ordre d'arrivée. Un waiter de sync.Mutex qui attend plus
+de 1ms fait passer le mutex en mode famine (Go 1.9+) : le déverrouillage
+remet alors le verrou directement au plus ancien waiter, en FIFO, au lieu de
+laisser une goroutine qui arrive le prendre au vol. Les sections critiques de
+10ms du serveur bad y basculent à chaque requête.
+
+@behavior:
+  1. Les requêtes sont soumises une à une, espacées de submitGap : chacune
+     est bloquée sur le mutex avant que la suivante n'arrive
+  2. Chaque requête note son rang de terminaison
+  3. La corrélation de rang (Spearman) entre soumission et terminaison doit
+     dépasser minCorrelation, tolérance large car l'ordonnanceur n'est pas déterministe
+*/
+func TestBadServerFairness(t *testing.T) {
+	const (
+		requests       = 16
+		submitGap      = 2 * time.Millisecond
+		minCorrelation = 0.5
+	)
+
+	handler := NewBadRepository(false).Router()
+	completion := make([]int, requests)
+	var finished atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(submitted int) {
+			defer wg.Done()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/process", nil))
+			completion[submitted] = int(finished.Add(1)) - 1
+		}(i)
+		time.Sleep(submitGap)
+	}
+	wg.Wait()
+
+	// Spearman sans ex æquo : 1 - 6 Σd² / (n(n² - 1))
+	sumSquares := 0
+	for submitted, completed := range completion {
+		d := submitted - completed
+		sumSquares += d * d
+	}
+	n := float64(requests)
+	correlation := 1 - 6*float64(sumSquares)/(n*(n*n-1))
+
+	t.Logf("completion order by submission: %v (Spearman %.2f)", completion, correlation)
+	if correlation < minCorrelation {
+		t.Errorf("rank correlation = %.2f, want >= %.2f: requests were not served roughly FIFO", correlation, minCorrelation)
+	}
+}
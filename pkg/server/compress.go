@@ -0,0 +1,190 @@
+package server
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// minGzipSize est la taille de corps à partir de laquelle gzipMW compresse : en dessous, l'en-tête gzip coûte plus qu'il ne fait gagner
+const minGzipSize = 1024
+
+// gzipPool recycle les gzip.Writer, dont l'état de compression pèse plusieurs centaines de Ko
+var gzipPool = sync.Pool{
+	New: func() any { return gzip.NewWriter(nil) },
+}
+
+/*
+acceptsGzip indique si un en-tête Accept-Encoding accepte gzip : gzip ou le
+joker, avec une qualité non nulle.
+
+@params:
+  - acceptEncoding: string valeur de l'en-tête Accept-Encoding
+
+@returns: bool - true si la réponse peut être compressée en gzip
+*/
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		q := 1.0
+		if raw, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(raw, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+gzipResponseWriter retient le code et le début du corps jusqu'à savoir si la
+réponse dépasse minGzipSize : au-delà, elle est compressée en gzip, sinon
+elle part telle quelle. Un Flush avant ce seuil tranche pour l'envoi non
+compressé, pour que les flux NDJSON restent diffusés au fil de l'eau.
+
+@fields:
+  - status: Code demandé par le handler (0 = pas encore de WriteHeader)
+  - buf: Début du corps, retenu tant que la décision n'est pas prise
+  - gz: Compresseur de la réponse, nil si elle n'est pas compressée
+  - decided: La réponse est partie, compressée ou non
+*/
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	buf     []byte
+	gz      *gzip.Writer
+	decided bool
+}
+
+// WriteHeader retient le code jusqu'à la décision, sauf s'il exclut tout corps
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.status != 0 {
+		return // Code déjà retenu : un second WriteHeader serait ignoré
+	}
+	w.status = status
+	if status == http.StatusNoContent || status == http.StatusNotModified || w.Header().Get("Content-Encoding") != "" {
+		w.send(false)
+	}
+}
+
+// Write retient le corps jusqu'à minGzipSize, puis compresse la suite
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= minGzipSize {
+		if err := w.send(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush envoie la réponse non compressée si rien n'est décidé, vide le compresseur sinon, puis relaie le Flush sous-jacent
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		w.send(false)
+	} else if w.gz != nil {
+		w.gz.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+/*
+send envoie les en-têtes et le corps retenu, compressé ou non.
+
+@params:
+  - compress: bool compresse la réponse en gzip
+
+@returns: error - Erreur d'écriture du corps retenu
+*/
+func (w *gzipResponseWriter) send(compress bool) error {
+	w.decided = true
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if compress {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Del("Content-Length")
+		w.gz = gzipPool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(status)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.gz != nil {
+		_, err := w.gz.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// finish envoie une réponse restée sous le seuil, ou termine le flux gzip et rend le compresseur au pool
+func (w *gzipResponseWriter) finish() {
+	if !w.decided {
+		if w.status == 0 && len(w.buf) == 0 {
+			return // Rien d'écrit : net/http enverra son 200 vide
+		}
+		w.send(false)
+		return
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		gzipPool.Put(w.gz)
+		w.gz = nil
+	}
+}
+
+/*
+gzipMW est le middleware de compression des routeurs : avec
+Accept-Encoding: gzip, une réponse d'au moins minGzipSize octets (par
+exemple /process?includeData=true) est compressée et porte
+Content-Encoding: gzip. Les petites réponses et les flux vidés avant le
+seuil partent tels quels.
+
+@params:
+  - next: http.Handler handler de la route
+
+@returns: http.Handler - Handler compressant ses réponses
+
+@note: La compression a lieu pendant l'écriture du corps, quand le handler
+appelle writeJSON : le serveur good a déjà libéré son mutex, le serveur bad
+le tient encore par son defer, et la compression s'ajoute à sa section
+critique. Un travail de plus à garder hors du verrou.
+*/
+func gzipMW(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if req.Method == http.MethodHead || !acceptsGzip(req.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.finish()
+		next.ServeHTTP(gw, req)
+	})
+}
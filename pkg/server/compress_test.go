@@ -0,0 +1,112 @@
+package server
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/*
+TestGzipRoundTrip vérifie qu'une copie complète demandée avec
+Accept-Encoding: gzip revient compressée et se décompresse en le corps
+envoyé sans compression (à request_id et duration près), que les petites
+réponses restent en clair et qu'un flux NDJSON, vidé avant le seuil, n'est
+pas compressé.
+*/
+func TestGzipRoundTrip(t *testing.T) {
+	repo := newSyncMapRepository(newRepoOptions(WithCopyData(true), WithWork(0, 0)))
+	repo.seed(50)
+	handler := repo.Router()
+
+	get := func(target, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d, want 200", target, rec.Code)
+		}
+		return rec
+	}
+
+	plain := get("/snapshot", "")
+	if plain.Header().Get("Content-Encoding") != "" || plain.Body.Len() < minGzipSize {
+		t.Fatalf("uncompressed snapshot: Content-Encoding %q, %d bytes; want none, at least %d",
+			plain.Header().Get("Content-Encoding"), plain.Body.Len(), minGzipSize)
+	}
+	compressed := get("/snapshot", "br, gzip;q=0.8")
+	if compressed.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", compressed.Header().Get("Content-Encoding"))
+	}
+	if !strings.Contains(strings.Join(compressed.Header().Values("Vary"), ","), "Accept-Encoding") {
+		t.Errorf("Vary = %q, want Accept-Encoding", compressed.Header().Values("Vary"))
+	}
+	if compressed.Body.Len() >= plain.Body.Len() {
+		t.Errorf("compressed body is %d bytes, uncompressed %d", compressed.Body.Len(), plain.Body.Len())
+	}
+	zr, err := gzip.NewReader(compressed.Body)
+	if err != nil {
+		t.Fatalf("invalid gzip stream: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("decompressing: %v", err)
+	}
+	// request_id et duration changent d'une requête à l'autre : le reste doit être identique
+	stable := func(raw []byte) map[string]any {
+		var v map[string]any
+		if err := json.Unmarshal(raw, &v); err != nil {
+			t.Fatalf("invalid JSON %q: %v", raw, err)
+		}
+		delete(v, "request_id")
+		delete(v, "duration")
+		return v
+	}
+	if !reflect.DeepEqual(stable(body), stable(plain.Body.Bytes())) {
+		t.Errorf("decompressed body differs from the uncompressed one:\n%s\n%s", body, plain.Body.Bytes())
+	}
+	if ct := compressed.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	if small := get("/stats", "gzip"); small.Header().Get("Content-Encoding") != "" {
+		t.Errorf("/stats (%d bytes) was compressed", small.Body.Len())
+	}
+
+	stream := NewGoodRepository(false).Router()
+	req := httptest.NewRequest(http.MethodGet, "/process/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	stream.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "" || !rec.Flushed || strings.Count(rec.Body.String(), "\n") != streamSteps+1 {
+		t.Errorf("stream: Content-Encoding %q, flushed %v, body %q; want %d plain NDJSON lines",
+			rec.Header().Get("Content-Encoding"), rec.Flushed, rec.Body.String(), streamSteps+1)
+	}
+}
+
+// TestAcceptsGzip vérifie la lecture de l'en-tête Accept-Encoding
+func TestAcceptsGzip(t *testing.T) {
+	for _, c := range []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"GZIP", true},
+		{"deflate, gzip;q=0.5", true},
+		{"gzip;q=0", false},
+		{"*", true},
+		{"br, identity", false},
+	} {
+		if got := acceptsGzip(c.header); got != c.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", c.header, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package server
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"time"
+)
+
+/*
+Config est la forme fichier (JSON) des options des serveurs, pour partager
+des configurations d'expérience reproductibles.
+
+@fields:
+  - Addr: Adresse d'écoute ("" = port par défaut de la variante)
+  - Copy: Copie les données partagées avant le traitement
+  - Payload: Taille en octets du blob de chaque entrée (tous les serveurs sauf floor)
+  - Upstream: URL appelée par /process/upstream (serveurs bad et good, "" = désactivé)
+  - Limit: Traitements lourds simultanés (serveurs semaphore et admission)
+  - Budget: Attente maximum d'admission (serveur admission), en durée Go ou en nanosecondes
+  - Workers: Nombre de workers (serveur pool)
+  - Queue: Profondeur de la file d'attente (serveur pool)
+  - MutexFraction: Fraction d'échantillonnage du profil de contention
+  - TTL: Âge maximum des entrées, en durée Go ("30s") ou en nanosecondes
+  - OTel: Collecteur OTLP/HTTP recevant les traces ("" = désactivé)
+  - Socket: Chemin d'une socket Unix remplaçant l'écoute TCP ("" = TCP)
+  - TLS: Écoute en HTTPS
+  - Cert, Key: Certificat et clé PEM du serveur TLS ("" = auto-signé)
+  - Log: Journalise chaque requête avec son identifiant X-Request-ID
+  - CPUOnly: Traitement lourd purement CPU, calibré au démarrage
+  - Prom: Expose /stats/prometheus au format texte Prometheus
+  - Encoder: Encodeur JSON des réponses de /process ("stdlib" ou "jsoniter", voir -encoder)
+  - PinWork: Traitement lourd verrouillé à un thread système (expérimental)
+  - Fanout: Goroutines entre lesquelles la boucle de calcul d'une requête est répartie
+  - SelfBench: Expose /bench, une mesure de latence du serveur sur lui-même
+  - NoDelay: TCP_NODELAY sur les connexions acceptées (false réactive Nagle)
+  - MapCap: Nombre maximum d'entrées de la map (0 = illimité)
+  - Sleep: Attente au début du traitement lourd, en durée Go ou en nanosecondes (ignorée avec CPUOnly)
+  - Iters: Itérations de la boucle du traitement lourd (ignorées avec CPUOnly)
+*/
+type Config struct {
+	Addr          string   `json:"addr"`
+	Copy          bool     `json:"copy"`
+	Payload       int      `json:"payload"`
+	Upstream      string   `json:"upstream"`
+	Limit         int64    `json:"limit"`
+	Budget        Duration `json:"budget"`
+	Workers       int      `json:"workers"`
+	Queue         int      `json:"queue"`
+	MutexFraction int      `json:"mutexFraction"`
+	TTL           Duration `json:"ttl"`
+	OTel          string   `json:"otel"`
+	Socket        string   `json:"socket"`
+	TLS           bool     `json:"tls"`
+	Cert          string   `json:"cert"`
+	Key           string   `json:"key"`
+	Log           bool     `json:"log"`
+	CPUOnly       bool     `json:"cpuOnly"`
+	Prom          bool     `json:"prom"`
+	Encoder       string   `json:"encoder"`
+	PinWork       bool     `json:"pinWork"`
+	Fanout        int      `json:"fanout"`
+	SelfBench     bool     `json:"selfBench"`
+	NoDelay       bool     `json:"nodelay"`
+	MapCap        int      `json:"mapCap"`
+	Sleep         Duration `json:"sleep"`
+	Iters         int      `json:"iters"`
+}
+
+/*
+Duration est une time.Duration lisible en JSON sous forme de chaîne ("500ms")
+ou de nombre de nanosecondes.
+*/
+type Duration time.Duration
+
+/*
+UnmarshalJSON accepte "1m30s" ou un entier en nanosecondes.
+
+@params:
+  - data: []byte valeur JSON
+
+@returns: error - Erreur si la valeur n'est ni une durée ni un nombre
+*/
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+		return nil
+	}
+	var ns int64
+	if err := json.Unmarshal(data, &ns); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\" or nanoseconds: %s", data)
+	}
+	*d = Duration(ns)
+	return nil
+}
+
+/*
+MarshalJSON écrit la durée sous forme de chaîne ("30s").
+
+@returns: ([]byte, error) - Chaîne JSON
+*/
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+/*
+DefaultConfig retourne la configuration par défaut, identique aux valeurs
+par défaut des flags.
+
+@returns: Config - Configuration par défaut
+*/
+func DefaultConfig() Config {
+	return Config{
+		Copy:          true,
+		Limit:         int64(runtime.NumCPU()),
+		Budget:        Duration(20 * time.Millisecond),
+		Workers:       runtime.NumCPU(),
+		Queue:         64,
+		MutexFraction: 1,
+		Encoder:       defaultEncoder,
+		Fanout:        1,
+		NoDelay:       true,
+		Sleep:         Duration(defaultWorkSleep),
+		Iters:         defaultWorkIters,
+	}
+}
+
+/*
+LoadConfig lit une configuration JSON. Les champs absents gardent leur valeur
+par défaut, les champs inconnus sont refusés pour qu'une faute de frappe ne
+passe pas inaperçue.
+
+@params:
+  - r: io.Reader contenant l'objet JSON
+
+@returns: (Config, error) - Configuration validée, ou erreur de lecture/validation
+*/
+func LoadConfig(r io.Reader) (Config, error) {
+	cfg := DefaultConfig()
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("config: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return Config{}, fmt.Errorf("config: %w", err)
+	}
+	return cfg, nil
+}
+
+/*
+validate vérifie que les valeurs numériques sont utilisables.
+
+@returns: error - Première valeur invalide rencontrée
+*/
+func (c Config) validate() error {
+	switch {
+	case c.Payload < 0:
+		return errors.New("payload must not be negative")
+	case c.Limit <= 0:
+		return errors.New("limit must be positive")
+	case c.Workers <= 0:
+		return errors.New("workers must be positive")
+	case c.Queue < 0:
+		return errors.New("queue must not be negative")
+	case c.MutexFraction < 0:
+		return errors.New("mutexFraction must not be negative")
+	case c.TTL < 0:
+		return errors.New("ttl must not be negative")
+	case c.Budget < 0:
+		return errors.New("budget must not be negative")
+	case c.Fanout <= 0:
+		return errors.New("fanout must be positive")
+	case c.MapCap < 0:
+		return errors.New("mapCap must not be negative")
+	case c.Sleep < 0:
+		return errors.New("sleep must not be negative")
+	case c.Iters < 0:
+		return errors.New("iters must not be negative")
+	case c.Addr != "" && c.Socket != "":
+		return errors.New("addr and socket are mutually exclusive")
+	}
+	if err := checkTLS(c.TLS, c.Cert, c.Key); err != nil {
+		return err
+	}
+	if err := checkEncoder(c.Encoder); err != nil {
+		return err
+	}
+	return checkUpstream(c.Upstream)
+}
+
+/*
+Options convertit la configuration en Options.
+
+@returns: Options - Options équivalentes
+*/
+func (c Config) Options() Options {
+	return Options{
+		Addr:                 c.Addr,
+		CopyData:             c.Copy,
+		Payload:              c.Payload,
+		Upstream:             c.Upstream,
+		SemaphoreLimit:       c.Limit,
+		AdmitBudget:          time.Duration(c.Budget),
+		PoolWorkers:          c.Workers,
+		PoolQueue:            c.Queue,
+		MutexProfileFraction: c.MutexFraction,
+		TTL:                  time.Duration(c.TTL),
+		OTelEndpoint:         c.OTel,
+		Socket:               c.Socket,
+		TLS:                  c.TLS,
+		CertFile:             c.Cert,
+		KeyFile:              c.Key,
+		AccessLog:            c.Log,
+		CPUOnly:              c.CPUOnly,
+		Prometheus:           c.Prom,
+		Encoder:              c.Encoder,
+		PinWork:              c.PinWork,
+		Fanout:               c.Fanout,
+		SelfBench:            c.SelfBench,
+		NoDelay:              c.NoDelay,
+		MapCap:               c.MapCap,
+		WorkSleep:            time.Duration(c.Sleep),
+		WorkIters:            c.Iters,
+	}
+}
+
+/*
+ParseFlags analyse les flags d'options, avec prise en charge de -config.
+Le fichier est appliqué d'abord, puis les arguments sont ré-analysés : un
+flag passé explicitement surcharge la valeur du fichier.
+
+@params:
+  - fs: *flag.FlagSet sur lequel RegisterFlags a été appelé
+  - args: []string arguments de la ligne de commande
+  - opts: *Options options liées aux flags du FlagSet
+
+@returns: error - Erreur d'analyse des flags, de lecture du fichier,
+-addr et -socket fournis ensemble, -upstream invalide, -encoder inconnu
+ou absent du binaire, -fanout nul ou négatif, ou -cert/-key incohérents ou
+illisibles
+*/
+func ParseFlags(fs *flag.FlagSet, args []string, opts *Options) error {
+	var path string
+	fs.StringVar(&path, "config", "", "Fichier JSON d'options, appliqué avant les flags")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		cfg, err := LoadConfig(f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		*opts = cfg.Options()
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+	}
+	if opts.Addr != "" && opts.Socket != "" {
+		return errors.New("-addr and -socket are mutually exclusive")
+	}
+	if err := checkTLS(opts.TLS, opts.CertFile, opts.KeyFile); err != nil {
+		return fmt.Errorf("-cert/-key: %w", err)
+	}
+	if opts.CertFile != "" {
+		// Un certificat illisible doit arrêter le démarrage, pas chaque handshake
+		if _, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile); err != nil {
+			return fmt.Errorf("-cert/-key: %w", err)
+		}
+	}
+	if err := checkEncoder(opts.Encoder); err != nil {
+		return fmt.Errorf("-encoder: %w", err)
+	}
+	if opts.Fanout <= 0 {
+		return fmt.Errorf("-fanout must be positive, got %d", opts.Fanout)
+	}
+	return checkUpstream(opts.Upstream)
+}
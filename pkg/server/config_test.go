@@ -0,0 +1,93 @@
+package server
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+TestLoadConfig vérifie les valeurs par défaut, la lecture des durées et le
+refus des champs inconnus ou invalides.
+*/
+func TestLoadConfig(t *testing.T) {
+	cfg, err := LoadConfig(strings.NewReader(`{"addr": ":9000", "copy": false, "ttl": "30s"}`))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	opts := cfg.Options()
+	if opts.Addr != ":9000" || opts.CopyData || opts.TTL != 30*time.Second {
+		t.Errorf("options = %+v, want addr :9000, copy false, ttl 30s", opts)
+	}
+	if want := DefaultConfig(); cfg.Queue != want.Queue || cfg.Limit != want.Limit {
+		t.Errorf("absent fields = queue %d limit %d, want defaults %d %d", cfg.Queue, cfg.Limit, want.Queue, want.Limit)
+	}
+
+	if cfg, err := LoadConfig(strings.NewReader(`{"ttl": 1000000}`)); err != nil || cfg.TTL != Duration(time.Millisecond) {
+		t.Errorf("numeric ttl = %v (err %v), want 1ms", time.Duration(cfg.TTL), err)
+	}
+
+	if cfg.Sleep != Duration(defaultWorkSleep) || cfg.Iters != defaultWorkIters {
+		t.Errorf("default work = %v and %d iterations, want %v and %d", time.Duration(cfg.Sleep), cfg.Iters, defaultWorkSleep, defaultWorkIters)
+	}
+	cfg, err = LoadConfig(strings.NewReader(`{"mapCap": 100, "sleep": "1ms", "iters": 500}`))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if opts := cfg.Options(); opts.MapCap != 100 || opts.WorkSleep != time.Millisecond || opts.WorkIters != 500 {
+		t.Errorf("options = mapCap %d sleep %v iters %d, want 100, 1ms and 500", opts.MapCap, opts.WorkSleep, opts.WorkIters)
+	}
+
+	for _, bad := range []string{
+		`{"shardz": 4}`,
+		`{"workers": 0}`,
+		`{"ttl": "soon"}`,
+		`{"queue": -1}`,
+		`{"budget": "-1s"}`,
+		`{"payload": -1}`,
+		`{"fanout": 0}`,
+		`{"mapCap": -1}`,
+		`{"sleep": "-1ms"}`,
+		`{"iters": -1}`,
+		`{"upstream": "localhost:9000"}`,
+		`{"tls": true, "cert": "server.pem"}`,
+		`{"cert": "server.pem", "key": "server.key"}`,
+		`{"addr": ":9000", "socket": "/tmp/x.sock"}`,
+		`not json`,
+	} {
+		if _, err := LoadConfig(strings.NewReader(bad)); err == nil {
+			t.Errorf("LoadConfig(%s) succeeded, want error", bad)
+		}
+	}
+}
+
+/*
+TestParseFlagsConfigPrecedence vérifie que le fichier -config s'applique avant
+les flags : un flag explicite surcharge le fichier, quel que soit son rang.
+*/
+func TestParseFlagsConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"queue": 8, "workers": 3, "ttl": "1m"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var opts Options
+	opts.RegisterFlags(fs)
+	if err := ParseFlags(fs, []string{"-workers=5", "-config", path}, &opts); err != nil {
+		t.Fatalf("ParseFlags: %v", err)
+	}
+
+	if opts.PoolQueue != 8 || opts.TTL != time.Minute {
+		t.Errorf("file values = queue %d ttl %v, want 8 and 1m", opts.PoolQueue, opts.TTL)
+	}
+	if opts.PoolWorkers != 5 {
+		t.Errorf("workers = %d, want flag value 5 to override file", opts.PoolWorkers)
+	}
+	if !opts.CopyData {
+		t.Error("copy = false, want default true")
+	}
+}
@@ -0,0 +1,243 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+/*
+DataStruct représente une structure de données complexe.
+
+@fields:
+  - Identifier: Identifiant unique
+  - Name: Nom de l'élément
+  - IsActive: État actif/inactif
+  - Counter: Compteur d'accès
+  - LastModified: Timestamp de dernière modification
+  - Payload: Blob de taille configurable (-payload), encodé en base64 en JSON
+*/
+type DataStruct struct {
+	Identifier   string    `json:"identifier"`
+	Name         string    `json:"name"`
+	IsActive     bool      `json:"is_active"`
+	Counter      int       `json:"counter"`
+	LastModified time.Time `json:"last_modified"`
+	Payload      []byte    `json:"payload,omitempty"`
+}
+
+/*
+BatchItem représente un élément d'un lot envoyé sur /process/batch.
+
+@fields:
+  - Name: Nom de l'élément à traiter
+*/
+type BatchItem struct {
+	Name string `json:"name"`
+}
+
+/*
+decodeBatch lit le corps JSON d'une requête batch.
+
+@params:
+  - req: *http.Request contenant un tableau JSON de BatchItem
+
+@returns: ([]BatchItem, error) - Éléments du lot, erreur si le corps est invalide,
+vide, suivi de données, ou si un élément n'a pas de nom
+*/
+func decodeBatch(req *http.Request) ([]BatchItem, error) {
+	dec := json.NewDecoder(req.Body)
+	dec.DisallowUnknownFields()
+	var items []BatchItem
+	if err := dec.Decode(&items); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after batch")
+	}
+	if len(items) == 0 {
+		return nil, errors.New("empty batch")
+	}
+	for i, item := range items {
+		if item.Name == "" {
+			return nil, fmt.Errorf("item %d: missing name", i)
+		}
+	}
+	return items, nil
+}
+
+/*
+writeData envoie une entrée lue sur /data/{id}, ou 404 si elle est absente.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - id: string identifiant demandé
+  - entry: DataStruct copie de l'entrée, lue sous la synchronisation du repository
+  - found: bool indique si l'entrée existe
+*/
+func writeData(w http.ResponseWriter, id string, entry DataStruct, found bool) {
+	if !found {
+		writeError(w, http.StatusNotFound, "data not found: "+id)
+		return
+	}
+	writeJSON(w, http.StatusOK, entry)
+}
+
+/*
+writeDeleted répond à DELETE /data/{id} : 204 si l'entrée a été supprimée,
+404 si elle n'existait pas.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - id: string identifiant demandé
+  - deleted: bool indique si une entrée a été supprimée
+*/
+func writeDeleted(w http.ResponseWriter, id string, deleted bool) {
+	if !deleted {
+		writeError(w, http.StatusNotFound, "data not found: "+id)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+seedEntry construit l'entrée synthétique numéro i, utilisée pour pré-remplir
+un repository avant une mesure.
+
+@params:
+  - i: int numéro de l'entrée
+  - payload: int taille en octets du blob de l'entrée
+
+@returns: *DataStruct - Entrée de clé "seed_i"
+*/
+func seedEntry(i, payload int) *DataStruct {
+	key := fmt.Sprintf("seed_%d", i)
+	return &DataStruct{
+		Identifier:   key,
+		Name:         fmt.Sprintf("Seed %d", i),
+		IsActive:     true,
+		Counter:      i,
+		LastModified: time.Now(),
+		Payload:      newPayload(payload),
+	}
+}
+
+/*
+newPayload alloue le blob d'une nouvelle entrée.
+
+@params:
+  - size: int taille en octets
+
+@returns: []byte - Blob de size octets, ou nil si size <= 0 (champ omis en JSON)
+*/
+func newPayload(size int) []byte {
+	if size <= 0 {
+		return nil
+	}
+	return make([]byte, size)
+}
+
+// maxSeedEntries borne le paramètre n de /seed
+const maxSeedEntries = 1000000
+
+/*
+seedHandler construit le handler POST /seed?n=N, qui pré-remplit le
+repository avec N entrées synthétiques (seed_0 à seed_N-1) avant une mesure.
+Les clés sont fixes : rappeler /seed avec le même N réécrit les mêmes entrées
+au lieu d'agrandir la map.
+
+@params:
+  - seed: func(n int) insertion des entrées, sous la synchronisation du repository
+
+@returns: http.HandlerFunc - 400 si n est absent, invalide ou hors de
+[1, maxSeedEntries], sinon JSON {"seeded": N}
+*/
+func seedHandler(seed func(n int)) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		n, err := strconv.Atoi(req.URL.Query().Get("n"))
+		if err != nil || n < 1 || n > maxSeedEntries {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("n must be an integer between 1 and %d", maxSeedEntries))
+			return
+		}
+		seed(n)
+		writeJSON(w, http.StatusOK, map[string]int{"seeded": n})
+	}
+}
+
+// maxSnapshotEntries borne le nombre d'entrées renvoyées par /process?includeData=true
+const maxSnapshotEntries = 100
+
+/*
+parseIncludeData lit le paramètre includeData de /process, qui demande de
+renvoyer la copie des données construite par le handler.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: (bool, bool) - Copie demandée ; false en second (et 400 envoyé)
+si la valeur n'est pas un booléen
+*/
+func parseIncludeData(w http.ResponseWriter, req *http.Request) (bool, bool) {
+	raw := req.URL.Query().Get("includeData")
+	if raw == "" {
+		return false, true
+	}
+	include, err := strconv.ParseBool(raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid includeData: "+raw+" (want true or false)")
+		return false, false
+	}
+	return include, true
+}
+
+/*
+addSnapshot ajoute à la réponse de /process la copie des données lue par le
+handler : la copie n'est plus un travail perdu, et le client peut vérifier
+la cohérence de ce qu'il a lu. Elle est déjà détachée de la map partagée,
+l'encodage se fait donc sans verrou.
+
+@params:
+  - response: *ResponseV1 réponse de /process à compléter
+  - snapshot: map[string]*DataStruct copie des données (vide avec -copy=false)
+
+@behavior:
+  - data : au plus maxSnapshotEntries entrées, triées par identifiant
+  - data_truncated : true si la copie comptait plus d'entrées (snapshot_size les compte toutes)
+*/
+func addSnapshot(response *ResponseV1, snapshot map[string]*DataStruct) {
+	keys := make([]string, 0, len(snapshot))
+	for key := range snapshot {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	truncated := len(keys) > maxSnapshotEntries
+	if truncated {
+		keys = keys[:maxSnapshotEntries]
+	}
+
+	entries := make([]*DataStruct, len(keys))
+	for i, key := range keys {
+		entries[i] = snapshot[key]
+	}
+	response.Data = entries
+	response.DataTruncated = &truncated
+}
+
+/*
+dataID extrait la variable de chemin {id} de /data/{id}.
+
+@params:
+  - req: *http.Request routée par gorilla/mux
+
+@returns: string identifiant de l'entrée
+*/
+func dataID(req *http.Request) string {
+	return mux.Vars(req)["id"]
+}
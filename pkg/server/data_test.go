@@ -0,0 +1,251 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+TestDataEndpoints vérifie sur chaque variante le cycle écriture via /process,
+lecture GET /data/{id}, suppression DELETE /data/{id}, puis 404.
+*/
+func TestDataEndpoints(t *testing.T) {
+	opts := Options{CopyData: true, SemaphoreLimit: 2, PoolWorkers: 2, PoolQueue: 4}
+
+	for _, v := range Variants {
+		if v.Name == "floor" {
+			continue // Ni map ni /data/{id}
+		}
+		t.Run(v.Name, func(t *testing.T) {
+			handler := v.NewHandler(opts)
+			defer handler.(io.Closer).Close()
+
+			// Les variantes à cache indexent leurs entrées par ?key=
+			processURL, id := "/process", "request_1"
+			if v.Name == "dcl" || v.Name == "singleflight" {
+				processURL, id = "/process?key=k", "k"
+			}
+
+			serve := func(method, target string) *httptest.ResponseRecorder {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+				return rec
+			}
+			// bigcopy démarre avec une map pré-remplie
+			initial := dataSize(t, handler)
+
+			if rec := serve(http.MethodGet, processURL); rec.Code != http.StatusOK {
+				t.Fatalf("GET %s status = %d, want 200", processURL, rec.Code)
+			}
+
+			rec := serve(http.MethodGet, "/data/"+id)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("GET /data/%s status = %d, want 200", id, rec.Code)
+			}
+			var entry DataStruct
+			if err := json.NewDecoder(rec.Body).Decode(&entry); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if entry.Identifier != id {
+				t.Errorf("identifier = %q, want %q", entry.Identifier, id)
+			}
+
+			if rec := serve(http.MethodDelete, "/data/"+id); rec.Code != http.StatusNoContent {
+				t.Errorf("DELETE /data/%s status = %d, want 204", id, rec.Code)
+			}
+			if rec := serve(http.MethodGet, "/data/"+id); rec.Code != http.StatusNotFound {
+				t.Errorf("GET after delete status = %d, want 404", rec.Code)
+			}
+			if rec := serve(http.MethodDelete, "/data/"+id); rec.Code != http.StatusNotFound {
+				t.Errorf("second DELETE status = %d, want 404", rec.Code)
+			}
+			if size := dataSize(t, handler); size != initial {
+				t.Errorf("data_size = %d after delete, want %d", size, initial)
+			}
+		})
+	}
+}
+
+/*
+TestSeedEndpoint vérifie /seed sur les variantes qui l'exposent : des appels
+concurrents, mêlés à des écritures /process, laissent exactement N entrées de
+seed plus une par écriture. Un n invalide est refusé.
+*/
+func TestSeedEndpoint(t *testing.T) {
+	const entries, seeders, writes = 500, 4, 4
+
+	for _, name := range []string{"bad", "good", "syncmap", "rwmutex"} {
+		t.Run(name, func(t *testing.T) {
+			v, _ := Lookup(name)
+			handler := v.NewHandler(Options{CopyData: true})
+			defer handler.(io.Closer).Close()
+
+			serve := func(method, target string) int {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+				return rec.Code
+			}
+
+			var wg sync.WaitGroup
+			for i := 0; i < seeders; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if code := serve(http.MethodPost, fmt.Sprintf("/seed?n=%d", entries)); code != http.StatusOK {
+						t.Errorf("POST /seed status = %d, want 200", code)
+					}
+				}()
+			}
+			for i := 0; i < writes; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					serve(http.MethodGet, "/process")
+				}()
+			}
+			wg.Wait()
+
+			if size := dataSize(t, handler); size != entries+writes {
+				t.Errorf("data_size = %d, want %d", size, entries+writes)
+			}
+			for _, target := range []string{"/seed", "/seed?n=0", "/seed?n=abc"} {
+				if code := serve(http.MethodPost, target); code != http.StatusBadRequest {
+					t.Errorf("POST %s status = %d, want 400", target, code)
+				}
+			}
+		})
+	}
+}
+
+/*
+TestPayload vérifie -payload sur les variantes qui le prennent en compte : les
+entrées écrites par /process et par /seed portent un blob de la taille
+demandée, encodé en base64 dans le JSON, et le champ est omis sans payload.
+*/
+func TestPayload(t *testing.T) {
+	const size = 1024
+
+	for _, name := range []string{"bad", "good", "syncmap", "rwmutex"} {
+		t.Run(name, func(t *testing.T) {
+			v, _ := Lookup(name)
+			for _, payload := range []int{size, 0} {
+				handler := v.NewHandler(Options{CopyData: true, Payload: payload})
+				defer handler.(io.Closer).Close()
+
+				serve := func(method, target string) *httptest.ResponseRecorder {
+					rec := httptest.NewRecorder()
+					handler.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+					return rec
+				}
+				serve(http.MethodGet, "/process")
+				serve(http.MethodPost, "/seed?n=1")
+
+				for _, id := range []string{"request_1", "seed_0"} {
+					rec := serve(http.MethodGet, "/data/"+id)
+					if rec.Code != http.StatusOK {
+						t.Fatalf("GET /data/%s status = %d, want 200", id, rec.Code)
+					}
+					var raw map[string]json.RawMessage
+					if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil {
+						t.Fatalf("invalid JSON: %v", err)
+					}
+					encoded, found := raw["payload"]
+					if payload == 0 {
+						if found {
+							t.Errorf("%s: payload present without -payload: %s", id, encoded)
+						}
+						continue
+					}
+					var s string
+					if err := json.Unmarshal(encoded, &s); err != nil {
+						t.Fatalf("%s: payload is not a JSON string: %s", id, encoded)
+					}
+					blob, err := base64.StdEncoding.DecodeString(s)
+					if err != nil {
+						t.Fatalf("%s: payload is not base64: %v", id, err)
+					}
+					if len(blob) != payload {
+						t.Errorf("%s: payload length = %d, want %d", id, len(blob), payload)
+					}
+				}
+			}
+		})
+	}
+}
+
+/*
+TestIncludeData vérifie /process?includeData=true sur chaque variante qui
+copie les données : la réponse contient la copie lue avant l'écriture, une
+valeur invalide est refusée, et au-delà de maxSnapshotEntries la copie est
+tronquée et signalée.
+*/
+func TestIncludeData(t *testing.T) {
+	opts := Options{CopyData: true, SemaphoreLimit: 2, PoolWorkers: 2, PoolQueue: 4, AdmitBudget: time.Second}
+
+	type processResponse struct {
+		SnapshotSize  int          `json:"snapshot_size"`
+		Data          []DataStruct `json:"data"`
+		DataTruncated *bool        `json:"data_truncated"`
+	}
+	process := func(t *testing.T, handler http.Handler, target string) (int, processResponse) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var resp processResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("GET %s: invalid JSON: %v", target, err)
+			}
+		}
+		return rec.Code, resp
+	}
+
+	for _, v := range Variants {
+		if v.Name == "dcl" || v.Name == "singleflight" {
+			continue // Caches par clé : pas de copie des données
+		}
+		if v.Name == "floor" {
+			continue // Ni map ni copie des données
+		}
+		if v.Name == "bigcopy" {
+			continue // Le handler du good sur une map pré-remplie : copie tronquée, voir le sous-test truncated
+		}
+		t.Run(v.Name, func(t *testing.T) {
+			handler := v.NewHandler(opts)
+			defer handler.(io.Closer).Close()
+
+			if code, resp := process(t, handler, "/process"); code != http.StatusOK || resp.Data != nil || resp.DataTruncated != nil {
+				t.Fatalf("GET /process: status %d, data %v, truncated %v; want 200 without data", code, resp.Data, resp.DataTruncated)
+			}
+			code, resp := process(t, handler, "/process?includeData=true")
+			if code != http.StatusOK {
+				t.Fatalf("GET /process?includeData=true status = %d, want 200", code)
+			}
+			if len(resp.Data) != 1 || resp.Data[0].Identifier != "request_1" || resp.DataTruncated == nil || *resp.DataTruncated {
+				t.Errorf("data = %+v, truncated %v; want [request_1], not truncated", resp.Data, resp.DataTruncated)
+			}
+			if code, _ := process(t, handler, "/process?includeData=maybe"); code != http.StatusBadRequest {
+				t.Errorf("GET /process?includeData=maybe status = %d, want 400", code)
+			}
+		})
+	}
+
+	t.Run("truncated", func(t *testing.T) {
+		const entries = maxSnapshotEntries + 50
+		repo := NewRepository(WithWork(0, 0))
+		defer repo.Close()
+		repo.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, fmt.Sprintf("/seed?n=%d", entries), nil))
+
+		_, resp := process(t, repo, "/process?includeData=1")
+		if resp.SnapshotSize != entries || len(resp.Data) != maxSnapshotEntries || resp.DataTruncated == nil || !*resp.DataTruncated {
+			t.Errorf("snapshot_size = %d, %d entries, truncated %v; want %d, %d, true",
+				resp.SnapshotSize, len(resp.Data), resp.DataTruncated, entries, maxSnapshotEntries)
+		}
+	})
+}
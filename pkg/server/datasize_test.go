@@ -0,0 +1,70 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+)
+
+/*
+BenchmarkDataSize mesure /process sur un repository pré-rempli de 0 à 100k
+entrées, avec la copie activée : la copie de la map et le travail du GC
+croissent avec sa taille, même sans aucune contention (une seule goroutine).
+syncmap ne lit qu'une clé par requête (voir BenchmarkSyncMapProcess) et sert
+de référence sans copie.
+
+	go test ./pkg/server -run '^$' -bench DataSize -benchtime 20x
+
+@metrics:
+  - ms/req: Latence moyenne du handler
+  - gc/op: Cycles de GC déclenchés par requête
+  - B/op, allocs/op: Allocations du handler (copie comprise)
+*/
+func BenchmarkDataSize(b *testing.B) {
+	variants := []struct {
+		name string
+		new  func(entries int) http.Handler
+	}{
+		{"bad", func(entries int) http.Handler {
+			repo := NewBadRepository(true)
+			repo.seed(entries)
+			return repo.Router()
+		}},
+		{"good", func(entries int) http.Handler {
+			repo := NewGoodRepository(true)
+			repo.seed(entries)
+			return repo.Router()
+		}},
+		{"syncmap", func(entries int) http.Handler {
+			repo := NewSyncMapRepository(true)
+			repo.seed(entries)
+			return repo.Router()
+		}},
+	}
+
+	for _, v := range variants {
+		for _, entries := range []int{0, 1000, 10000, 100000} {
+			b.Run(fmt.Sprintf("%s/entries=%d", v.name, entries), func(b *testing.B) {
+				handler := v.new(entries)
+				b.ReportAllocs()
+
+				var before, after runtime.MemStats
+				runtime.ReadMemStats(&before)
+				b.ResetTimer()
+				start := time.Now()
+				for i := 0; i < b.N; i++ {
+					handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/process", nil))
+				}
+				elapsed := time.Since(start)
+				b.StopTimer()
+				runtime.ReadMemStats(&after)
+
+				b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/req")
+				b.ReportMetric(float64(after.NumGC-before.NumGC)/float64(b.N), "gc/op")
+			})
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+/*
+DCLRepository implémente un cache protégé par un RWMutex avec le pattern
+double-checked locking : lecture optimiste sous RLock, puis en cas d'absence
+passage au Lock exclusif avec une seconde vérification.
+
+@fields:
+  - mu: RWMutex protégeant la map (lectures concurrentes, écriture exclusive)
+  - data: Cache des résultats calculés, indexé par clé de requête
+  - work: Traitement lourd d'un miss, exécuté sous le Lock
+  - mapCap: Nombre maximum d'entrées de data (0 = illimité)
+  - payload: Taille en octets du blob Payload de chaque entrée écrite
+  - enc: Encodeur des réponses de /process (voir -encoder)
+  - counter: Nombre total de requêtes traitées
+  - hits: Nombre de requêtes servies depuis le cache
+  - misses: Nombre de calculs effectivement réalisés
+*/
+type DCLRepository struct {
+	mu      sync.RWMutex
+	data    map[string]*DataStruct
+	work    WorkFunc
+	mapCap  int
+	payload int
+	enc     Encoder
+	counter atomic.Int64
+	hits    atomic.Int64
+	misses  atomic.Int64
+}
+
+/*
+NewDCLRepository crée et initialise un nouveau cache.
+
+@returns: *DCLRepository - Nouvelle instance avec la map initialisée
+*/
+func NewDCLRepository() *DCLRepository {
+	return newDCLRepository(newRepoOptions())
+}
+
+/*
+newDCLRepository crée le cache à partir d'options déjà appliquées : un miss
+exécute le traitement injecté (WithWorkFunc).
+
+@params:
+  - o: repoOptions configuration (borne de la map, traitement, payload, encodeur)
+
+@returns: *DCLRepository - Nouvelle instance avec la map initialisée
+*/
+func newDCLRepository(o repoOptions) *DCLRepository {
+	return &DCLRepository{
+		data:    make(map[string]*DataStruct),
+		work:    o.work,
+		mapCap:  o.mapCap,
+		payload: o.payload,
+		enc:     o.encoder,
+	}
+}
+
+/*
+DCLHandler retourne le résultat associé à la clé ?key=, en le calculant
+une seule fois grâce au double-checked locking.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP (paramètre key, "default" si absent)
+
+@behavior:
+  1. RLock : cherche la clé, RUnlock immédiat (chemin rapide, lectures parallèles)
+  2. Si absente : Lock exclusif puis NOUVELLE vérification, car un autre
+     goroutine a pu calculer la valeur entre RUnlock et Lock
+  3. Toujours absente : calcule et stocke sous le Lock, puis Unlock
+
+@performance: Les hits ne prennent que le RLock ; les misses sont sérialisés
+sous le Lock (voir le serveur singleflight pour éviter ce coût)
+*/
+func (r *DCLRepository) DCLHandler(w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	currentCounter := r.counter.Add(1)
+	ctx, span := startSpan(req.Context(), "dcl.process")
+	defer span.End()
+
+	key := req.URL.Query().Get("key")
+	if key == "" {
+		key = "default"
+	}
+
+	// Premier contrôle sous verrou partagé
+	tracedLock(ctx, "mu.read", r.mu.RLock)
+	entry, ok := r.data[key]
+	r.mu.RUnlock()
+
+	cached := ok
+	if !ok {
+		// RWMutex ne permet pas de "promouvoir" un RLock : il faut le relâcher
+		// puis prendre le Lock, et donc re-vérifier
+		tracedLock(ctx, "mu.write", r.mu.Lock)
+		entry, ok = r.data[key]
+		if !ok {
+			heavySpan := startPhase(ctx, "process.heavy")
+			result := r.work(ctx) // Attente simulée puis calcul intensif
+			heavySpan.End()
+
+			entry = &DataStruct{
+				Identifier:   key,
+				Name:         fmt.Sprintf("Cached %s", key),
+				IsActive:     true,
+				Counter:      result,
+				LastModified: time.Now(),
+				Payload:      newPayload(r.payload),
+			}
+			makeRoom(r.data, key, r.mapCap)
+			r.data[key] = entry
+			r.misses.Add(1)
+		}
+		r.mu.Unlock()
+		cached = ok
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", cached))
+	if cached {
+		r.hits.Add(1)
+	}
+
+	response := newResponse(req, "double_checked_locking", start)
+	response.Counter = int(currentCounter)
+	response.Result = entry.Counter
+	response.Key = key
+	response.Cached = &cached
+
+	writeEncoded(w, http.StatusOK, response, r.enc)
+}
+
+/*
+evictExpired supprime les entrées du cache dont LastModified précède cutoff.
+Le Lock exclusif est nécessaire pour supprimer ; une clé évincée sera
+recalculée au prochain miss.
+
+@params:
+  - cutoff: time.Time date limite de dernière modification
+
+@returns: int - Nombre d'entrées supprimées
+*/
+func (r *DCLRepository) evictExpired(cutoff time.Time) int {
+	r.mu.Lock()
+	evicted := 0
+	for key, entry := range r.data {
+		if entry.LastModified.Before(cutoff) {
+			delete(r.data, key)
+			evicted++
+		}
+	}
+	r.mu.Unlock()
+	return evicted
+}
+
+/*
+GetDataHandler retourne l'entrée {id} en JSON, ou 404 si elle est absente.
+Le mutex n'est tenu que le temps de copier l'entrée, l'encodage se fait après.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+
+@returns: JSON DataStruct
+*/
+func (r *DCLRepository) GetDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.mu.RLock()
+	var entry DataStruct
+	v, found := r.data[id]
+	if found {
+		entry = *v
+	}
+	r.mu.RUnlock()
+
+	writeData(w, id, entry, found)
+}
+
+/*
+DeleteDataHandler supprime l'entrée {id} : 204 si supprimée, 404 si absente.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+*/
+func (r *DCLRepository) DeleteDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.mu.Lock()
+	_, found := r.data[id]
+	delete(r.data, id)
+	r.mu.Unlock()
+
+	writeDeleted(w, id, found)
+}
+
+/*
+StatsHandler retourne les statistiques actuelles du cache.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON contenant total_requests, data_size, cache_hits et cache_misses
+*/
+func (r *DCLRepository) StatsHandler(w http.ResponseWriter, req *http.Request) {
+	r.mu.RLock()
+	dataSize := len(r.data)
+	r.mu.RUnlock()
+
+	stats := map[string]interface{}{
+		"total_requests": r.counter.Load(),
+		"data_size":      dataSize,
+		"cache_hits":     r.hits.Load(),
+		"cache_misses":   r.misses.Load(),
+	}
+
+	writeStats(w, stats)
+}
+
+/*
+Router configure les routes du serveur avec gorilla/mux.
+
+@returns: *mux.Router - Routeur prêt à être servi
+
+@endpoints:
+  - GET /process?key=X : Résultat mis en cache par double-checked locking
+  - GET /data/{id} : Lecture d'une entrée (404 si absente)
+  - DELETE /data/{id} : Suppression d'une entrée
+  - GET /stats : Statistiques du serveur
+  - GET /debug/allocs : Compteurs d'allocation du processus
+  - GET /debug/contention : Résumé du profil de contention des mutex
+*/
+func (r *DCLRepository) Router() *mux.Router {
+	router := newRouter()
+	router.HandleFunc("/process", r.DCLHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.GetDataHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.DeleteDataHandler).Methods("DELETE")
+	router.HandleFunc("/stats", r.StatsHandler).Methods("GET")
+	router.HandleFunc("/debug/allocs", AllocsHandler).Methods("GET")
+	router.HandleFunc("/debug/contention", ContentionHandler).Methods("GET")
+	return router
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+/*
+TestDCLComputesOnce lance de nombreuses requêtes concurrentes sur la même clé
+et vérifie qu'un seul calcul a lieu. À exécuter avec -race : la seconde
+vérification sous Lock garantit l'absence de double calcul et de data race.
+*/
+func TestDCLComputesOnce(t *testing.T) {
+	repo := NewDCLRepository()
+	handler := repo.Router()
+
+	const goroutines = 50
+	var wg sync.WaitGroup
+	results := make(chan int, goroutines)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/process?key=hot", nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, want 200", rec.Code)
+				return
+			}
+			var body struct {
+				Result int `json:"result"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Errorf("invalid JSON: %v", err)
+				return
+			}
+			results <- body.Result
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	first := -1
+	for result := range results {
+		if first == -1 {
+			first = result
+		} else if result != first {
+			t.Errorf("result = %d, want %d for every request", result, first)
+		}
+	}
+
+	if misses := repo.misses.Load(); misses != 1 {
+		t.Errorf("misses = %d, want exactly 1 computation", misses)
+	}
+	if hits := repo.hits.Load(); hits != goroutines-1 {
+		t.Errorf("hits = %d, want %d", hits, goroutines-1)
+	}
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Durée de TestNoDeadlock : go test ./pkg/server -run NoDeadlock -race -deadlock.duration=10s
+var (
+	deadlockDuration = flag.Duration("deadlock.duration", 2*time.Second, "Durée de martelage des endpoints dans TestNoDeadlock")
+	deadlockStall    = flag.Duration("deadlock.stall", time.Second, "Durée sans progression au-delà de laquelle TestNoDeadlock échoue")
+)
+
+/*
+deadlockRequest construit une requête vers un endpoint de la variante, en
+faisant tourner les clés pour que lectures, suppressions et écritures portent
+sur les mêmes entrées.
+
+@params:
+  - n: int64 numéro de la requête
+
+@returns: *http.Request - Requête vers l'un des endpoints communs
+*/
+func deadlockRequest(n int64) *http.Request {
+	id := fmt.Sprintf("request_%d", n%16)
+	switch n % 8 {
+	case 0:
+		return httptest.NewRequest(http.MethodGet, "/process?key="+id, nil)
+	case 1:
+		return httptest.NewRequest(http.MethodGet, "/process/stream", nil)
+	case 2:
+		return httptest.NewRequest(http.MethodPost, "/process/batch", strings.NewReader(`[{"name":"a"},{"name":"b"}]`))
+	case 3:
+		return httptest.NewRequest(http.MethodGet, "/data/"+id, nil)
+	case 4:
+		return httptest.NewRequest(http.MethodDelete, "/data/"+id, nil)
+	case 5:
+		return httptest.NewRequest(http.MethodGet, "/stats", nil)
+	case 6:
+		return httptest.NewRequest(http.MethodGet, "/debug/allocs", nil)
+	default:
+		return httptest.NewRequest(http.MethodGet, "/debug/contention?top=1", nil)
+	}
+}
+
+/*
+TestNoDeadlock martèle en parallèle tous les endpoints de toutes les variantes,
+janitor compris, et échoue si le compteur de requêtes terminées d'une variante
+cesse d'avancer : un handler bloqué par un mauvais ordre de verrouillage (ou
+une promotion RLock → Lock) est détecté au lieu de bloquer la suite de tests.
+À lancer avec -race pour détecter aussi les accès concurrents non protégés.
+*/
+func TestNoDeadlock(t *testing.T) {
+	const workersPerVariant = 8
+
+	opts := DefaultConfig().Options()
+	opts.TTL = 20 * time.Millisecond // Le janitor prend aussi les verrous
+
+	// Un compteur par variante : la progression des autres ne masque pas un blocage
+	progress := make([]atomic.Int64, len(Variants))
+	handlers := make([]http.Handler, len(Variants))
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for idx, v := range Variants {
+		handlers[idx] = v.NewHandler(opts)
+		for i := 0; i < workersPerVariant; i++ {
+			wg.Add(1)
+			go func(handler http.Handler, done *atomic.Int64, seed int64) {
+				defer wg.Done()
+				// Chaque worker parcourt tous les endpoints, décalé des autres
+				for n := seed; ; n++ {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					handler.ServeHTTP(httptest.NewRecorder(), deadlockRequest(n))
+					done.Add(1)
+				}
+			}(handlers[idx], &progress[idx], int64(i))
+		}
+	}
+
+	// En cas d'échec, ni les workers ni les janitors ne sont arrêtés : attendre
+	// un handler bloqué bloquerait le test au lieu de le faire échouer
+	fail := func(format string, args ...any) {
+		pprof.Lookup("goroutine").WriteTo(os.Stderr, 1)
+		t.Fatalf(format+" (goroutines dumped above)", args...)
+	}
+
+	deadline := time.After(*deadlockDuration)
+	// Vérification fréquente : un blocage est signalé dès qu'il dure deadlockStall
+	watchdog := time.NewTicker(*deadlockStall / 4)
+	defer watchdog.Stop()
+	last := make([]int64, len(Variants))
+	lastChange := make([]time.Time, len(Variants))
+	for idx := range lastChange {
+		lastChange[idx] = time.Now()
+	}
+	for running := true; running; {
+		select {
+		case <-deadline:
+			running = false
+		case now := <-watchdog.C:
+			for idx := range Variants {
+				if current := progress[idx].Load(); current != last[idx] {
+					last[idx], lastChange[idx] = current, now
+				} else if stalled := now.Sub(lastChange[idx]); stalled >= *deadlockStall {
+					fail("%s: no request completed for %v, probable deadlock", Variants[idx].Name, stalled.Round(time.Millisecond))
+				}
+			}
+		}
+	}
+	close(stop)
+
+	// Les workers finissent leur requête en cours : un handler bloqué empêcherait l'arrêt
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(*deadlockStall):
+		fail("workers still blocked %v after stop, probable deadlock", *deadlockStall)
+	}
+
+	total := int64(0)
+	for idx, handler := range handlers {
+		if closer, ok := handler.(io.Closer); ok {
+			closer.Close()
+		}
+		total += progress[idx].Load()
+	}
+	t.Logf("%d requests completed across %d variants", total, len(Variants))
+}
@@ -0,0 +1,289 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+AllocsHandler expose les compteurs d'allocation du processus serveur.
+Les benchmarks lisent ces compteurs avant et après une série de requêtes pour
+en déduire les allocations côté serveur par requête.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON contenant total_alloc_bytes, mallocs et num_gc
+
+@note: Compteurs globaux au processus ; avec `cmd/servers all`, ils cumulent
+les allocations de tous les serveurs lancés.
+*/
+func AllocsHandler(w http.ResponseWriter, req *http.Request) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total_alloc_bytes": m.TotalAlloc,
+		"mallocs":           m.Mallocs,
+		"num_gc":            m.NumGC,
+	})
+}
+
+// memStatsInterval borne la fréquence des runtime.ReadMemStats faits pour /stats
+const memStatsInterval = 100 * time.Millisecond
+
+/*
+heapGauge met en cache HeapAlloc : ReadMemStats arrête le monde, et /stats
+peut être interrogé en boucle pendant un benchmark.
+
+@fields:
+  - mu: Mutex protégeant le cache
+  - readAt: Date de la dernière lecture
+  - heapAlloc: Octets alloués sur le tas à cette date
+*/
+var heapGauge struct {
+	mu        sync.Mutex
+	readAt    time.Time
+	heapAlloc uint64
+}
+
+/*
+cachedHeapAlloc retourne HeapAlloc, relu au plus une fois par memStatsInterval.
+
+@returns: uint64 octets alloués sur le tas (vivants ou non encore collectés)
+*/
+func cachedHeapAlloc() uint64 {
+	heapGauge.mu.Lock()
+	defer heapGauge.mu.Unlock()
+	if time.Since(heapGauge.readAt) >= memStatsInterval {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		heapGauge.heapAlloc = m.HeapAlloc
+		heapGauge.readAt = time.Now()
+	}
+	return heapGauge.heapAlloc
+}
+
+/*
+addRuntimeGauges ajoute aux statistiques d'un serveur le nombre de goroutines
+et la taille du tas. Sous le serveur bad à forte concurrence, les goroutines
+en attente du mutex s'empilent : goroutines croît avec la latence.
+
+@params:
+  - stats: map[string]interface{} statistiques à compléter
+
+@note: Valeurs globales au processus, à appeler hors du mutex du repository
+*/
+func addRuntimeGauges(stats map[string]interface{}) {
+	stats["goroutines"] = runtime.NumGoroutine()
+	stats["heap_alloc_bytes"] = cachedHeapAlloc()
+}
+
+// defaultContentionTop est le nombre de sites bloquants retournés par défaut
+const defaultContentionTop = 10
+
+/*
+EnableContentionProfiling active le profil de contention des mutex du runtime.
+À appeler une fois au démarrage : la fraction est globale au processus.
+
+@params:
+  - fraction: int un événement de contention sur fraction est échantillonné (0 désactive)
+*/
+func EnableContentionProfiling(fraction int) {
+	runtime.SetMutexProfileFraction(fraction)
+}
+
+/*
+ContentionSite agrège le temps bloqué attribué à un site d'appel.
+Le runtime attribue la contention au code qui libère le mutex : pour le
+serveur bad, c'est le Unlock différé de BadHandler.
+
+@fields:
+  - Function: Première fonction hors runtime/sync de la pile
+  - Location: Fichier et ligne de l'appel
+  - BlockedMs: Temps total pendant lequel d'autres goroutines ont attendu
+  - Contentions: Nombre d'événements de contention
+*/
+type ContentionSite struct {
+	Function    string  `json:"function"`
+	Location    string  `json:"location"`
+	BlockedMs   float64 `json:"blocked_ms"`
+	Contentions int64   `json:"contentions"`
+}
+
+/*
+ContentionSummary résume le profil de contention des mutex du processus.
+
+@fields:
+  - Fraction: Fraction d'échantillonnage active
+  - TotalBlockedMs: Temps bloqué total, extrapolé selon l'échantillonnage
+  - TotalContentions: Nombre total d'événements de contention, extrapolé
+  - Sites: Sites d'appel triés par temps bloqué décroissant
+*/
+type ContentionSummary struct {
+	Fraction         int              `json:"fraction"`
+	TotalBlockedMs   float64          `json:"total_blocked_ms"`
+	TotalContentions int64            `json:"total_contentions"`
+	Sites            []ContentionSite `json:"sites"`
+}
+
+/*
+ContentionHandler expose un résumé JSON du profil "mutex" du runtime, pour
+comparer la contention des serveurs par programme.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request, paramètre optionnel ?top=N (10 par défaut)
+
+@returns: JSON ContentionSummary avec les N sites les plus bloquants
+
+@note: Comme /debug/allocs, le profil est global au processus ; avec
+`cmd/servers all`, il mélange tous les serveurs lancés.
+*/
+func ContentionHandler(w http.ResponseWriter, req *http.Request) {
+	top := defaultContentionTop
+	if raw := req.URL.Query().Get("top"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid top: "+raw)
+			return
+		}
+		top = n
+	}
+
+	var buf bytes.Buffer
+	if err := pprof.Lookup("mutex").WriteTo(&buf, 1); err != nil {
+		writeError(w, http.StatusInternalServerError, "mutex profile unavailable: "+err.Error())
+		return
+	}
+	summary, err := parseMutexProfile(&buf)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "invalid mutex profile: "+err.Error())
+		return
+	}
+	summary.Fraction = runtime.SetMutexProfileFraction(-1)
+	if len(summary.Sites) > top {
+		summary.Sites = summary.Sites[:top]
+	}
+
+	writeJSON(w, http.StatusOK, summary)
+}
+
+/*
+parseMutexProfile analyse le format texte (debug=1) du profil "mutex".
+
+@params:
+  - r: io.Reader contenant la sortie de pprof.Lookup("mutex").WriteTo(w, 1)
+
+@returns: (ContentionSummary, error) - Sites agrégés par fonction, triés par temps bloqué
+
+@behavior:
+  - Les lignes "cycles/second=" et "sampling period=" servent à convertir
+    les cycles en millisecondes et à extrapoler les échantillons
+  - Chaque enregistrement "<cycles> <count> @ ..." est attribué à la première
+    frame "#" de sa pile qui n'appartient pas à runtime, sync ou internal
+*/
+func parseMutexProfile(r io.Reader) (ContentionSummary, error) {
+	var summary ContentionSummary
+	cyclesPerMs := 0.0
+	period := int64(1)
+	sites := make(map[string]*ContentionSite)
+
+	var cycles, count int64
+	attributed := true
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "cycles/second="):
+			perSecond, err := strconv.ParseFloat(strings.TrimPrefix(line, "cycles/second="), 64)
+			if err != nil {
+				return summary, err
+			}
+			cyclesPerMs = perSecond / 1e3
+		case strings.HasPrefix(line, "sampling period="):
+			p, err := strconv.ParseInt(strings.TrimPrefix(line, "sampling period="), 10, 64)
+			if err != nil {
+				return summary, err
+			}
+			if p > 0 {
+				period = p
+			}
+		case strings.Contains(line, " @ "):
+			fields := strings.Fields(line)
+			var err error
+			if cycles, err = strconv.ParseInt(fields[0], 10, 64); err != nil {
+				return summary, err
+			}
+			if count, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+				return summary, err
+			}
+			attributed = false
+		case strings.HasPrefix(line, "#") && !attributed:
+			// Format: "#\t0xADDR\tfonction+0xOFF\tfichier:ligne"
+			fields := strings.Fields(line)
+			if len(fields) < 4 {
+				continue
+			}
+			function := fields[2]
+			if i := strings.LastIndex(function, "+0x"); i >= 0 {
+				function = function[:i]
+			}
+			if isRuntimeFrame(function) {
+				continue
+			}
+			site, ok := sites[function]
+			if !ok {
+				site = &ContentionSite{Function: function, Location: fields[3]}
+				sites[function] = site
+			}
+			site.Contentions += count * period
+			if cyclesPerMs > 0 {
+				site.BlockedMs += float64(cycles*period) / cyclesPerMs
+			}
+			attributed = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return summary, err
+	}
+
+	summary.Sites = make([]ContentionSite, 0, len(sites))
+	for _, site := range sites {
+		summary.TotalBlockedMs += site.BlockedMs
+		summary.TotalContentions += site.Contentions
+		summary.Sites = append(summary.Sites, *site)
+	}
+	sort.Slice(summary.Sites, func(i, j int) bool {
+		return summary.Sites[i].BlockedMs > summary.Sites[j].BlockedMs
+	})
+	return summary, nil
+}
+
+/*
+isRuntimeFrame indique si une frame appartient à l'implémentation des verrous
+plutôt qu'au code applicatif.
+
+@params:
+  - function: string nom complet de la fonction
+
+@returns: bool - true pour runtime, sync et internal
+*/
+func isRuntimeFrame(function string) bool {
+	for _, prefix := range []string{"runtime.", "sync.", "internal/"} {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+TestContentionHandlerReportsBadHandler sature le serveur bad puis vérifie que
+/debug/contention désigne BadHandler comme site de contention dominant.
+*/
+func TestContentionHandlerReportsBadHandler(t *testing.T) {
+	EnableContentionProfiling(1)
+	defer EnableContentionProfiling(0)
+
+	handler := NewBadRepository(false).Router()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/process", nil))
+		}()
+	}
+	wg.Wait()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/contention?top=3", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var summary ContentionSummary
+	if err := json.NewDecoder(rec.Body).Decode(&summary); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if summary.Fraction != 1 {
+		t.Errorf("fraction = %d, want 1", summary.Fraction)
+	}
+	if len(summary.Sites) == 0 || len(summary.Sites) > 3 {
+		t.Fatalf("got %d sites, want 1..3", len(summary.Sites))
+	}
+	if top := summary.Sites[0]; !strings.Contains(top.Function, "BadHandler") || top.BlockedMs <= 0 {
+		t.Errorf("top site = %+v, want BadHandler with blocked time", top)
+	}
+}
+
+/*
+TestParseMutexProfile vérifie la conversion cycles -> ms, l'extrapolation par
+la période d'échantillonnage et l'attribution à la première frame applicative.
+*/
+func TestParseMutexProfile(t *testing.T) {
+	profile := `--- mutex:
+cycles/second=2000000000
+sampling period=2
+4000000 3 @ 0x1 0x2 0x3
+#	0x1	sync.(*Mutex).Unlock+0x12	/usr/local/go/src/sync/mutex.go:65
+#	0x2	mutex-benchmark/pkg/server.(*BadRepository).BadHandler+0x84	/src/bad.go:120
+#	0x3	net/http.HandlerFunc.ServeHTTP+0x2f	/usr/local/go/src/net/http/server.go:2220
+`
+	summary, err := parseMutexProfile(strings.NewReader(profile))
+	if err != nil {
+		t.Fatalf("parseMutexProfile: %v", err)
+	}
+	if len(summary.Sites) != 1 {
+		t.Fatalf("got %d sites, want 1", len(summary.Sites))
+	}
+	site := summary.Sites[0]
+	if site.Function != "mutex-benchmark/pkg/server.(*BadRepository).BadHandler" || site.Location != "/src/bad.go:120" {
+		t.Errorf("site = %+v", site)
+	}
+	// 4e6 cycles × 2 / 2e9 cycles/s = 4ms
+	if site.BlockedMs != 4 || site.Contentions != 6 {
+		t.Errorf("blocked = %.2fms contentions = %d, want 4ms and 6", site.BlockedMs, site.Contentions)
+	}
+	if summary.TotalBlockedMs != 4 || summary.TotalContentions != 6 {
+		t.Errorf("totals = %.2fms / %d, want 4ms / 6", summary.TotalBlockedMs, summary.TotalContentions)
+	}
+}
+
+/*
+TestStatsRuntimeGauges vérifie que /stats des serveurs bad, good et syncmap
+expose goroutines et heap_alloc_bytes, et que HeapAlloc est servi depuis le
+cache tant que memStatsInterval n'est pas écoulé.
+*/
+func TestStatsRuntimeGauges(t *testing.T) {
+	for _, name := range []string{"bad", "good", "syncmap"} {
+		v, _ := Lookup(name)
+		handler := v.NewHandler(Options{CopyData: true})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+		handler.(io.Closer).Close()
+
+		var stats struct {
+			Goroutines     int    `json:"goroutines"`
+			HeapAllocBytes uint64 `json:"heap_alloc_bytes"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+			t.Fatalf("%s: invalid JSON: %v", name, err)
+		}
+		if stats.Goroutines <= 0 || stats.HeapAllocBytes == 0 {
+			t.Errorf("%s: stats = %+v, want positive goroutines and heap_alloc_bytes", name, stats)
+		}
+	}
+
+	heapGauge.mu.Lock()
+	heapGauge.readAt, heapGauge.heapAlloc = time.Now(), 42
+	heapGauge.mu.Unlock()
+	if got := cachedHeapAlloc(); got != 42 {
+		t.Errorf("cachedHeapAlloc = %d within the interval, want cached 42", got)
+	}
+	// Invalide le cache pour les tests suivants
+	heapGauge.mu.Lock()
+	heapGauge.readAt = time.Time{}
+	heapGauge.mu.Unlock()
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// directParallelism multiplie GOMAXPROCS pour obtenir le nombre de goroutines des benchmarks Parallel
+var directParallelism = flag.Int("direct.parallelism", 4, "goroutines par GOMAXPROCS des benchmarks *HandlerParallel")
+
+/*
+benchmarkHandlerDirect appelle un handler en parallèle sans passer par le
+réseau : ni connexion TCP, ni client HTTP, ni routeur. Seul le verrouillage
+et le traitement restent mesurés, ce qui isole l'effet du mutex.
+
+@params:
+  - b: *testing.B benchmark courant
+  - handler: http.HandlerFunc handler appelé directement
+  - parallelism: int goroutines par GOMAXPROCS (0 garde le défaut de b.RunParallel)
+
+@behavior:
+  - Chaque goroutine de b.RunParallel construit sa requête et son recorder
+  - Échoue si une réponse n'est pas un 200
+*/
+func benchmarkHandlerDirect(b *testing.B, handler http.HandlerFunc, parallelism int) {
+	if parallelism > 0 {
+		b.SetParallelism(parallelism)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(http.MethodGet, "/process", nil))
+			if rec.Code != http.StatusOK {
+				b.Errorf("status %d", rec.Code)
+				return
+			}
+		}
+	})
+}
+
+/*
+BenchmarkBadHandlerDirect mesure BadHandler sans la couche HTTP.
+À comparer avec BenchmarkGoodHandlerDirect : l'écart est celui du verrou
+tenu pendant le traitement lourd, sans le bruit du réseau.
+
+	go test ./pkg/server -run '^$' -bench HandlerDirect
+*/
+func BenchmarkBadHandlerDirect(b *testing.B) {
+	benchmarkHandlerDirect(b, NewBadRepository(true).BadHandler, 0)
+}
+
+/*
+BenchmarkGoodHandlerDirect mesure GoodHandler sans la couche HTTP.
+*/
+func BenchmarkGoodHandlerDirect(b *testing.B) {
+	benchmarkHandlerDirect(b, NewGoodRepository(true).GoodHandler, 0)
+}
+
+/*
+BenchmarkBadHandlerParallel mesure BadHandler avec -direct.parallelism
+goroutines par GOMAXPROCS. Le flag -cpu fait varier GOMAXPROCS : la latence
+du serveur bad reste constante quel que soit le nombre de cœurs, le verrou
+sérialisant tout.
+
+	go test ./pkg/server -run '^$' -bench HandlerParallel -cpu 1,2,4,8
+
+@behavior:
+  - Le repository est créé une fois par benchmark et partagé par toutes les goroutines
+*/
+func BenchmarkBadHandlerParallel(b *testing.B) {
+	benchmarkHandlerDirect(b, NewBadRepository(true).BadHandler, *directParallelism)
+}
+
+/*
+BenchmarkGoodHandlerParallel mesure GoodHandler avec la même charge : le
+temps par opération baisse avec GOMAXPROCS et le parallélisme.
+*/
+func BenchmarkGoodHandlerParallel(b *testing.B) {
+	benchmarkHandlerDirect(b, NewGoodRepository(true).GoodHandler, *directParallelism)
+}
+
+/*
+BenchmarkSyncMapHandlerParallel mesure SyncMapHandler avec la même charge.
+*/
+func BenchmarkSyncMapHandlerParallel(b *testing.B) {
+	benchmarkHandlerDirect(b, NewSyncMapRepository(true).SyncMapHandler, *directParallelism)
+}
@@ -0,0 +1,16 @@
+/*
+Package server contient toute la logique des serveurs de démonstration,
+importable et testable sans lancer de binaire.
+
+@behavior:
+  - Une variante = un type repository et son constructeur (NewBadRepository,
+    NewGoodRepository, NewSyncMapRepository, ...), qui partagent DataStruct
+  - NewRepository(opts ...Option) construit les variantes good, rwmutex et
+    syncmap à partir d'options composables (WithLockStrategy, WithMapCap,
+    WithTTL, WithWork, WithCopyData)
+  - Router() expose les handlers d'une variante : un httptest.Server ou un
+    httptest.NewRecorder suffit pour la tester ou la benchmarker
+  - Variants recense les variantes (nom, port, construction depuis Options)
+  - Les binaires cmd/* ne font qu'analyser les flags et appeler Run
+*/
+package server
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// drainLogInterval est la période des messages de progression pendant le drain
+const drainLogInterval = 500 * time.Millisecond
+
+/*
+inFlightHandler compte les requêtes en cours de traitement : la jauge monte
+à l'entrée du handler et redescend à sa sortie, réponse écrite. Posé par
+NewServer au plus près du serveur, il compte toutes les routes.
+
+@fields:
+  - next: Handler de la variante et de ses middlewares
+  - inFlight: Requêtes entrées et pas encore sorties
+*/
+type inFlightHandler struct {
+	next     http.Handler
+	inFlight atomic.Int64
+}
+
+/*
+withInFlight enveloppe un handler dans la jauge des requêtes en cours.
+
+@params:
+  - next: http.Handler handler à compter
+
+@returns: *inFlightHandler - Handler comptant ses requêtes en cours
+*/
+func withInFlight(next http.Handler) *inFlightHandler {
+	return &inFlightHandler{next: next}
+}
+
+/*
+ServeHTTP sert la requête en la comptant dans la jauge pendant son traitement.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+*/
+func (h *inFlightHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.inFlight.Add(1)
+	defer h.inFlight.Add(-1)
+	h.next.ServeHTTP(w, req)
+}
+
+/*
+drain arrête un serveur : il n'accepte plus de connexion et attend, au plus
+jusqu'à l'échéance de ctx, la fin des requêtes en cours. Le nombre de
+requêtes en cours est journalisé à l'arrêt, puis toutes les
+drainLogInterval tant que le drain dure.
+
+@params:
+  - ctx: context.Context échéance du drain
+  - srv: *http.Server serveur à arrêter (sa jauge est lue si NewServer l'a posée)
+  - logf: func(format string, args ...any) journal de progression (log.Printf dans Run)
+
+@returns: error - nil si toutes les requêtes ont fini, l'erreur de ctx sinon
+
+@note: Sur le serveur bad, une requête en cours peut attendre le mutex
+derrière toutes les autres : le drain dure alors autant que la file du
+verrou, un traitement par requête en attente, et non un seul traitement.
+*/
+func drain(ctx context.Context, srv *http.Server, logf func(format string, args ...any)) error {
+	gauge, _ := srv.Handler.(*inFlightHandler)
+	inFlight := func() int64 {
+		if gauge == nil {
+			return 0
+		}
+		return gauge.inFlight.Load()
+	}
+
+	start := time.Now()
+	logf("arrêt de %s : %d requête(s) en cours", srv.Addr, inFlight())
+	done := make(chan error, 1)
+	go func() { done <- srv.Shutdown(ctx) }()
+
+	ticker := time.NewTicker(drainLogInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			elapsed := time.Since(start).Round(time.Millisecond)
+			if err != nil {
+				logf("arrêt de %s : délai dépassé après %s, %d requête(s) interrompue(s)", srv.Addr, elapsed, inFlight())
+				return err
+			}
+			logf("arrêt de %s : drain terminé en %s", srv.Addr, elapsed)
+			return nil
+		case <-ticker.C:
+			logf("arrêt de %s : drain en cours, %d requête(s) restante(s)", srv.Addr, inFlight())
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+startDrainServer sert le serveur bad, traitement de work, derrière la jauge
+des requêtes en cours, et lance une requête /process.
+
+@params:
+  - t: *testing.T instance du test
+  - work: time.Duration durée du traitement lourd
+
+@returns: (*http.Server, <-chan error) - Serveur, et résultat de la requête
+lancée (nil pour un 200)
+*/
+func startDrainServer(t *testing.T, work time.Duration) (*http.Server, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gauge := withInFlight(NewRepository(WithLockStrategy(DeferMutex), WithWork(work, 0)))
+	srv := &http.Server{Addr: ln.Addr().String(), Handler: gauge}
+	go srv.Serve(ln)
+
+	result := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + srv.Addr + "/process")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				err = fmt.Errorf("status %d", resp.StatusCode)
+			}
+		}
+		result <- err
+	}()
+	for deadline := time.Now().Add(5 * time.Second); gauge.inFlight.Load() == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("request never reached the handler")
+		}
+	}
+	return srv, result
+}
+
+// drainLog collecte les messages de drain pour les vérifier
+type drainLog struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *drainLog) logf(format string, args ...any) {
+	l.mu.Lock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+	l.mu.Unlock()
+}
+
+/*
+TestDrainWaitsForInFlight vérifie que drain attend une requête lente du
+serveur bad déjà en cours : elle se termine par un 200, drain ne rend la
+main qu'après elle, et le journal annonce une requête en cours.
+*/
+func TestDrainWaitsForInFlight(t *testing.T) {
+	const work = 300 * time.Millisecond
+	srv, result := startDrainServer(t, work)
+
+	var log drainLog
+	start := time.Now()
+	if err := drain(context.Background(), srv, log.logf); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+	elapsed := time.Since(start)
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("in-flight request: %v, want 200", err)
+		}
+	default:
+		t.Error("drain returned before the in-flight request finished")
+	}
+	if elapsed < work/2 {
+		t.Errorf("drain took %v, want it to wait for the %v request", elapsed, work)
+	}
+	if len(log.lines) < 2 || !strings.Contains(log.lines[0], "1 requête(s) en cours") || !strings.Contains(log.lines[len(log.lines)-1], "drain terminé") {
+		t.Errorf("drain log = %q, want 1 request in flight, then the drain done", log.lines)
+	}
+	if _, err := http.Get("http://" + srv.Addr + "/stats"); err == nil {
+		t.Error("server still accepts connections after drain")
+	}
+}
+
+/*
+TestDrainDeadline vérifie qu'une requête plus longue que l'échéance ne
+bloque pas l'arrêt : drain rend l'erreur du contexte et journalise la
+requête interrompue.
+*/
+func TestDrainDeadline(t *testing.T) {
+	srv, result := startDrainServer(t, 2*time.Second)
+	defer func() { <-result }()
+
+	var log drainLog
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := drain(ctx, srv, log.logf); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("drain = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if last := log.lines[len(log.lines)-1]; !strings.Contains(last, "délai dépassé") || !strings.Contains(last, "1 requête(s) interrompue(s)") {
+		t.Errorf("drain log = %q, want the deadline and 1 interrupted request", log.lines)
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// defaultEncoder est l'encodeur des réponses sans -encoder : encoding/json, sans dépendance
+const defaultEncoder = "stdlib"
+
+/*
+Encoder encode les réponses JSON des handlers de /process (flag -encoder).
+Changer d'encodeur à verrouillage identique isole la part de l'encodage JSON
+dans le coût d'une requête.
+
+@note: Encode doit écrire v suivi d'un '\n', comme json.Encoder, et rendre
+une erreur pour une valeur que encoding/json refuserait (NaN, canal) :
+writeEncoded répond alors 500 sans rien envoyer du corps.
+*/
+type Encoder interface {
+	Encode(w io.Writer, v any) error
+}
+
+/*
+stdlibEncoder encode avec encoding/json. Dans un responseBuffer, il réutilise
+le json.Encoder du buffer au lieu d'en allouer un par réponse.
+*/
+type stdlibEncoder struct{}
+
+// Encode écrit v en JSON suivi d'un '\n'
+func (stdlibEncoder) Encode(w io.Writer, v any) error {
+	if b, ok := w.(*responseBuffer); ok {
+		return b.enc.Encode(v)
+	}
+	return json.NewEncoder(w).Encode(v)
+}
+
+// encoders sont les encodeurs compilés dans le binaire, par nom de -encoder (complété par les fichiers à build tag)
+var encoders = map[string]Encoder{
+	defaultEncoder: stdlibEncoder{},
+}
+
+// encoderBuildTags associe les encodeurs optionnels au build tag qui les compile
+var encoderBuildTags = map[string]string{
+	"jsoniter": "jsoniter",
+}
+
+/*
+encoderNames retourne les noms des encodeurs compilés, triés.
+
+@returns: []string - Noms acceptés par -encoder
+*/
+func encoderNames() []string {
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/*
+checkEncoder vérifie le nom d'encodeur d'une configuration ou d'un flag.
+
+@params:
+  - name: string valeur de -encoder ("" = stdlib)
+
+@returns: error - Erreur si l'encodeur est inconnu, ou connu mais absent du
+binaire faute du build tag qui le compile
+*/
+func checkEncoder(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := encoders[name]; ok {
+		return nil
+	}
+	if tag, ok := encoderBuildTags[name]; ok {
+		return fmt.Errorf("encoder %q: not built in, rebuild with -tags %s", name, tag)
+	}
+	return fmt.Errorf("encoder %q: want one of %s", name, strings.Join(encoderNames(), ", "))
+}
+
+/*
+encoder retourne l'encodeur choisi par les options.
+
+@returns: Encoder - Encodeur -encoder, stdlib s'il est vide ou absent du
+binaire (ParseFlags et LoadConfig l'ont déjà refusé)
+*/
+func (o Options) encoder() Encoder {
+	if enc, ok := encoders[o.Encoder]; ok {
+		return enc
+	}
+	return stdlibEncoder{}
+}
@@ -0,0 +1,36 @@
+//go:build jsoniter
+
+package server
+
+import (
+	"io"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// jsoniterAPI reproduit encoding/json : clés de map triées, HTML échappé, NaN refusé
+var jsoniterAPI = jsoniter.ConfigCompatibleWithStandardLibrary
+
+/*
+jsoniterEncoder encode avec json-iterator (-encoder=jsoniter), compilé
+seulement avec -tags jsoniter : sans ce tag, le binaire ne dépend pas de la
+bibliothèque.
+*/
+type jsoniterEncoder struct{}
+
+// Encode écrit v en JSON suivi d'un '\n' dans le buffer d'un Stream emprunté au pool de jsoniter, puis le copie dans w
+func (jsoniterEncoder) Encode(w io.Writer, v any) error {
+	stream := jsoniterAPI.BorrowStream(nil)
+	defer jsoniterAPI.ReturnStream(stream)
+	stream.WriteVal(v)
+	if stream.Error != nil {
+		return stream.Error
+	}
+	stream.WriteRaw("\n")
+	_, err := w.Write(stream.Buffer())
+	return err
+}
+
+func init() {
+	encoders["jsoniter"] = jsoniterEncoder{}
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+encoderResponses construit les réponses encodées par les tests et le
+benchmark : celle de /process, et celle de /process?include=data avec
+100 entrées (un payload de 64 octets chacune).
+
+@returns: map[string]*ResponseV1 - Réponse par nom de cas
+*/
+func encoderResponses() map[string]*ResponseV1 {
+	process := func() *ResponseV1 {
+		return &ResponseV1{
+			SchemaVersion: SchemaVersion,
+			Method:        "good_no_defer",
+			RequestID:     "0b6a3b8e-3c69-4a6f-9d3f-5e0b2c7a1d42",
+			Counter:       123456,
+			Result:        499999500000,
+			SnapshotSize:  100,
+			Duration:      10342,
+		}
+	}
+	snapshot := make(map[string]*DataStruct, 100)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("request_%d", i)
+		snapshot[key] = &DataStruct{
+			Identifier:   key,
+			Name:         fmt.Sprintf("Request <%d> & co", i),
+			IsActive:     i%2 == 0,
+			Counter:      i * 1000,
+			LastModified: time.Date(2024, 5, 1, 12, 0, i, 0, time.UTC),
+			Payload:      newPayload(64),
+		}
+	}
+	withData := process()
+	addSnapshot(withData, snapshot)
+	return map[string]*ResponseV1{"process": process(), "snapshot100": withData}
+}
+
+/*
+TestEncodersMatchStdlib vérifie que chaque encodeur compilé produit
+exactement le JSON d'encoding/json (clés triées, HTML échappé, '\n' final),
+et qu'une valeur non encodable devient un 500 sans corps partiel.
+*/
+func TestEncodersMatchStdlib(t *testing.T) {
+	for _, name := range encoderNames() {
+		enc := encoders[name]
+		for caseName, response := range encoderResponses() {
+			var want, got bytes.Buffer
+			if err := (stdlibEncoder{}).Encode(&want, response); err != nil {
+				t.Fatal(err)
+			}
+			if err := enc.Encode(&got, response); err != nil {
+				t.Fatalf("%s/%s: %v", name, caseName, err)
+			}
+			if got.String() != want.String() {
+				t.Errorf("%s/%s:\n%s\nwant:\n%s", name, caseName, got.String(), want.String())
+			}
+		}
+
+		rec := httptest.NewRecorder()
+		writeEncoded(rec, http.StatusOK, map[string]float64{"value": math.NaN()}, enc)
+		if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), `"status":500`) {
+			t.Errorf("%s: NaN gave %d %q, want a 500 error body", name, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+/*
+TestCheckEncoder vérifie les noms acceptés par -encoder : vide ou stdlib
+toujours, jsoniter seulement dans un binaire compilé avec -tags jsoniter.
+*/
+func TestCheckEncoder(t *testing.T) {
+	for _, name := range []string{"", "stdlib"} {
+		if err := checkEncoder(name); err != nil {
+			t.Errorf("checkEncoder(%q) = %v", name, err)
+		}
+	}
+	_, built := encoders["jsoniter"]
+	if err := checkEncoder("jsoniter"); built != (err == nil) {
+		t.Errorf("checkEncoder(jsoniter) = %v with jsoniter built in = %v", err, built)
+	} else if err != nil && !strings.Contains(err.Error(), "-tags jsoniter") {
+		t.Errorf("checkEncoder(jsoniter) = %v, want a hint about -tags jsoniter", err)
+	}
+	if err := checkEncoder("nope"); err == nil || !strings.Contains(err.Error(), "stdlib") {
+		t.Errorf("checkEncoder(nope) = %v, want the list of encoders", err)
+	}
+	if opts := (Options{Encoder: "nope"}); opts.encoder() != (stdlibEncoder{}) {
+		t.Errorf("Options.encoder() = %T, want the stdlib fallback", opts.encoder())
+	}
+}
+
+/*
+BenchmarkEncoder compare les encodeurs compilés sur la réponse de /process
+et sur celle de /process?include=data, encodées comme par les handlers dans
+un buffer de responsePool. Sans build tag, seul stdlib est mesuré :
+
+	go test ./pkg/server -run '^$' -bench Encoder -benchmem -tags jsoniter
+*/
+func BenchmarkEncoder(b *testing.B) {
+	responses := encoderResponses()
+	w := &discardResponse{header: make(http.Header)}
+	for _, caseName := range []string{"process", "snapshot100"} {
+		response := responses[caseName]
+		for _, name := range encoderNames() {
+			enc := encoders[name]
+			b.Run(caseName+"/"+name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					writeEncoded(w, http.StatusOK, response, enc)
+				}
+			})
+		}
+	}
+}
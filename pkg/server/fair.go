@@ -0,0 +1,327 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+/*
+FairRepository répond à la critique "la comparaison bad/good n'est pas
+équitable" : ses deux modes exécutent EXACTEMENT la même section critique
+(la même fonction), seule la libération du mutex change. L'écart mesuré
+entre les deux est le coût de defer, et non celui de la taille de la section.
+
+@fields:
+  - mu: Mutex protégeant counter et data
+  - counter: Compteur global des requêtes
+  - data: Map des résultats, comme le serveur bad
+  - copyData: Active la copie des données avant traitement (workload "lecture puis traitement")
+  - work: Traitement lourd de la section critique, identique dans les deux modes
+  - mapCap: Nombre maximum d'entrées de data (0 = illimité)
+  - payload: Taille en octets du blob Payload de chaque entrée écrite
+  - enc: Encodeur des réponses de /process (voir -encoder)
+  - lockHold: Durées de détention du mutex, identiques dans les deux modes
+*/
+type FairRepository struct {
+	mu       sync.Mutex
+	counter  int
+	data     map[string]*DataStruct
+	copyData bool
+	work     WorkFunc
+	mapCap   int
+	payload  int
+	enc      Encoder
+	lockHold lockHoldStats
+}
+
+/*
+fairResult contient le résultat d'une section critique.
+*/
+type fairResult struct {
+	counter  int
+	result   int
+	snapshot map[string]*DataStruct // Copie des données lue sous le mutex
+}
+
+/*
+NewFairRepository crée et initialise un nouveau repository.
+
+@params:
+  - copyData: bool active la copie des données partagées à chaque requête
+
+@returns: *FairRepository - Nouvelle instance avec la map initialisée
+*/
+func NewFairRepository(copyData bool) *FairRepository {
+	return newFairRepository(newRepoOptions(WithCopyData(copyData)))
+}
+
+/*
+newFairRepository crée le repository à partir d'options déjà appliquées,
+pour que la section critique exécute le traitement injecté (WithWorkFunc)
+comme le serveur bad.
+
+@params:
+  - o: repoOptions configuration (copie, borne de la map, traitement, payload, encodeur)
+
+@returns: *FairRepository - Nouvelle instance avec la map initialisée
+*/
+func newFairRepository(o repoOptions) *FairRepository {
+	return &FairRepository{
+		data:     make(map[string]*DataStruct),
+		copyData: o.copyData,
+		work:     o.work,
+		mapCap:   o.mapCap,
+		payload:  o.payload,
+		enc:      o.encoder,
+	}
+}
+
+/*
+FairHandler exécute la section critique du serveur bad (lecture, traitement
+lourd et écriture sous le mutex), en libérant le mutex selon ?release=.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP (release=defer par défaut, ou unlock)
+
+@behavior:
+  1. release=defer : Lock, defer Unlock, section critique
+  2. release=unlock : Lock, section critique, Unlock explicite au même point
+  3. Autre valeur : 400
+
+@performance: Les deux modes ont la latence du serveur bad : c'est la taille de
+la section critique qui coûte, pas defer
+*/
+func (r *FairRepository) FairHandler(w http.ResponseWriter, req *http.Request) {
+	includeData, ok := parseIncludeData(w, req)
+	if !ok {
+		return
+	}
+	start := time.Now()
+	ctx, span := startSpan(req.Context(), "fair.process")
+	defer span.End()
+
+	release := req.URL.Query().Get("release")
+	var res fairResult
+	switch release {
+	case "", "defer":
+		release = "defer"
+		res = r.processDefer(ctx)
+	case "unlock":
+		res = r.processUnlock(ctx)
+	default:
+		writeError(w, http.StatusBadRequest, "invalid release: "+release+" (want defer or unlock)")
+		return
+	}
+
+	response := newResponse(req, "fair_"+release, start)
+	response.Counter = res.counter
+	response.Result = res.result
+	response.SnapshotSize = len(res.snapshot)
+
+	if includeData {
+		addSnapshot(response, res.snapshot)
+	}
+	writeEncoded(w, http.StatusOK, response, r.enc)
+}
+
+/*
+processDefer exécute la section critique avec defer Unlock.
+
+@params:
+  - ctx: context.Context contexte portant le span de la requête
+
+@returns: fairResult - Résultat de la section critique
+*/
+func (r *FairRepository) processDefer(ctx context.Context) fairResult {
+	tracedLock(ctx, "mu", r.mu.Lock)
+	defer r.mu.Unlock()
+	return r.criticalSection(ctx)
+}
+
+/*
+processUnlock exécute la même section critique avec un Unlock explicite.
+
+@params:
+  - ctx: context.Context contexte portant le span de la requête
+
+@returns: fairResult - Résultat de la section critique
+*/
+func (r *FairRepository) processUnlock(ctx context.Context) fairResult {
+	tracedLock(ctx, "mu", r.mu.Lock)
+	res := r.criticalSection(ctx)
+	r.mu.Unlock()
+	return res
+}
+
+/*
+criticalSection est la section critique commune aux deux modes, appelée
+mutex verrouillé.
+
+@params:
+  - ctx: context.Context contexte portant le span de la requête
+
+@returns: fairResult - Compteur, résultat du calcul et taille de la copie
+*/
+func (r *FairRepository) criticalSection(ctx context.Context) fairResult {
+	lockStart := time.Now()
+
+	r.counter++
+	currentCounter := r.counter
+	// La copie modélise un workload "lecture puis traitement" : son coût
+	// croît avec la taille de la map, elle est donc désactivable (-copy=false)
+	dataCopy := make(map[string]*DataStruct)
+	if r.copyData {
+		for k, v := range r.data {
+			dataCopy[k] = &DataStruct{
+				Identifier:   v.Identifier,
+				Name:         v.Name,
+				IsActive:     v.IsActive,
+				Counter:      v.Counter,
+				LastModified: v.LastModified,
+				Payload:      bytes.Clone(v.Payload),
+			}
+		}
+	}
+
+	// Traitement lourd sous le mutex, comme le serveur bad
+	heavySpan := startPhase(ctx, "process.heavy")
+	result := r.work(ctx) // Attente simulée puis calcul intensif
+	heavySpan.End()
+
+	writeSpan := startPhase(ctx, "write")
+	key := fmt.Sprintf("request_%d", currentCounter)
+	makeRoom(r.data, key, r.mapCap)
+	r.data[key] = &DataStruct{
+		Identifier:   key,
+		Name:         fmt.Sprintf("Request %d", currentCounter),
+		IsActive:     true,
+		Counter:      result,
+		LastModified: time.Now(),
+		Payload:      newPayload(r.payload),
+	}
+	writeSpan.End()
+
+	r.lockHold.record(time.Since(lockStart))
+	return fairResult{counter: currentCounter, result: result, snapshot: dataCopy}
+}
+
+/*
+evictExpired supprime les entrées dont LastModified précède cutoff.
+Appelée par le janitor, sous le mutex le temps du seul balayage.
+
+@params:
+  - cutoff: time.Time date limite de dernière modification
+
+@returns: int - Nombre d'entrées supprimées
+*/
+func (r *FairRepository) evictExpired(cutoff time.Time) int {
+	r.mu.Lock()
+	evicted := 0
+	for key, entry := range r.data {
+		if entry.LastModified.Before(cutoff) {
+			delete(r.data, key)
+			evicted++
+		}
+	}
+	r.mu.Unlock()
+	return evicted
+}
+
+/*
+GetDataHandler retourne l'entrée {id} en JSON, ou 404 si elle est absente.
+Le mutex n'est tenu que le temps de copier l'entrée, l'encodage se fait après.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+
+@returns: JSON DataStruct
+*/
+func (r *FairRepository) GetDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.mu.Lock()
+	var entry DataStruct
+	v, found := r.data[id]
+	if found {
+		entry = *v
+	}
+	r.mu.Unlock()
+
+	writeData(w, id, entry, found)
+}
+
+/*
+DeleteDataHandler supprime l'entrée {id} : 204 si supprimée, 404 si absente.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+*/
+func (r *FairRepository) DeleteDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.mu.Lock()
+	_, found := r.data[id]
+	delete(r.data, id)
+	r.mu.Unlock()
+
+	writeDeleted(w, id, found)
+}
+
+/*
+StatsHandler retourne les statistiques actuelles du serveur.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON contenant total_requests, data_size et les durées de détention du mutex
+*/
+func (r *FairRepository) StatsHandler(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	counter := r.counter
+	dataSize := len(r.data)
+	r.mu.Unlock()
+
+	avgHold, maxHold := r.lockHold.snapshot()
+	stats := map[string]interface{}{
+		"total_requests":   counter,
+		"data_size":        dataSize,
+		"avg_lock_hold_us": avgHold,
+		"max_lock_hold_us": maxHold,
+	}
+
+	writeStats(w, stats)
+}
+
+/*
+Router configure les routes du serveur avec gorilla/mux.
+
+@returns: *mux.Router - Routeur prêt à être servi
+
+@endpoints:
+  - GET /process?release=defer|unlock : Même section critique, libération par defer ou explicite
+  - GET /data/{id} : Lecture d'une entrée (404 si absente)
+  - DELETE /data/{id} : Suppression d'une entrée
+  - GET /stats : Statistiques du serveur
+  - GET /debug/allocs : Compteurs d'allocation du processus
+  - GET /debug/contention : Résumé du profil de contention des mutex
+*/
+func (r *FairRepository) Router() *mux.Router {
+	router := newRouter()
+	router.HandleFunc("/process", r.FairHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.GetDataHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.DeleteDataHandler).Methods("DELETE")
+	router.HandleFunc("/stats", r.StatsHandler).Methods("GET")
+	router.HandleFunc("/debug/allocs", AllocsHandler).Methods("GET")
+	router.HandleFunc("/debug/contention", ContentionHandler).Methods("GET")
+	return router
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+/*
+FloorRepository est le plancher théorique des variantes : un /process qui
+n'incrémente qu'un compteur atomique puis fait le traitement lourd, sans map,
+sans copie et sans aucun verrou. Rien ne s'y sérialise : sa latence est celle
+du traitement et du transport HTTP seuls.
+
+Comparé à lui, l'écart d'une variante mesure ce que coûte sa machinerie de
+map et de verrou, et non le travail irréductible que toutes font.
+
+@fields:
+  - counter: Compteur atomique des requêtes /process, seul état partagé
+  - work: Traitement lourd de /process
+  - enc: Encodeur des réponses de /process (voir -encoder)
+*/
+type FloorRepository struct {
+	counter atomic.Int64
+	work    WorkFunc
+	enc     Encoder
+}
+
+/*
+NewFloorRepository crée un repository plancher avec le traitement lourd historique.
+
+@returns: *FloorRepository - Nouvelle instance, compteur à zéro
+*/
+func NewFloorRepository() *FloorRepository {
+	return newFloorRepository(newRepoOptions())
+}
+
+/*
+newFloorRepository crée un repository plancher à partir d'options déjà
+appliquées. Seuls le traitement et l'encodeur s'appliquent : sans map, les
+options de copie, de borne, de TTL et de payload sont sans objet.
+
+@params:
+  - o: repoOptions configuration (traitement, encodeur)
+
+@returns: *FloorRepository - Nouvelle instance, compteur à zéro
+*/
+func newFloorRepository(o repoOptions) *FloorRepository {
+	return &FloorRepository{work: o.work, enc: o.encoder}
+}
+
+/*
+FloorHandler traite une requête sans aucune synchronisation hors du compteur.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@behavior:
+  1. Incrémente le compteur atomiquement
+  2. Effectue le traitement lourd
+  3. Renvoie la réponse : ni écriture, ni copie, snapshot_size vaut toujours 0
+
+@performance: Aucune attente entre requêtes : le plancher des autres variantes
+*/
+func (r *FloorRepository) FloorHandler(w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	currentCounter := int(r.counter.Add(1))
+	ctx, span := startSpan(req.Context(), "floor.process")
+	defer span.End()
+
+	heavySpan := startPhase(ctx, "process.heavy")
+	result := r.work(ctx) // Attente simulée puis calcul intensif
+	heavySpan.End()
+
+	response := newResponse(req, "atomic_floor", start)
+	response.Counter = currentCounter
+	response.Result = result
+	writeEncoded(w, http.StatusOK, response, r.enc)
+}
+
+/*
+StatsHandler retourne les statistiques actuelles du serveur.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON contenant total_requests
+*/
+func (r *FloorRepository) StatsHandler(w http.ResponseWriter, req *http.Request) {
+	stats := map[string]interface{}{
+		"total_requests": r.counter.Load(),
+	}
+
+	writeStats(w, stats)
+}
+
+/*
+Router configure les routes du serveur avec gorilla/mux.
+
+@returns: *mux.Router - Routeur prêt à être servi
+
+@endpoints:
+  - GET /process : Compteur atomique et traitement lourd, sans map ni verrou
+  - GET /stats : Statistiques du serveur
+  - GET /debug/allocs : Compteurs d'allocation du processus
+*/
+func (r *FloorRepository) Router() *mux.Router {
+	router := newRouter()
+	router.HandleFunc("/process", r.FloorHandler).Methods("GET")
+	router.HandleFunc("/stats", r.StatsHandler).Methods("GET")
+	router.HandleFunc("/debug/allocs", AllocsHandler).Methods("GET")
+	return router
+}
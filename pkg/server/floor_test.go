@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+TestFloorNoSerialization vérifie que le serveur floor ne sérialise rien :
+des requêtes simultanées se recouvrent entièrement, là où un mutex tenu
+pendant le traitement les ferait attendre l'une après l'autre, et aucune
+incrémentation du compteur ne se perd.
+*/
+func TestFloorNoSerialization(t *testing.T) {
+	const (
+		requests = 10
+		work     = 50 * time.Millisecond
+	)
+	repo := newFloorRepository(newRepoOptions(WithWork(work, 0)))
+	handler := repo.Router()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/process", nil))
+			var resp ResponseV1
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || rec.Code != http.StatusOK {
+				t.Errorf("GET /process: status %d, %v", rec.Code, err)
+			} else if resp.Method != "atomic_floor" || resp.SnapshotSize != 0 {
+				t.Errorf("GET /process = %+v, want atomic_floor without snapshot", resp)
+			}
+		}()
+	}
+	wg.Wait()
+	// Sérialisées, les requêtes prendraient requests × work = 500ms
+	if elapsed := time.Since(start); elapsed > requests*work/2 {
+		t.Errorf("%d concurrent requests took %v, want them to overlap (~%v)", requests, elapsed, work)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	var stats struct {
+		TotalRequests int64 `json:"total_requests"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if stats.TotalRequests != requests {
+		t.Errorf("total_requests = %d, want %d", stats.TotalRequests, requests)
+	}
+}
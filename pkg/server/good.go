@@ -0,0 +1,531 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+/*
+GoodRepository contient les données partagées protégées par un mutex.
+Cette structure simule un état partagé typique dans une application Go.
+
+@fields:
+  - mu: Mutex pour protéger l'accès concurrent aux données
+  - counter: Compteur global des requêtes traitées
+  - data: Map simulant des données métier partagées avec structure complexe
+  - copyData: Active la copie des données avant traitement (workload "lecture puis traitement")
+  - lockHold: Durées de détention du mutex sur /process, le flux, l'upstream et les lots
+  - lockWait: Histogramme des attentes de TOUTES les acquisitions du mutex
+  - mapCap: Nombre maximum d'entrées de data (0 = illimité)
+  - work: Traitement lourd de /process et de chaque élément d'un lot
+  - payload: Taille en octets du blob Payload de chaque entrée écrite, copié sous le mutex
+  - upstream: URL appelée par /process/upstream, hors mutex ("" = non configuré)
+  - enc: Encodeur des réponses de /process, des lots et de l'upstream (voir -encoder)
+*/
+type GoodRepository struct {
+	mu       sync.Mutex
+	counter  int
+	data     map[string]*DataStruct
+	copyData bool
+	lockHold lockHoldStats
+	lockWait lockWaitHistogram
+	mapCap   int
+	work     WorkFunc
+	payload  int
+	upstream string
+	enc      Encoder
+}
+
+/*
+NewGoodRepository crée et initialise un nouveau repository.
+
+@params:
+  - copyData: bool active la copie des données partagées à chaque requête
+
+@returns: *GoodRepository - Nouvelle instance avec la map initialisée
+*/
+func NewGoodRepository(copyData bool) *GoodRepository {
+	return newGoodRepository(newRepoOptions(WithCopyData(copyData)))
+}
+
+/*
+newGoodRepository crée un repository à partir d'options déjà appliquées.
+
+@params:
+  - o: repoOptions configuration (copie, borne de la map, traitement, payload, upstream, encodeur)
+
+@returns: *GoodRepository - Nouvelle instance avec la map initialisée
+*/
+func newGoodRepository(o repoOptions) *GoodRepository {
+	return &GoodRepository{
+		data:     make(map[string]*DataStruct),
+		copyData: o.copyData,
+		mapCap:   o.mapCap,
+		work:     o.work,
+		payload:  o.payload,
+		upstream: o.upstream,
+		enc:      o.encoder,
+	}
+}
+
+/*
+GoodHandler démontre la BONNE PRATIQUE d'utilisation des mutex.
+Le mutex est libéré immédiatement après chaque opération critique,
+permettant un maximum de parallélisme.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@behavior:
+  1. Verrouille le mutex pour la lecture/copie des données
+  2. Libère immédiatement le mutex après la copie
+  3. Effectue le traitement lourd SANS le mutex
+  4. Re-verrouille uniquement pour l'écriture finale
+  5. Libère immédiatement après l'écriture
+
+@performance: Cette approche maximise la concurrence et les performances
+*/
+func (r *GoodRepository) GoodHandler(w http.ResponseWriter, req *http.Request) {
+	includeData, ok := parseIncludeData(w, req)
+	if !ok {
+		return
+	}
+	start := time.Now()
+	ctx, span := startSpan(req.Context(), "good.process")
+	defer span.End()
+
+	// Première acquisition du mutex pour lecture
+	r.lockWait.record(tracedLock(ctx, "mu.read", r.mu.Lock))
+	lockStart := time.Now()
+	r.counter++
+	currentCounter := r.counter
+	// La copie modélise un workload "lecture puis traitement" : son coût
+	// croît avec la taille de la map, elle est donc désactivable (-copy=false)
+	dataCopy := make(map[string]*DataStruct)
+	if r.copyData {
+		for k, v := range r.data {
+			dataCopy[k] = &DataStruct{
+				Identifier:   v.Identifier,
+				Name:         v.Name,
+				IsActive:     v.IsActive,
+				Counter:      v.Counter,
+				LastModified: v.LastModified,
+				Payload:      bytes.Clone(v.Payload),
+			}
+		}
+	}
+	lockHeld := time.Since(lockStart)
+	r.mu.Unlock() // Libération immédiate après la lecture
+	r.lockHold.record(lockHeld)
+
+	// Traitement lourd SANS le mutex
+	heavySpan := startPhase(ctx, "process.heavy")
+	result := r.work(ctx) // Attente simulée puis calcul intensif
+	heavySpan.End()
+
+	// Deuxième acquisition du mutex uniquement pour l'écriture
+	key := fmt.Sprintf("request_%d", currentCounter)
+	payload := newPayload(r.payload) // Allouée avant de reprendre le mutex
+	writeSpan := startPhase(ctx, "write")
+	r.lockWait.record(tracedLock(ctx, "mu.write", r.mu.Lock))
+	lockStart = time.Now()
+	makeRoom(r.data, key, r.mapCap)
+	r.data[key] = &DataStruct{
+		Identifier:   key,
+		Name:         fmt.Sprintf("Request %d", currentCounter),
+		IsActive:     true,
+		Counter:      result,
+		LastModified: time.Now(),
+		Payload:      payload,
+	}
+	lockHeld = time.Since(lockStart)
+	r.mu.Unlock() // Libération immédiate après l'écriture
+	writeSpan.End()
+	r.lockHold.record(lockHeld)
+
+	response := newResponse(req, "good_no_defer", start)
+	response.Counter = currentCounter
+	response.Result = result
+	response.SnapshotSize = len(dataCopy)
+
+	if includeData {
+		addSnapshot(response, dataCopy)
+	}
+	writeEncoded(w, http.StatusOK, response, r.enc)
+}
+
+/*
+StreamHandler diffuse la progression du traitement lourd en NDJSON, mutex
+libéré AVANT le flux : le transfert vers le client ne bloque personne.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@behavior:
+  1. Verrouille le mutex pour réserver le compteur, puis le libère
+  2. Envoie une ligne {"step", "steps", "partial"} après chaque étape du calcul, SANS le mutex
+  3. Re-verrouille uniquement pour l'écriture, puis envoie {"done": true, ...}
+
+@performance: Les flux avancent en parallèle, quel que soit le débit des clients
+*/
+func (r *GoodRepository) StreamHandler(w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+
+	flusher, ok := startStream(w)
+	if !ok {
+		return
+	}
+
+	r.lockWait.record(tracedLock(req.Context(), "mu.read", r.mu.Lock))
+	lockStart := time.Now()
+	r.counter++
+	currentCounter := r.counter
+	lockHeld := time.Since(lockStart)
+	r.mu.Unlock()
+	r.lockHold.record(lockHeld)
+
+	// Flux SANS le mutex
+	result := streamHeavyWork(req.Context(), w, flusher, r.work)
+
+	key := fmt.Sprintf("request_%d", currentCounter)
+	payload := newPayload(r.payload)
+	r.lockWait.record(tracedLock(req.Context(), "mu.write", r.mu.Lock))
+	lockStart = time.Now()
+	makeRoom(r.data, key, r.mapCap)
+	r.data[key] = &DataStruct{
+		Identifier:   key,
+		Name:         fmt.Sprintf("Request %d", currentCounter),
+		IsActive:     true,
+		Counter:      result,
+		LastModified: time.Now(),
+		Payload:      payload,
+	}
+	lockHeld = time.Since(lockStart)
+	r.mu.Unlock()
+	r.lockHold.record(lockHeld)
+
+	response := newResponse(req, "good_no_defer_stream", start)
+	response.Counter = currentCounter
+	response.Result = result
+	response.Done = true
+	json.NewEncoder(w).Encode(response)
+}
+
+/*
+UpstreamHandler appelle l'upstream configuré (-upstream) SANS le mutex :
+le verrou ne protège que l'incrément du compteur et l'écriture finale, les
+appels réseau des requêtes concurrentes se recouvrent.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@behavior:
+  1. Verrouille le mutex pour réserver un compteur, puis le libère
+  2. Appelle l'upstream (GET) et lit sa réponse SANS le mutex
+  3. Re-verrouille uniquement pour l'écriture ; 502 si l'upstream échoue,
+     503 s'il n'est pas configuré
+
+@performance: La latence de l'upstream est payée en parallèle
+*/
+func (r *GoodRepository) UpstreamHandler(w http.ResponseWriter, req *http.Request) {
+	if !upstreamConfigured(w, r.upstream) {
+		return
+	}
+	start := time.Now()
+	ctx, span := startSpan(req.Context(), "good.upstream")
+	defer span.End()
+
+	r.lockWait.record(tracedLock(ctx, "mu.read", r.mu.Lock))
+	lockStart := time.Now()
+	r.counter++
+	currentCounter := r.counter
+	lockHeld := time.Since(lockStart)
+	r.mu.Unlock() // Libération AVANT l'appel réseau
+	r.lockHold.record(lockHeld)
+
+	upstreamSpan := startPhase(ctx, "upstream.get")
+	upstream, err := callUpstream(ctx, r.upstream)
+	upstreamSpan.End()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "upstream: "+err.Error())
+		return
+	}
+
+	key := fmt.Sprintf("request_%d", currentCounter)
+	payload := newPayload(r.payload)
+	r.lockWait.record(tracedLock(ctx, "mu.write", r.mu.Lock))
+	lockStart = time.Now()
+	makeRoom(r.data, key, r.mapCap)
+	r.data[key] = &DataStruct{
+		Identifier:   key,
+		Name:         fmt.Sprintf("Upstream %d", currentCounter),
+		IsActive:     true,
+		Counter:      int(upstream.bytes),
+		LastModified: time.Now(),
+		Payload:      payload,
+	}
+	lockHeld = time.Since(lockStart)
+	r.mu.Unlock()
+	r.lockHold.record(lockHeld)
+
+	response := newResponse(req, "good_no_defer_upstream", start)
+	response.Counter = currentCounter
+	response.UpstreamStatus = upstream.status
+	response.UpstreamBytes = upstream.bytes
+	writeEncoded(w, http.StatusOK, response, r.enc)
+}
+
+/*
+BatchHandler traite un lot d'éléments en ne verrouillant que deux fois,
+quelle que soit la taille du lot : une réservation des compteurs, puis une
+écriture groupée. Le traitement lourd de tous les éléments se fait sans mutex.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant un tableau JSON de BatchItem
+
+@behavior:
+  1. Décode le lot (400 si invalide)
+  2. Réserve N compteurs en une seule section critique
+  3. Effectue les N traitements lourds SANS le mutex
+  4. Écrit les N résultats en une seule section critique
+
+@performance: Le nombre d'acquisitions du mutex ne dépend pas de la taille du lot
+*/
+func (r *GoodRepository) BatchHandler(w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+
+	items, err := decodeBatch(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid batch: "+err.Error())
+		return
+	}
+
+	// Réservation des compteurs pour tout le lot
+	r.lockWait.record(tracedLock(req.Context(), "mu.read", r.mu.Lock))
+	lockStart := time.Now()
+	firstCounter := r.counter + 1
+	r.counter += len(items)
+	lockHeld := time.Since(lockStart)
+	r.mu.Unlock()
+	r.lockHold.record(lockHeld)
+
+	// Traitement lourd de chaque élément SANS le mutex
+	entries := make([]*DataStruct, len(items))
+	counters := make([]int, len(items))
+	for idx, item := range items {
+		result := r.work(req.Context()) // Attente simulée puis calcul intensif
+
+		counters[idx] = firstCounter + idx
+		key := fmt.Sprintf("request_%d", counters[idx])
+		entries[idx] = &DataStruct{
+			Identifier:   key,
+			Name:         item.Name,
+			IsActive:     true,
+			Counter:      result,
+			LastModified: time.Now(),
+			Payload:      newPayload(r.payload),
+		}
+	}
+
+	// Écriture groupée : une seule acquisition pour tout le lot
+	r.lockWait.record(tracedLock(req.Context(), "mu.write", r.mu.Lock))
+	lockStart = time.Now()
+	for _, entry := range entries {
+		makeRoom(r.data, entry.Identifier, r.mapCap)
+		r.data[entry.Identifier] = entry
+	}
+	lockHeld = time.Since(lockStart)
+	r.mu.Unlock()
+	r.lockHold.record(lockHeld)
+
+	response := newResponse(req, "good_no_defer_batch", start)
+	response.BatchSize = len(items)
+	response.Counters = counters
+
+	writeEncoded(w, http.StatusOK, response, r.enc)
+}
+
+/*
+seed ajoute n entrées synthétiques (seed_0 à seed_n-1) en une seule section
+critique, pour mesurer l'effet de la taille de la map sur la copie et le GC.
+
+@params:
+  - n: int nombre d'entrées à insérer
+*/
+func (r *GoodRepository) seed(n int) {
+	r.lockWait.record(tracedLock(context.Background(), "mu.write", r.mu.Lock))
+	for i := 0; i < n; i++ {
+		entry := seedEntry(i, r.payload)
+		makeRoom(r.data, entry.Identifier, r.mapCap)
+		r.data[entry.Identifier] = entry
+	}
+	r.mu.Unlock()
+}
+
+/*
+evictExpired supprime les entrées dont LastModified précède cutoff.
+Appelée par le janitor, sous le mutex le temps du seul balayage.
+
+@params:
+  - cutoff: time.Time date limite de dernière modification
+
+@returns: int - Nombre d'entrées supprimées
+*/
+func (r *GoodRepository) evictExpired(cutoff time.Time) int {
+	r.lockWait.record(tracedLock(context.Background(), "mu.write", r.mu.Lock))
+	evicted := 0
+	for key, entry := range r.data {
+		if entry.LastModified.Before(cutoff) {
+			delete(r.data, key)
+			evicted++
+		}
+	}
+	r.mu.Unlock()
+	return evicted
+}
+
+/*
+GetDataHandler retourne l'entrée {id} en JSON, ou 404 si elle est absente.
+Le mutex n'est tenu que le temps de copier l'entrée, l'encodage se fait après.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+
+@returns: JSON DataStruct
+*/
+func (r *GoodRepository) GetDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.lockWait.record(tracedLock(req.Context(), "mu.read", r.mu.Lock))
+	var entry DataStruct
+	v, found := r.data[id]
+	if found {
+		entry = *v
+	}
+	r.mu.Unlock()
+
+	writeData(w, id, entry, found)
+}
+
+/*
+DeleteDataHandler supprime l'entrée {id} : 204 si supprimée, 404 si absente.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+*/
+func (r *GoodRepository) DeleteDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.lockWait.record(tracedLock(req.Context(), "mu.write", r.mu.Lock))
+	_, found := r.data[id]
+	delete(r.data, id)
+	r.mu.Unlock()
+
+	writeDeleted(w, id, found)
+}
+
+/*
+StatsHandler retourne les statistiques actuelles du serveur.
+Utilise correctement le mutex uniquement pour la lecture des données.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON contenant total_requests, data_size, avg_lock_hold_us,
+max_lock_hold_us, goroutines et heap_alloc_bytes
+*/
+func (r *GoodRepository) StatsHandler(w http.ResponseWriter, req *http.Request) {
+	avgHold, maxHold := r.lockHold.snapshot()
+
+	r.lockWait.record(tracedLock(req.Context(), "mu.read", r.mu.Lock))
+	stats := map[string]interface{}{
+		"total_requests":   r.counter,
+		"data_size":        len(r.data),
+		"avg_lock_hold_us": avgHold,
+		"max_lock_hold_us": maxHold,
+	}
+	r.mu.Unlock()
+	addRuntimeGauges(stats)
+
+	writeStats(w, stats)
+}
+
+/*
+LockWaitHandler expose l'histogramme des temps d'attente de chaque acquisition du mutex.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON LockWaitSummary (count, avg_ms, max_ms, buckets, overflow)
+*/
+func (r *GoodRepository) LockWaitHandler(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, r.lockWait.snapshot())
+}
+
+/*
+ResetHandler remet à zéro les mesures de verrouillage (attente et détention),
+pour démarrer une expérience sans redémarrer le serveur. Les données et le
+compteur de requêtes sont conservés.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON {"status": "reset"}
+*/
+func (r *GoodRepository) ResetHandler(w http.ResponseWriter, req *http.Request) {
+	r.lockWait.reset()
+	r.lockHold.reset()
+	writeJSON(w, http.StatusOK, map[string]string{"status": "reset"})
+}
+
+/*
+Router configure les routes du serveur avec gorilla/mux.
+
+@returns: *mux.Router - Routeur prêt à être servi
+
+@endpoints:
+  - GET /process : Handler avec bonne utilisation du mutex
+  - POST /process/batch : Lot traité hors mutex, écriture groupée
+  - GET /process/stream : Progression NDJSON, mutex libéré avant le flux
+  - GET /process/upstream : Appel à -upstream, mutex libéré avant l'appel
+  - GET /data/{id} : Lecture d'une entrée (404 si absente)
+  - DELETE /data/{id} : Suppression d'une entrée
+  - GET /stats : Statistiques du serveur
+  - GET /debug/allocs : Compteurs d'allocation du processus
+  - GET /debug/contention : Résumé du profil de contention des mutex
+  - GET /debug/lockwait : Histogramme des attentes du mutex
+  - POST /reset : Remise à zéro des mesures de verrouillage
+  - POST /seed?n=N : Pré-remplissage avec N entrées synthétiques
+*/
+func (r *GoodRepository) Router() *mux.Router {
+	router := newRouter()
+	router.HandleFunc("/process", r.GoodHandler).Methods("GET")
+	router.HandleFunc("/process/batch", r.BatchHandler).Methods("POST")
+	router.HandleFunc("/process/stream", r.StreamHandler).Methods("GET")
+	router.HandleFunc("/process/upstream", r.UpstreamHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.GetDataHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.DeleteDataHandler).Methods("DELETE")
+	router.HandleFunc("/stats", r.StatsHandler).Methods("GET")
+	router.HandleFunc("/debug/allocs", AllocsHandler).Methods("GET")
+	router.HandleFunc("/debug/contention", ContentionHandler).Methods("GET")
+	router.HandleFunc("/debug/lockwait", r.LockWaitHandler).Methods("GET")
+	router.HandleFunc("/reset", r.ResetHandler).Methods("POST")
+	router.HandleFunc("/seed", seedHandler(r.seed)).Methods("POST")
+	return router
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minJanitorInterval évite un balayage en boucle serrée pour de très petits TTL
+const minJanitorInterval = 10 * time.Millisecond
+
+/*
+startJanitor lance une goroutine qui évince périodiquement les entrées plus
+anciennes que ttl. Le balayage a lieu toutes les ttl/2, si bien qu'une entrée
+ne survit jamais plus de 1,5 × ttl.
+
+@params:
+  - ttl: time.Duration âge maximum d'une entrée (0 ou négatif désactive le janitor)
+  - evict: func(cutoff time.Time) int supprime les entrées modifiées avant cutoff
+    en respectant la synchronisation du repository, et retourne leur nombre
+
+@returns: func() - Arrête le janitor et attend la fin du balayage en cours (idempotent)
+*/
+func startJanitor(ttl time.Duration, evict func(cutoff time.Time) int) func() {
+	if ttl <= 0 {
+		return func() {}
+	}
+	interval := ttl / 2
+	if interval < minJanitorInterval {
+		interval = minJanitorInterval
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				evict(now.Add(-ttl))
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+/*
+managedHandler associe un handler HTTP aux goroutines de fond de son
+repository, arrêtées par Close.
+*/
+type managedHandler struct {
+	http.Handler
+	stop func()
+}
+
+/*
+Close arrête les goroutines de fond du repository.
+
+@returns: error - Toujours nil
+*/
+func (h managedHandler) Close() error {
+	h.stop()
+	return nil
+}
+
+/*
+withJanitor démarre le janitor d'un repository et l'attache à son handler.
+
+@params:
+  - handler: http.Handler routeur du repository
+  - ttl: time.Duration âge maximum d'une entrée (0 désactive)
+  - evict: func(cutoff time.Time) int méthode d'éviction du repository
+
+@returns: http.Handler - Handler implémentant io.Closer pour arrêter le janitor
+*/
+func withJanitor(handler http.Handler, ttl time.Duration, evict func(cutoff time.Time) int) http.Handler {
+	return managedHandler{Handler: handler, stop: startJanitor(ttl, evict)}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+TestJanitorEvictsExpiredEntries écrit des entrées pendant que le janitor
+tourne, puis vérifie qu'elles sont toutes évincées une fois le TTL écoulé.
+À exécuter avec -race : lectures, écritures et suppressions sont concurrentes.
+*/
+func TestJanitorEvictsExpiredEntries(t *testing.T) {
+	const ttl = 30 * time.Millisecond
+
+	good := NewGoodRepository(true)
+	syncmap := NewSyncMapRepository(true)
+	repos := []struct {
+		name    string
+		handler http.Handler
+		evict   func(cutoff time.Time) int
+	}{
+		{"good", good.Router(), good.evictExpired},
+		{"syncmap", syncmap.Router(), syncmap.evictExpired},
+	}
+
+	for _, repo := range repos {
+		stop := startJanitor(ttl, repo.evict)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				repo.handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/process", nil))
+			}()
+		}
+		wg.Wait()
+
+		deadline := time.Now().Add(time.Second)
+		size := dataSize(t, repo.handler)
+		for size != 0 && time.Now().Before(deadline) {
+			time.Sleep(ttl / 2)
+			size = dataSize(t, repo.handler)
+		}
+		stop()
+		stop() // idempotent
+
+		if size != 0 {
+			t.Errorf("%s: data_size = %d after TTL, want 0", repo.name, size)
+		}
+	}
+}
+
+/*
+TestJanitorDisabled vérifie qu'un TTL nul ne démarre aucun janitor.
+*/
+func TestJanitorDisabled(t *testing.T) {
+	called := false
+	stop := startJanitor(0, func(time.Time) int {
+		called = true
+		return 0
+	})
+	time.Sleep(2 * minJanitorInterval)
+	stop()
+	if called {
+		t.Error("evict called with ttl = 0")
+	}
+}
+
+// dataSize lit data_size sur /stats
+func dataSize(t *testing.T, handler http.Handler) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	var stats struct {
+		DataSize int `json:"data_size"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	return stats.DataSize
+}
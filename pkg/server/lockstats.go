@@ -0,0 +1,171 @@
+package server
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+/*
+lockHoldStats agrège sans verrou les durées de détention d'un mutex.
+Les compteurs sont atomiques : les enregistrer ne prolonge pas la section
+critique mesurée.
+
+@fields:
+  - totalNs: Somme des durées de détention en nanosecondes
+  - count: Nombre de sections critiques enregistrées
+  - maxNs: Plus longue détention observée en nanosecondes
+*/
+type lockHoldStats struct {
+	totalNs atomic.Int64
+	count   atomic.Int64
+	maxNs   atomic.Int64
+}
+
+/*
+record ajoute une durée de détention à l'agrégat.
+
+@params:
+  - held: time.Duration durée pendant laquelle le mutex est resté verrouillé
+*/
+func (s *lockHoldStats) record(held time.Duration) {
+	ns := held.Nanoseconds()
+	s.totalNs.Add(ns)
+	s.count.Add(1)
+	for {
+		current := s.maxNs.Load()
+		if ns <= current || s.maxNs.CompareAndSwap(current, ns) {
+			return
+		}
+	}
+}
+
+/*
+snapshot retourne la détention moyenne et maximale en microsecondes.
+
+@returns: (float64, float64) - Moyenne et maximum, 0 si rien n'a été enregistré
+*/
+func (s *lockHoldStats) snapshot() (avgUs, maxUs float64) {
+	count := s.count.Load()
+	if count == 0 {
+		return 0, 0
+	}
+	avgUs = float64(s.totalNs.Load()) / float64(count) / 1e3
+	maxUs = float64(s.maxNs.Load()) / 1e3
+	return avgUs, maxUs
+}
+
+/*
+reset remet l'agrégat à zéro. Les trois compteurs sont remis à zéro un par
+un : une détention enregistrée pendant le reset peut être comptée à moitié.
+*/
+func (s *lockHoldStats) reset() {
+	s.totalNs.Store(0)
+	s.count.Store(0)
+	s.maxNs.Store(0)
+}
+
+// lockWaitBounds sont les bornes supérieures des buckets de l'histogramme d'attente
+var lockWaitBounds = [...]time.Duration{
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+/*
+lockWaitHistogram répartit sans verrou les temps d'attente d'acquisition
+d'un mutex dans des buckets fixes (lockWaitBounds).
+
+@fields:
+  - buckets: Compteur par bucket, le dernier reçoit les attentes au-delà de la dernière borne
+  - stats: Moyenne et maximum des attentes
+*/
+type lockWaitHistogram struct {
+	buckets [len(lockWaitBounds) + 1]atomic.Int64
+	stats   lockHoldStats
+}
+
+/*
+record range une attente dans son bucket.
+
+@params:
+  - wait: time.Duration temps écoulé entre la demande du verrou et son acquisition
+*/
+func (h *lockWaitHistogram) record(wait time.Duration) {
+	i := 0
+	for i < len(lockWaitBounds) && wait > lockWaitBounds[i] {
+		i++
+	}
+	h.buckets[i].Add(1)
+	h.stats.record(wait)
+}
+
+/*
+LockWaitBucket est un bucket de l'histogramme des attentes.
+
+@fields:
+  - LeMs: Borne supérieure du bucket en millisecondes (incluse)
+  - Count: Nombre d'attentes dans le bucket
+*/
+type LockWaitBucket struct {
+	LeMs  float64 `json:"le_ms"`
+	Count int64   `json:"count"`
+}
+
+/*
+LockWaitSummary est l'histogramme des attentes renvoyé par /debug/lockwait.
+
+@fields:
+  - Count: Nombre d'acquisitions enregistrées
+  - AvgMs: Attente moyenne en millisecondes
+  - MaxMs: Plus longue attente en millisecondes
+  - Buckets: Buckets non cumulés, par borne croissante
+  - Overflow: Attentes au-delà de la dernière borne
+*/
+type LockWaitSummary struct {
+	Count    int64            `json:"count"`
+	AvgMs    float64          `json:"avg_ms"`
+	MaxMs    float64          `json:"max_ms"`
+	Buckets  []LockWaitBucket `json:"buckets"`
+	Overflow int64            `json:"overflow"`
+}
+
+/*
+snapshot retourne l'état courant de l'histogramme.
+
+@returns: LockWaitSummary - Buckets et statistiques à l'instant de l'appel
+*/
+func (h *lockWaitHistogram) snapshot() LockWaitSummary {
+	avgUs, maxUs := h.stats.snapshot()
+	summary := LockWaitSummary{
+		Count:    h.stats.count.Load(),
+		AvgMs:    avgUs / 1e3,
+		MaxMs:    maxUs / 1e3,
+		Buckets:  make([]LockWaitBucket, len(lockWaitBounds)),
+		Overflow: h.buckets[len(lockWaitBounds)].Load(),
+	}
+	for i, bound := range lockWaitBounds {
+		summary.Buckets[i] = LockWaitBucket{
+			LeMs:  float64(bound) / float64(time.Millisecond),
+			Count: h.buckets[i].Load(),
+		}
+	}
+	return summary
+}
+
+/*
+reset remet l'histogramme à zéro.
+*/
+func (h *lockWaitHistogram) reset() {
+	for i := range h.buckets {
+		h.buckets[i].Store(0)
+	}
+	h.stats.reset()
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+/*
+TestLockHoldStats vérifie que /stats expose la détention du mutex : le serveur
+bad la garde pendant tout le traitement lourd (≥ 10ms), les serveurs good et
+scoped ne la gardent que le temps des sections critiques.
+*/
+func TestLockHoldStats(t *testing.T) {
+	servers := []struct {
+		name    string
+		handler http.Handler
+	}{
+		{"bad", NewBadRepository(true).Router()},
+		{"good", NewGoodRepository(true).Router()},
+		{"scoped", NewScopedRepository(true).Router()},
+	}
+
+	holds := make(map[string]float64)
+	for _, srv := range servers {
+		for i := 0; i < 3; i++ {
+			srv.handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/process", nil))
+		}
+
+		rec := httptest.NewRecorder()
+		srv.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+		var stats struct {
+			AvgLockHoldUs float64 `json:"avg_lock_hold_us"`
+			MaxLockHoldUs float64 `json:"max_lock_hold_us"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+			t.Fatalf("%s: invalid JSON: %v", srv.name, err)
+		}
+		if stats.AvgLockHoldUs <= 0 || stats.MaxLockHoldUs < stats.AvgLockHoldUs {
+			t.Errorf("%s: avg=%.1fµs max=%.1fµs, want 0 < avg <= max", srv.name, stats.AvgLockHoldUs, stats.MaxLockHoldUs)
+		}
+		holds[srv.name] = stats.AvgLockHoldUs
+	}
+
+	if holds["bad"] < 10000 {
+		t.Errorf("bad avg lock hold = %.1fµs, want >= 10ms", holds["bad"])
+	}
+	for _, name := range []string{"good", "scoped"} {
+		if holds[name] >= holds["bad"] {
+			t.Errorf("%s avg lock hold %.1fµs should be far below bad %.1fµs", name, holds[name], holds["bad"])
+		}
+	}
+}
+
+/*
+TestLockWaitHistogram vérifie /debug/lockwait et /reset : sous une rafale de
+requêtes concurrentes, les attentes du serveur bad s'étalent sur plusieurs
+tranches de 10ms alors que celles du serveur good restent courtes.
+*/
+func TestLockWaitHistogram(t *testing.T) {
+	const requests = 5
+
+	servers := []struct {
+		name    string
+		handler http.Handler
+	}{
+		{"bad", NewBadRepository(true).Router()},
+		{"good", NewGoodRepository(true).Router()},
+	}
+
+	fetch := func(t *testing.T, handler http.Handler) LockWaitSummary {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/lockwait", nil))
+		var summary LockWaitSummary
+		if err := json.NewDecoder(rec.Body).Decode(&summary); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		return summary
+	}
+
+	maxWait := make(map[string]float64)
+	for _, srv := range servers {
+		var wg sync.WaitGroup
+		for i := 0; i < requests; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				srv.handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/process", nil))
+			}()
+		}
+		wg.Wait()
+
+		summary := fetch(t, srv.handler)
+		var bucketed int64
+		for _, bucket := range summary.Buckets {
+			bucketed += bucket.Count
+		}
+		if summary.Count == 0 || bucketed+summary.Overflow != summary.Count {
+			t.Errorf("%s: count=%d, buckets+overflow=%d, want equal and > 0", srv.name, summary.Count, bucketed+summary.Overflow)
+		}
+		maxWait[srv.name] = summary.MaxMs
+
+		rec := httptest.NewRecorder()
+		srv.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reset", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: POST /reset status %d", srv.name, rec.Code)
+		}
+		if after := fetch(t, srv.handler); after.Count != 0 || after.MaxMs != 0 {
+			t.Errorf("%s: after reset count=%d max=%.3fms, want 0", srv.name, after.Count, after.MaxMs)
+		}
+	}
+
+	// Le dernier arrivé sur le serveur bad attend les traitements des autres
+	if maxWait["bad"] < 20 {
+		t.Errorf("bad max lock wait = %.3fms, want >= 20ms", maxWait["bad"])
+	}
+	if maxWait["good"] >= maxWait["bad"] {
+		t.Errorf("good max lock wait %.3fms should be far below bad %.3fms", maxWait["good"], maxWait["bad"])
+	}
+}
+
+/*
+TestLockWaitCoversEveryRoute vérifie que les serveurs bad et good comptent
+dans /debug/lockwait chaque acquisition du mutex, pas seulement celles de
+/process : chaque route qui verrouille fait croître le compteur.
+*/
+func TestLockWaitCoversEveryRoute(t *testing.T) {
+	routes := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodGet, "/process", ""},
+		{http.MethodGet, "/process/stream", ""},
+		{http.MethodGet, "/process/upstream", ""},
+		{http.MethodPost, "/process/batch", `[{"name":"a"},{"name":"b"}]`},
+		{http.MethodGet, "/data/request_1", ""},
+		{http.MethodDelete, "/data/request_1", ""},
+		{http.MethodGet, "/stats", ""},
+	}
+
+	for _, strategy := range []LockStrategy{DeferMutex, Mutex} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			upstreamURL, _ := newStubUpstream(t)
+			repo := NewRepository(WithLockStrategy(strategy), WithUpstream(upstreamURL), WithWorkFunc(fanOutWork(0, 10, 1)))
+			defer repo.Close()
+
+			count := func() int64 {
+				rec := httptest.NewRecorder()
+				repo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/lockwait", nil))
+				var summary LockWaitSummary
+				if err := json.NewDecoder(rec.Body).Decode(&summary); err != nil {
+					t.Fatalf("invalid JSON: %v", err)
+				}
+				return summary.Count
+			}
+
+			for _, route := range routes {
+				before := count()
+				rec := httptest.NewRecorder()
+				repo.ServeHTTP(rec, httptest.NewRequest(route.method, route.path, strings.NewReader(route.body)))
+				if rec.Code >= http.StatusInternalServerError {
+					t.Fatalf("%s %s status = %d: %s", route.method, route.path, rec.Code, rec.Body)
+				}
+				if after := count(); after <= before {
+					t.Errorf("%s %s: lock wait count %d -> %d, want an increase", route.method, route.path, before, after)
+				}
+			}
+		})
+	}
+}
+
+/*
+TestBatchLockHold vérifie que /process/batch alimente la détention publiée
+par /stats : le serveur bad garde le mutex pendant les traitements de tout
+le lot (≥ 2 × 10ms), le good seulement pendant ses deux sections critiques.
+*/
+func TestBatchLockHold(t *testing.T) {
+	servers := []struct {
+		name    string
+		handler http.Handler
+	}{
+		{"bad", NewBadRepository(true).Router()},
+		{"good", NewGoodRepository(true).Router()},
+	}
+
+	holds := make(map[string]float64)
+	for _, srv := range servers {
+		rec := httptest.NewRecorder()
+		srv.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/process/batch", strings.NewReader(`[{"name":"a"},{"name":"b"}]`)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: POST /process/batch status %d: %s", srv.name, rec.Code, rec.Body)
+		}
+
+		rec = httptest.NewRecorder()
+		srv.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+		var stats struct {
+			AvgLockHoldUs float64 `json:"avg_lock_hold_us"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+			t.Fatalf("%s: invalid JSON: %v", srv.name, err)
+		}
+		if stats.AvgLockHoldUs <= 0 {
+			t.Errorf("%s: avg lock hold = %.1fµs after a batch, want > 0", srv.name, stats.AvgLockHoldUs)
+		}
+		holds[srv.name] = stats.AvgLockHoldUs
+	}
+
+	if holds["bad"] < 20000 {
+		t.Errorf("bad avg lock hold = %.1fµs, want >= 20ms for a batch of two", holds["bad"])
+	}
+	if holds["good"] >= holds["bad"] {
+		t.Errorf("good avg lock hold %.1fµs should be far below bad %.1fµs", holds["good"], holds["bad"])
+	}
+}
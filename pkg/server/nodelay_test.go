@@ -0,0 +1,82 @@
+//go:build unix
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"syscall"
+	"testing"
+)
+
+/*
+tcpNoDelay lit l'option TCP_NODELAY d'une connexion TCP.
+
+@params:
+  - t: *testing.T instance du test
+  - c: *net.TCPConn connexion à inspecter
+
+@returns: bool - true si Nagle est désactivé
+*/
+func tcpNoDelay(t *testing.T, c *net.TCPConn) bool {
+	t.Helper()
+	raw, err := c.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var value int
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		value, sockErr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_NODELAY)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if sockErr != nil {
+		t.Fatal(sockErr)
+	}
+	return value != 0
+}
+
+/*
+TestNoDelayConn vérifie que le ConnContext de NewServer règle TCP_NODELAY
+dans les deux sens sur une connexion acceptée, en clair comme sous TLS, où
+il atteint la connexion TCP sous la connexion chiffrée.
+*/
+func TestNoDelayConn(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	accept := func() *net.TCPConn {
+		t.Helper()
+		client, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { client.Close() })
+		conn, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn.(*net.TCPConn)
+	}
+
+	for _, noDelay := range []bool{false, true, false} {
+		conn := accept()
+		srv := Variants[0].NewServer(ln.Addr().String(), Options{NoDelay: noDelay})
+		srv.ConnContext(context.Background(), conn)
+		if got := tcpNoDelay(t, conn); got != noDelay {
+			t.Errorf("NoDelay %v: TCP_NODELAY = %v", noDelay, got)
+		}
+
+		tlsConn := accept()
+		srv.ConnContext(context.Background(), tls.Server(tlsConn, &tls.Config{}))
+		if got := tcpNoDelay(t, tlsConn); got != noDelay {
+			t.Errorf("NoDelay %v over TLS: TCP_NODELAY = %v", noDelay, got)
+		}
+	}
+}
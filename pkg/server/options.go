@@ -0,0 +1,566 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+/*
+LockStrategy choisit la synchronisation du repository construit par
+NewRepository. Toutes les stratégies libèrent le verrou avant le traitement
+lourd, comme le serveur good, sauf DeferMutex qui reproduit le serveur bad.
+*/
+type LockStrategy int
+
+const (
+	// Mutex protège la map par un sync.Mutex (GoodRepository)
+	Mutex LockStrategy = iota
+	// RWMutex lit sous RLock et écrit sous Lock (RWMutexRepository)
+	RWMutex
+	// SyncMap stocke les entrées dans un sync.Map (SyncMapRepository)
+	SyncMap
+	// DeferMutex garde le sync.Mutex par defer pendant tout le traitement (BadRepository)
+	DeferMutex
+	// AtomicValue publie une map immuable par copy-on-write (AtomicValueRepository)
+	AtomicValue
+)
+
+/*
+String retourne le nom de la stratégie, identique au nom de la variante.
+
+@returns: string "good", "rwmutex", "syncmap", "bad" ou "atomicvalue"
+*/
+func (s LockStrategy) String() string {
+	switch s {
+	case RWMutex:
+		return "rwmutex"
+	case SyncMap:
+		return "syncmap"
+	case DeferMutex:
+		return "bad"
+	case AtomicValue:
+		return "atomicvalue"
+	default:
+		return "good"
+	}
+}
+
+/*
+WorkFunc est le traitement lourd de /process, appelé avec le contexte de la
+requête. Sa valeur de retour est renvoyée comme résultat de la requête.
+Séparer le traitement du verrouillage permet d'injecter un travail rapide et
+déterministe dans les tests, et de comparer bad et good à travail identique.
+
+@note: /process/stream appelle le traitement une fois par étape, avec un
+contexte portant l'étape (withWorkStep). Les traitements construits ici n'en
+exécutent que la tranche ; un WorkFunc qui l'ignore s'exécute en entier à
+chaque étape.
+*/
+type WorkFunc func(ctx context.Context) int
+
+// workStepKey est la clé de contexte de l'étape d'un traitement découpé
+type workStepKey struct{}
+
+/*
+workStep désigne l'étape d'un traitement découpé en tranches égales.
+
+@fields:
+  - step: Numéro de l'étape (1 à steps)
+  - steps: Nombre total d'étapes
+*/
+type workStep struct {
+	step  int
+	steps int
+}
+
+/*
+withWorkStep demande au traitement de n'exécuter que l'étape step sur steps :
+sa part de l'attente et sa tranche de la boucle. La somme des résultats des
+étapes est le résultat du traitement entier.
+
+@params:
+  - ctx: context.Context contexte de la requête
+  - step: int numéro de l'étape (1 à steps)
+  - steps: int nombre total d'étapes
+
+@returns: context.Context - Contexte portant l'étape
+*/
+func withWorkStep(ctx context.Context, step, steps int) context.Context {
+	return context.WithValue(ctx, workStepKey{}, workStep{step: step, steps: steps})
+}
+
+/*
+workSlice retourne la part du traitement à exécuter pour ctx : tout le
+traitement, ou la tranche de l'étape posée par withWorkStep.
+
+@params:
+  - ctx: context.Context contexte de la requête
+  - sleep: time.Duration attente du traitement entier
+  - iters: int itérations du traitement entier
+
+@returns: (time.Duration, int, int) - Attente, première itération et
+itération de fin (exclue) de la tranche
+*/
+func workSlice(ctx context.Context, sleep time.Duration, iters int) (time.Duration, int, int) {
+	s, ok := ctx.Value(workStepKey{}).(workStep)
+	if !ok || s.steps <= 1 {
+		return sleep, 0, iters
+	}
+	return sleep / time.Duration(s.steps), iters * (s.step - 1) / s.steps, iters * s.step / s.steps
+}
+
+/*
+sleepWork construit le traitement lourd historique : une attente (appel
+externe simulé) suivie d'une boucle de calcul.
+
+@params:
+  - sleep: time.Duration durée de l'attente
+  - iters: int nombre d'itérations de la boucle de calcul
+
+@returns: WorkFunc - Traitement renvoyant la somme des itérations
+*/
+func sleepWork(sleep time.Duration, iters int) WorkFunc {
+	return func(ctx context.Context) int {
+		sleep, from, to := workSlice(ctx, sleep, iters)
+		if sleep > 0 {
+			time.Sleep(sleep) // Simule un traitement
+		}
+		return spinRange(from, to) // Calcul intensif simulé
+	}
+}
+
+/*
+spin est la boucle de calcul du traitement lourd.
+
+@params:
+  - iters: int nombre d'itérations
+
+@returns: int - Somme des itérations, renvoyée pour que la boucle ne soit pas éliminée
+*/
+func spin(iters int) int {
+	return spinRange(0, iters)
+}
+
+// defaultWorkSleep et defaultWorkIters sont l'attente et la boucle du traitement lourd historique
+const (
+	defaultWorkSleep = 10 * time.Millisecond
+	defaultWorkIters = 1000000
+)
+
+// defaultWork est le traitement lourd historique des serveurs : 10ms puis 1M itérations
+var defaultWork = sleepWork(defaultWorkSleep, defaultWorkIters)
+
+/*
+fanOutWork construit le traitement lourd du mode -fanout : l'attente, puis la
+boucle de calcul découpée en parts tranches consécutives, calculées chacune
+par sa goroutine et attendues par un sync.WaitGroup. Le résultat est celui
+de sleepWork(sleep, iters).
+
+@params:
+  - sleep: time.Duration durée de l'attente
+  - iters: int nombre total d'itérations de la boucle de calcul
+  - parts: int nombre de goroutines (1 = boucle dans la goroutine de la requête)
+
+@returns: WorkFunc - Traitement parallélisé à l'intérieur de la requête
+
+@note: les goroutines ne prennent aucun verrou, mais le handler les attend :
+sur le serveur bad, elles tournent donc sous le mutex de la requête, et les
+tranches d'une requête ne recouvrent jamais celles d'une autre. Sur le
+serveur good, le verrou est déjà libéré et les tranches de toutes les
+requêtes en vol se partagent les cœurs.
+*/
+func fanOutWork(sleep time.Duration, iters, parts int) WorkFunc {
+	if parts <= 1 {
+		return sleepWork(sleep, iters)
+	}
+	return func(ctx context.Context) int {
+		sleep, from, to := workSlice(ctx, sleep, iters)
+		if sleep > 0 {
+			time.Sleep(sleep) // Simule un traitement
+		}
+		n := to - from
+		sums := make([]int, parts)
+		var wg sync.WaitGroup
+		for p := 0; p < parts; p++ {
+			wg.Add(1)
+			go func(p int) {
+				defer wg.Done()
+				sums[p] = spinRange(from+n*p/parts, from+n*(p+1)/parts)
+			}(p)
+		}
+		wg.Wait()
+		result := 0
+		for _, sum := range sums {
+			result += sum
+		}
+		return result
+	}
+}
+
+/*
+spinRange est la tranche [from, to) de la boucle de calcul de spin.
+
+@params:
+  - from: int première itération
+  - to: int itération de fin, exclue
+
+@returns: int - Somme des itérations de la tranche
+*/
+func spinRange(from, to int) int {
+	result := 0
+	for i := from; i < to; i++ {
+		result += i
+	}
+	return result
+}
+
+// cpuWorkTarget est la durée visée du traitement -cpuOnly, celle de l'attente qu'il remplace
+const cpuWorkTarget = 10 * time.Millisecond
+
+// calibrationMin est la durée minimum d'une mesure de calibrateWork, pour dominer la résolution de l'horloge
+const calibrationMin = 20 * time.Millisecond
+
+// calibrationRuns est le nombre de mesures dont calibrateWork retient la plus rapide
+const calibrationRuns = 3
+
+// spinSink reçoit le résultat des boucles de calibration
+var spinSink int
+
+/*
+calibrateWork estime le nombre d'itérations de spin qui prend target sur
+cette machine. Le nombre d'itérations double jusqu'à ce qu'une mesure dure
+au moins calibrationMin, puis il est ramené à target par règle de trois.
+Chaque taille est mesurée calibrationRuns fois et la plus rapide est
+retenue : une préemption pendant la mesure ne peut que la rallonger.
+
+@params:
+  - target: time.Duration durée visée d'un traitement
+
+@returns: int - Itérations correspondant à target (au moins 1, 0 si target <= 0)
+*/
+func calibrateWork(target time.Duration) int {
+	if target <= 0 {
+		return 0
+	}
+	for iters := 1 << 16; ; iters *= 2 {
+		best := time.Duration(1<<63 - 1)
+		for run := 0; run < calibrationRuns; run++ {
+			start := time.Now()
+			spinSink += spin(iters)
+			best = min(best, time.Since(start))
+		}
+		if best >= calibrationMin {
+			return max(1, int(float64(iters)*float64(target)/float64(best)))
+		}
+	}
+}
+
+// cpuWorkIters calibre une seule fois, au démarrage, la boucle du traitement -cpuOnly partagé par les serveurs :
+// la section critique dure un temps de calcul fixe au lieu d'un time.Sleep soumis au réveil du scheduler
+var cpuWorkIters = sync.OnceValue(func() int {
+	iters := calibrateWork(cpuWorkTarget)
+	fmt.Printf("Traitement CPU calibré : %d itérations ≈ %s\n", iters, cpuWorkTarget)
+	return iters
+})
+
+/*
+pinnedWork exécute work sur la goroutine verrouillée à son thread système
+(runtime.LockOSThread), pour le mode expérimental -pinWork : la goroutine ne
+change plus de thread entre deux points de préemption, ce qui retire une
+source de bruit de la mesure de détention d'un verrou tenu pendant le calcul.
+
+@params:
+  - work: WorkFunc traitement à épingler
+
+@returns: WorkFunc - Traitement épinglé ; UnlockOSThread est appelé par defer,
+même si work panique, pour ne pas laisser le thread réservé à une goroutine
+du serveur HTTP
+
+@note: le thread reste réservé pendant tout le traitement, y compris pendant
+l'attente de 10ms : le runtime doit démarrer d'autres threads pour les autres
+goroutines, et le parallélisme global baisse. Le système peut toujours
+déplacer le thread d'un cœur à l'autre (pas d'affinité CPU).
+*/
+func pinnedWork(work WorkFunc) WorkFunc {
+	return func(ctx context.Context) int {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		return work(ctx)
+	}
+}
+
+/*
+repoOptions regroupe les paramètres appliqués par les Option.
+
+@fields:
+  - copyData: Copie les données partagées avant le traitement
+  - mapCap: Nombre maximum d'entrées de la map (0 = illimité)
+  - ttl: Âge maximum des entrées avant éviction par le janitor (0 désactive)
+  - work: Traitement lourd de /process
+  - strategy: Synchronisation du repository
+  - payload: Taille en octets du blob Payload des entrées écrites (0 = aucun)
+  - upstream: URL appelée par /process/upstream ("" = non configuré)
+  - seed: Entrées synthétiques insérées à la construction (0 = map vide)
+  - encoder: Encodeur JSON des réponses de /process
+*/
+type repoOptions struct {
+	copyData bool
+	mapCap   int
+	ttl      time.Duration
+	work     WorkFunc
+	strategy LockStrategy
+	payload  int
+	upstream string
+	seed     int
+	encoder  Encoder
+}
+
+/*
+Option modifie la configuration d'un repository construit par NewRepository.
+*/
+type Option func(*repoOptions)
+
+/*
+newRepoOptions applique les options aux valeurs par défaut, identiques au
+comportement historique des serveurs : copie activée, map illimitée, pas de
+TTL, traitement de 10ms + 1M itérations, sync.Mutex, encoding/json.
+
+@params:
+  - opts: ...Option options à appliquer, dans l'ordre
+
+@returns: repoOptions - Configuration résultante
+*/
+func newRepoOptions(opts ...Option) repoOptions {
+	o := repoOptions{copyData: true, work: defaultWork, strategy: Mutex, encoder: stdlibEncoder{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+/*
+WithCopyData active ou non la copie des données avant le traitement.
+
+@params:
+  - enabled: bool copie activée (défaut : true)
+*/
+func WithCopyData(enabled bool) Option {
+	return func(o *repoOptions) { o.copyData = enabled }
+}
+
+/*
+WithMapCap borne le nombre d'entrées de la map : au-delà, l'écriture d'une
+nouvelle clé évince une entrée arbitraire. Garde la copie et le GC à coût
+constant pendant les tests longs.
+
+@params:
+  - n: int nombre maximum d'entrées (0 ou négatif = illimité)
+*/
+func WithMapCap(n int) Option {
+	return func(o *repoOptions) { o.mapCap = n }
+}
+
+/*
+WithTTL démarre un janitor qui évince les entrées non modifiées depuis d.
+Il s'arrête avec Repository.Close.
+
+@params:
+  - d: time.Duration âge maximum d'une entrée (0 désactive)
+*/
+func WithTTL(d time.Duration) Option {
+	return func(o *repoOptions) { o.ttl = d }
+}
+
+/*
+WithWork règle le traitement lourd de /process.
+
+@params:
+  - sleep: time.Duration attente simulant un appel externe (défaut : 10ms)
+  - iters: int itérations de la boucle de calcul (défaut : 1 000 000)
+*/
+func WithWork(sleep time.Duration, iters int) Option {
+	return func(o *repoOptions) { o.work = sleepWork(sleep, iters) }
+}
+
+/*
+WithWorkFunc remplace le traitement lourd de /process et des lots par f,
+par exemple un travail instantané dans un test, ou le même travail injecté
+dans DeferMutex et Mutex pour une comparaison à charge égale.
+
+@params:
+  - f: WorkFunc traitement à exécuter (nil garde le traitement par défaut)
+*/
+func WithWorkFunc(f WorkFunc) Option {
+	return func(o *repoOptions) {
+		if f != nil {
+			o.work = f
+		}
+	}
+}
+
+/*
+WithSeed pré-remplit le repository avec n entrées synthétiques (seed_0 à
+seed_n-1) à sa construction, comme un POST /seed?n=N avant la première
+requête. Avec WithPayload, la map copiée par /process a dès le départ une
+taille connue.
+
+@params:
+  - n: int nombre d'entrées (0 ou négatif = map vide)
+*/
+func WithSeed(n int) Option {
+	return func(o *repoOptions) { o.seed = n }
+}
+
+/*
+WithPayload ajoute à chaque entrée écrite un blob de size octets, copié avec
+l'entrée par la lecture sous verrou : la section critique du serveur good
+s'allonge alors avec la taille des valeurs, pas seulement avec leur nombre.
+
+@params:
+  - size: int taille du blob en octets (0 ou négatif = aucun blob)
+*/
+func WithPayload(size int) Option {
+	return func(o *repoOptions) { o.payload = size }
+}
+
+/*
+WithUpstream configure l'URL appelée par /process/upstream (serveurs bad et
+good) : le serveur bad l'appelle mutex verrouillé, le good après l'avoir libéré.
+
+@params:
+  - url: string URL de l'upstream ("" = /process/upstream répond 503)
+*/
+func WithUpstream(url string) Option {
+	return func(o *repoOptions) { o.upstream = url }
+}
+
+/*
+WithEncoder choisit l'encodeur JSON des réponses de /process (et des lots
+et de /process/upstream sur bad et good).
+
+@params:
+  - enc: Encoder encodeur à utiliser (nil garde encoding/json)
+*/
+func WithEncoder(enc Encoder) Option {
+	return func(o *repoOptions) {
+		if enc != nil {
+			o.encoder = enc
+		}
+	}
+}
+
+/*
+WithLockStrategy choisit la synchronisation du repository.
+
+@params:
+  - s: LockStrategy Mutex (défaut), RWMutex, SyncMap, DeferMutex ou AtomicValue
+*/
+func WithLockStrategy(s LockStrategy) Option {
+	return func(o *repoOptions) { o.strategy = s }
+}
+
+/*
+Repository est un repository construit par NewRepository : il sert les
+routes de la stratégie choisie, et Close arrête son janitor.
+
+@fields:
+  - Strategy: Synchronisation utilisée
+  - router: Routes du repository sous-jacent
+  - stop: Arrête le janitor (sans effet si aucun TTL)
+*/
+type Repository struct {
+	Strategy LockStrategy
+	router   *mux.Router
+	stop     func()
+}
+
+/*
+NewRepository construit un repository à partir d'options composables, pour
+varier une expérience sans passer par les constructeurs de chaque variante.
+
+	repo := NewRepository(WithLockStrategy(RWMutex), WithMapCap(10000), WithTTL(30*time.Second))
+	defer repo.Close()
+
+@params:
+  - opts: ...Option options à appliquer aux valeurs par défaut
+
+@returns: *Repository - Repository prêt à servir, à fermer avec Close
+*/
+func NewRepository(opts ...Option) *Repository {
+	o := newRepoOptions(opts...)
+
+	var router *mux.Router
+	var evict func(cutoff time.Time) int
+	var seed func(n int)
+	switch o.strategy {
+	case RWMutex:
+		repo := newRWMutexRepository(o)
+		router, evict, seed = repo.Router(), repo.evictExpired, repo.seed
+	case SyncMap:
+		repo := newSyncMapRepository(o)
+		router, evict, seed = repo.Router(), repo.evictExpired, repo.seed
+	case DeferMutex:
+		repo := newBadRepository(o)
+		router, evict, seed = repo.Router(), repo.evictExpired, repo.seed
+	case AtomicValue:
+		repo := newAtomicValueRepository(o)
+		router, evict, seed = repo.Router(), repo.evictExpired, repo.seed
+	default:
+		repo := newGoodRepository(o)
+		router, evict, seed = repo.Router(), repo.evictExpired, repo.seed
+	}
+	if o.seed > 0 {
+		seed(o.seed)
+	}
+	return &Repository{Strategy: o.strategy, router: router, stop: startJanitor(o.ttl, evict)}
+}
+
+/*
+ServeHTTP sert une requête avec les routes du repository.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+*/
+func (r *Repository) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.router.ServeHTTP(w, req)
+}
+
+/*
+Close arrête le janitor du repository.
+
+@returns: error - Toujours nil
+*/
+func (r *Repository) Close() error {
+	r.stop()
+	return nil
+}
+
+/*
+makeRoom évince une entrée arbitraire si la map a atteint sa borne et que
+key est une nouvelle clé. L'appelant détient le verrou en écriture.
+
+@params:
+  - data: map[string]*DataStruct map à borner
+  - key: string clé sur le point d'être écrite
+  - maxEntries: int borne de la map (0 ou négatif = illimitée)
+*/
+func makeRoom(data map[string]*DataStruct, key string, maxEntries int) {
+	if maxEntries <= 0 || len(data) < maxEntries {
+		return
+	}
+	if _, exists := data[key]; exists {
+		return
+	}
+	// L'ordre d'itération des maps est aléatoire : la victime est quelconque
+	for victim := range data {
+		delete(data, victim)
+		return
+	}
+}
@@ -0,0 +1,450 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+/*
+TestNewRepositoryOptions vérifie sur chaque stratégie que WithWork remplace
+le traitement lourd et que WithMapCap borne la map, et que les valeurs par
+défaut reproduisent le comportement historique.
+*/
+func TestNewRepositoryOptions(t *testing.T) {
+	if o := newRepoOptions(); !o.copyData || o.mapCap != 0 || o.ttl != 0 || o.work == nil || o.strategy != Mutex {
+		t.Errorf("default options = %+v, want copy, unbounded, no TTL, default work, Mutex", o)
+	}
+
+	const mapCap = 3
+	for _, strategy := range []LockStrategy{Mutex, RWMutex, SyncMap, DeferMutex, AtomicValue} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			repo := NewRepository(WithLockStrategy(strategy), WithMapCap(mapCap), WithWork(0, 10))
+			defer repo.Close()
+
+			for i := 0; i < 2*mapCap; i++ {
+				rec := httptest.NewRecorder()
+				repo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/process", nil))
+				var body struct {
+					Result int `json:"result"`
+				}
+				if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+					t.Fatalf("invalid JSON: %v", err)
+				}
+				if body.Result != 45 {
+					t.Fatalf("result = %d, want 45 (sum of 10 iterations)", body.Result)
+				}
+			}
+
+			rec := httptest.NewRecorder()
+			repo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+			var stats struct {
+				DataSize int `json:"data_size"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if stats.DataSize != mapCap {
+				t.Errorf("data_size = %d, want %d", stats.DataSize, mapCap)
+			}
+		})
+	}
+}
+
+/*
+TestWorkFuncSameWork injecte le même traitement dans DeferMutex (bad) et
+Mutex (good) et compte combien de traitements s'exécutent en même temps :
+un seul à la fois sous le defer, plusieurs quand le verrou est libéré avant.
+La différence ne vient que du verrouillage, pas du travail simulé.
+*/
+func TestWorkFuncSameWork(t *testing.T) {
+	const requests = 8
+
+	for _, c := range []struct {
+		strategy LockStrategy
+		parallel bool
+	}{
+		{DeferMutex, false},
+		{Mutex, true},
+	} {
+		t.Run(c.strategy.String(), func(t *testing.T) {
+			var active, maxActive atomic.Int64
+			work := func(ctx context.Context) int {
+				n := active.Add(1)
+				for {
+					m := maxActive.Load()
+					if n <= m || maxActive.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				active.Add(-1)
+				return 1
+			}
+			repo := NewRepository(WithLockStrategy(c.strategy), WithWorkFunc(work))
+			defer repo.Close()
+
+			var wg sync.WaitGroup
+			for i := 0; i < requests; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					repo.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/process", nil))
+				}()
+			}
+			wg.Wait()
+
+			if got := maxActive.Load(); (got > 1) != c.parallel {
+				t.Errorf("max concurrent work = %d, want parallel=%v", got, c.parallel)
+			}
+		})
+	}
+}
+
+/*
+TestFanOutWork vérifie que la boucle répartie entre goroutines rend le même
+résultat que la boucle d'un seul tenant, y compris quand les tranches sont
+inégales.
+*/
+func TestFanOutWork(t *testing.T) {
+	const iters = 100003
+	want := spin(iters)
+	for _, parts := range []int{0, 1, 2, 7, 64} {
+		if got := fanOutWork(0, iters, parts)(context.Background()); got != want {
+			t.Errorf("fanOutWork(%d parts) = %d, want %d", parts, got, want)
+		}
+	}
+}
+
+/*
+TestWorkSteps vérifie que les tranches posées par withWorkStep recouvrent
+exactement le traitement entier, avec ou sans -fanout.
+*/
+func TestWorkSteps(t *testing.T) {
+	const iters, steps = 100003, 7
+	for name, work := range map[string]WorkFunc{
+		"sleep":  sleepWork(0, iters),
+		"fanout": fanOutWork(0, iters, 3),
+	} {
+		sum := 0
+		for step := 1; step <= steps; step++ {
+			sum += work(withWorkStep(context.Background(), step, steps))
+		}
+		if want := spin(iters); sum != want {
+			t.Errorf("%s: sum of %d steps = %d, want %d", name, steps, sum, want)
+		}
+	}
+}
+
+/*
+TestRepositoriesUseInjectedWork vérifie que les variantes construites avec
+leurs propres paramètres exécutent le traitement injecté par WithWorkFunc,
+et non un traitement codé en dur.
+*/
+func TestRepositoriesUseInjectedWork(t *testing.T) {
+	var calls atomic.Int64
+	work := func(ctx context.Context) int {
+		calls.Add(1)
+		return 42
+	}
+	o := newRepoOptions(WithWorkFunc(work))
+	handlers := map[string]http.Handler{
+		"fair":         newFairRepository(o).Router(),
+		"scoped":       newScopedRepository(o).Router(),
+		"semaphore":    newSemaphoreRepository(o, 1).Router(),
+		"trylock":      newTryLockRepository(o).Router(),
+		"admission":    newAdmissionRepository(o, 1, 0).Router(),
+		"dcl":          newDCLRepository(o).Router(),
+		"singleflight": newSingleflightRepository(o).Router(),
+	}
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			before := calls.Load()
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/process?key=k", nil))
+			var resp ResponseV1
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("status %d, invalid body %q: %v", rec.Code, rec.Body.String(), err)
+			}
+			if resp.Result != 42 || calls.Load() != before+1 {
+				t.Errorf("result = %d after %d work calls, want 42 after 1", resp.Result, calls.Load()-before)
+			}
+		})
+	}
+}
+
+/*
+TestVariantsApplyOptions vérifie que chaque variante qui stocke des entrées
+applique -mapCap, -payload et -iters : après trois écritures, la map ne
+garde que la dernière, qui porte le blob demandé, et le résultat est celui
+de la boucle configurée.
+*/
+func TestVariantsApplyOptions(t *testing.T) {
+	const iters, payload = 10, 16
+	opts := Options{CopyData: true, SemaphoreLimit: 2, PoolWorkers: 2, PoolQueue: 4, AdmitBudget: time.Second,
+		MapCap: 1, Payload: payload, WorkIters: iters}
+
+	for _, v := range Variants {
+		if v.Name == "floor" {
+			continue // Ni map ni entrées
+		}
+		t.Run(v.Name, func(t *testing.T) {
+			handler := v.NewHandler(opts)
+			defer handler.(io.Closer).Close()
+
+			var resp ResponseV1
+			for i := 1; i <= 3; i++ {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/process?key=k%d", i), nil))
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("status %d, invalid body %q: %v", rec.Code, rec.Body.String(), err)
+				}
+			}
+			if resp.Result != spin(iters) {
+				t.Errorf("result = %d, want %d", resp.Result, spin(iters))
+			}
+			if size := dataSize(t, handler); size != 1 {
+				t.Errorf("data_size = %d, want 1", size)
+			}
+
+			last := "request_3"
+			if resp.Key != "" {
+				last = resp.Key // dcl et singleflight indexent par clé
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/data/"+last, nil))
+			var entry DataStruct
+			if err := json.Unmarshal(rec.Body.Bytes(), &entry); err != nil {
+				t.Fatalf("GET /data/%s status %d: %v", last, rec.Code, err)
+			}
+			if len(entry.Payload) != payload {
+				t.Errorf("payload length = %d, want %d", len(entry.Payload), payload)
+			}
+		})
+	}
+}
+
+/*
+BenchmarkFanOut compare le parallélisme à l'intérieur des requêtes sur les
+serveurs bad et good, avec 4 requêtes en vol par GOMAXPROCS : la boucle de
+calcul de chaque requête est répartie entre fanout goroutines (-fanout). Sur
+le serveur bad, le mutex tenu par defer empêche les tranches de deux requêtes
+de se recouvrir ; sur le good, elles se partagent les cœurs. Sur un seul
+cœur, aucun découpage ne peut accélérer un calcul. ns/op est le temps écoulé
+par requête, l'inverse du débit.
+
+	go test ./pkg/server -run '^$' -bench FanOut -cpu 1,4
+*/
+func BenchmarkFanOut(b *testing.B) {
+	const iters = 2000000
+	for _, strategy := range []LockStrategy{DeferMutex, Mutex} {
+		for _, fanout := range []int{1, 4} {
+			b.Run(fmt.Sprintf("%s/fanout=%d", strategy, fanout), func(b *testing.B) {
+				repo := NewRepository(WithLockStrategy(strategy), WithCopyData(false), WithWorkFunc(fanOutWork(0, iters, fanout)))
+				defer repo.Close()
+				b.SetParallelism(4)
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						repo.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/process", nil))
+					}
+				})
+			})
+		}
+	}
+}
+
+/*
+TestCalibrateWork vérifie que les itérations calibrées durent à peu près la
+cible : la meilleure de plusieurs mesures, pour ne pas échouer sur une
+préemption, doit tomber dans un facteur 3 de target.
+*/
+func TestCalibrateWork(t *testing.T) {
+	const target = 5 * time.Millisecond
+	if got := calibrateWork(0); got != 0 {
+		t.Errorf("calibrateWork(0) = %d, want 0", got)
+	}
+
+	iters := calibrateWork(target)
+	best := time.Duration(1<<63 - 1)
+	for run := 0; run < 5; run++ {
+		start := time.Now()
+		spinSink += spin(iters)
+		best = min(best, time.Since(start))
+	}
+	if best < target/3 || best > 3*target {
+		t.Errorf("calibrateWork(%s) = %d iterations, measured %s", target, iters, best)
+	}
+}
+
+/*
+TestPinnedWork vérifie que pinnedWork rend le résultat du traitement et
+laisse remonter sa panique, le defer d'UnlockOSThread s'exécutant au passage.
+*/
+func TestPinnedWork(t *testing.T) {
+	if got := pinnedWork(sleepWork(0, 10))(context.Background()); got != 45 {
+		t.Errorf("pinned work result = %d, want 45", got)
+	}
+
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("recovered %v, want the work's panic", r)
+		}
+	}()
+	pinnedWork(func(context.Context) int { panic("boom") })(context.Background())
+	t.Fatal("pinned work did not panic")
+}
+
+/*
+BenchmarkPinWork compare la dispersion de la durée d'un même calcul, épinglé
+à un thread système (-pinWork) ou non, avec 4 goroutines par GOMAXPROCS en
+concurrence pour le processeur, comme les requêtes d'un serveur chargé.
+
+	go test ./pkg/server -run '^$' -bench PinWork -benchtime 2000x
+
+@metrics:
+  - mean-us: Durée moyenne d'un calcul
+  - stddev-us: Écart type de cette durée
+  - p99-us: 99e percentile de cette durée
+*/
+func BenchmarkPinWork(b *testing.B) {
+	const iters = 100000
+	for _, v := range []struct {
+		name string
+		work WorkFunc
+	}{
+		{"unpinned", sleepWork(0, iters)},
+		{"pinned", pinnedWork(sleepWork(0, iters))},
+	} {
+		b.Run(v.name, func(b *testing.B) {
+			var mu sync.Mutex
+			durations := make([]time.Duration, 0, b.N)
+			b.SetParallelism(4)
+			b.RunParallel(func(pb *testing.PB) {
+				ctx := context.Background()
+				local := make([]time.Duration, 0, b.N)
+				sink := 0
+				for pb.Next() {
+					start := time.Now()
+					sink += v.work(ctx)
+					local = append(local, time.Since(start))
+				}
+				mu.Lock()
+				durations = append(durations, local...)
+				spinSink += sink
+				mu.Unlock()
+			})
+			b.StopTimer()
+
+			mean, stddev, p99 := durationSpread(durations)
+			b.ReportMetric(mean, "mean-us")
+			b.ReportMetric(stddev, "stddev-us")
+			b.ReportMetric(p99, "p99-us")
+		})
+	}
+}
+
+/*
+durationSpread résume des durées en microsecondes.
+
+@params:
+  - durations: []time.Duration durées mesurées (triées sur place)
+
+@returns: (float64, float64, float64) - Moyenne, écart type et 99e percentile
+*/
+func durationSpread(durations []time.Duration) (mean, stddev, p99 float64) {
+	if len(durations) == 0 {
+		return 0, 0, 0
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	for _, d := range durations {
+		mean += float64(d.Microseconds())
+	}
+	mean /= float64(len(durations))
+	for _, d := range durations {
+		diff := float64(d.Microseconds()) - mean
+		stddev += diff * diff
+	}
+	stddev = math.Sqrt(stddev / float64(len(durations)))
+	p99 = float64(durations[(len(durations)-1)*99/100].Microseconds())
+	return mean, stddev, p99
+}
+
+/*
+TestWithSeed vérifie sur chaque stratégie que WithSeed pré-remplit la map à
+la construction : la première requête /process copie déjà les n entrées
+(/snapshot pour sync.Map, dont /process ne lit qu'une clé).
+*/
+func TestWithSeed(t *testing.T) {
+	const seeded = 5
+	for _, strategy := range []LockStrategy{Mutex, RWMutex, SyncMap, DeferMutex, AtomicValue} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			repo := NewRepository(WithLockStrategy(strategy), WithSeed(seeded), WithPayload(16), WithWork(0, 10))
+			defer repo.Close()
+
+			if size := dataSize(t, repo); size != seeded {
+				t.Errorf("data_size before any request = %d, want %d", size, seeded)
+			}
+			target := "/process"
+			if strategy == SyncMap {
+				target = "/snapshot"
+			}
+			rec := httptest.NewRecorder()
+			repo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+			var body struct {
+				SnapshotSize int `json:"snapshot_size"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if body.SnapshotSize != seeded {
+				t.Errorf("snapshot_size = %d, want %d", body.SnapshotSize, seeded)
+			}
+		})
+	}
+}
+
+/*
+TestBigCopyLockHold compare la détention du mutex des serveurs good et
+bigcopy, au même handler : la copie de la map pré-remplie allonge la section
+critique de plusieurs ordres de grandeur, l'écriture restant aussi courte.
+*/
+func TestBigCopyLockHold(t *testing.T) {
+	holds := map[string]float64{}
+	for _, name := range []string{"good", "bigcopy"} {
+		v, _ := Lookup(name)
+		handler := v.NewHandler(Options{CopyData: true})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/process", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: GET /process status = %d, want 200", name, rec.Code)
+		}
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+		var stats struct {
+			DataSize int     `json:"data_size"`
+			AvgHold  float64 `json:"avg_lock_hold_us"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+			t.Fatalf("%s: invalid JSON: %v", name, err)
+		}
+		handler.(io.Closer).Close()
+		if name == "bigcopy" && stats.DataSize != bigCopySeed+1 {
+			t.Errorf("bigcopy data_size = %d, want %d", stats.DataSize, bigCopySeed+1)
+		}
+		holds[name] = stats.AvgHold
+	}
+	if holds["bigcopy"] < 10*holds["good"] {
+		t.Errorf("avg_lock_hold_us: bigcopy %.1f, good %.1f, want the copy to dominate", holds["bigcopy"], holds["good"])
+	}
+}
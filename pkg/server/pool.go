@@ -0,0 +1,377 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+/*
+poolJob représente une requête acceptée en attente d'un worker.
+
+@fields:
+  - ctx: Contexte de la requête, portant le span parent du traitement
+  - done: Canal recevant le résultat une fois le traitement terminé
+*/
+type poolJob struct {
+	ctx  context.Context
+	done chan poolResult
+}
+
+/*
+poolResult contient le résultat d'un traitement effectué par un worker.
+*/
+type poolResult struct {
+	counter  int
+	result   int
+	snapshot map[string]*DataStruct // Copie des données lue sous le mutex
+}
+
+/*
+PoolRepository découple l'acceptation des requêtes de la capacité de traitement.
+Les handlers déposent le travail dans une file bornée, consommée par un nombre
+fixe de workers ; quand la file est pleine, la requête est rejetée (503).
+
+@fields:
+  - mu: Mutex pour protéger l'accès concurrent aux données
+  - counter: Compteur global des requêtes traitées
+  - data: Map simulant des données métier partagées avec structure complexe
+  - copyData: Active la copie des données avant traitement (workload "lecture puis traitement")
+  - mapCap: Nombre maximum d'entrées de la map (0 = illimité)
+  - work: Traitement lourd exécuté par les workers, hors mutex
+  - payload: Taille en octets du payload de chaque nouvelle entrée
+  - enc: Encodeur des réponses de /process (voir -encoder)
+  - jobs: File bornée des requêtes acceptées, fermée par Close
+  - rejected: Nombre de requêtes rejetées faute de place dans la file
+  - closeMu: Protège closed et le dépôt dans jobs contre sa fermeture
+  - closed: Vrai une fois Close appelé, les nouvelles requêtes sont rejetées
+  - closeOnce: Garantit une seule fermeture de jobs
+  - workers: Attend la sortie des workers dans Close
+*/
+type PoolRepository struct {
+	mu        sync.Mutex
+	counter   int
+	data      map[string]*DataStruct
+	copyData  bool
+	mapCap    int
+	work      WorkFunc
+	payload   int
+	enc       Encoder
+	jobs      chan poolJob
+	rejected  atomic.Int64
+	closeMu   sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+	workers   sync.WaitGroup
+}
+
+/*
+NewPoolRepository crée le repository et démarre le pool de workers.
+
+@params:
+  - copyData: bool active la copie des données partagées à chaque requête
+  - workers: int nombre de goroutines de traitement
+  - queueDepth: int nombre de requêtes pouvant attendre un worker
+
+@returns: *PoolRepository - Nouvelle instance avec ses workers démarrés,
+à arrêter avec Close
+*/
+func NewPoolRepository(copyData bool, workers, queueDepth int) *PoolRepository {
+	return newPoolRepository(newRepoOptions(WithCopyData(copyData)), workers, queueDepth)
+}
+
+/*
+newPoolRepository crée le repository à partir d'options déjà appliquées,
+pour que les workers exécutent le même traitement, écrivent le même
+payload et que les réponses passent par le même encodeur que les autres
+variantes.
+
+@params:
+  - o: repoOptions configuration (copie, borne, traitement, payload, encodeur)
+  - workers: int nombre de goroutines de traitement
+  - queueDepth: int nombre de requêtes pouvant attendre un worker
+
+@returns: *PoolRepository - Nouvelle instance avec ses workers démarrés,
+à arrêter avec Close
+*/
+func newPoolRepository(o repoOptions, workers, queueDepth int) *PoolRepository {
+	r := &PoolRepository{
+		data:     make(map[string]*DataStruct),
+		copyData: o.copyData,
+		mapCap:   o.mapCap,
+		work:     o.work,
+		payload:  o.payload,
+		enc:      o.encoder,
+		jobs:     make(chan poolJob, queueDepth),
+	}
+	r.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer r.workers.Done()
+			r.worker()
+		}()
+	}
+	return r
+}
+
+/*
+Close ferme la file et attend la sortie des workers. Les requêtes déjà
+déposées sont traitées jusqu'au bout, les suivantes reçoivent un 503.
+Appelable plusieurs fois.
+
+@returns: error - Toujours nil
+*/
+func (r *PoolRepository) Close() error {
+	r.closeOnce.Do(func() {
+		r.closeMu.Lock()
+		r.closed = true
+		close(r.jobs)
+		r.closeMu.Unlock()
+	})
+	r.workers.Wait()
+	return nil
+}
+
+/*
+submit dépose un job dans la file sans bloquer.
+
+@params:
+  - job: poolJob requête à traiter
+
+@returns: (bool, string) - true si déposé, sinon le motif du rejet
+*/
+func (r *PoolRepository) submit(job poolJob) (bool, string) {
+	// Lecture partagée : les dépôts ne s'excluent pas, seul Close les attend
+	r.closeMu.RLock()
+	defer r.closeMu.RUnlock()
+	if r.closed {
+		return false, "work pool is closed"
+	}
+	select {
+	case r.jobs <- job:
+		return true, ""
+	default:
+		return false, "work queue is full"
+	}
+}
+
+/*
+worker consomme la file et effectue le traitement lourd.
+Chaque worker ne partage qu'un seul mutex, verrouillé brièvement pour la
+lecture puis pour l'écriture finale.
+*/
+func (r *PoolRepository) worker() {
+	for job := range r.jobs {
+		ctx := job.ctx
+		tracedLock(ctx, "mu.read", r.mu.Lock)
+		r.counter++
+		currentCounter := r.counter
+		// La copie modélise un workload "lecture puis traitement" : son coût
+		// croît avec la taille de la map, elle est donc désactivable (-copy=false)
+		dataCopy := make(map[string]*DataStruct)
+		if r.copyData {
+			for k, v := range r.data {
+				dataCopy[k] = &DataStruct{
+					Identifier:   v.Identifier,
+					Name:         v.Name,
+					IsActive:     v.IsActive,
+					Counter:      v.Counter,
+					LastModified: v.LastModified,
+					Payload:      bytes.Clone(v.Payload),
+				}
+			}
+		}
+		r.mu.Unlock()
+
+		// Traitement lourd SANS le mutex
+		heavySpan := startPhase(ctx, "process.heavy")
+		result := r.work(ctx) // Attente simulée puis calcul intensif
+		heavySpan.End()
+
+		key := fmt.Sprintf("request_%d", currentCounter)
+		payload := newPayload(r.payload) // Allouée avant de reprendre le mutex
+		writeSpan := startPhase(ctx, "write")
+		tracedLock(ctx, "mu.write", r.mu.Lock)
+		makeRoom(r.data, key, r.mapCap)
+		r.data[key] = &DataStruct{
+			Identifier:   key,
+			Name:         fmt.Sprintf("Request %d", currentCounter),
+			IsActive:     true,
+			Counter:      result,
+			LastModified: time.Now(),
+			Payload:      payload,
+		}
+		r.mu.Unlock()
+		writeSpan.End()
+
+		job.done <- poolResult{counter: currentCounter, result: result, snapshot: dataCopy}
+	}
+}
+
+/*
+PoolHandler dépose la requête dans la file du pool et attend son résultat.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@behavior:
+  1. Tente de déposer la requête dans la file sans bloquer
+  2. Si la file est pleine ou le pool fermé, répond 503 immédiatement (backpressure)
+  3. Sinon attend qu'un worker ait terminé le traitement
+
+@performance: Capacité de traitement fixe, surcharge rejetée au lieu d'être mise en file indéfiniment
+*/
+func (r *PoolRepository) PoolHandler(w http.ResponseWriter, req *http.Request) {
+	includeData, ok := parseIncludeData(w, req)
+	if !ok {
+		return
+	}
+	start := time.Now()
+	ctx, span := startSpan(req.Context(), "pool.process")
+	defer span.End()
+
+	// Canal bufferisé : le worker ne bloque pas si le client est parti
+	job := poolJob{ctx: ctx, done: make(chan poolResult, 1)}
+	if ok, reason := r.submit(job); !ok {
+		r.rejected.Add(1)
+		span.SetAttributes(attribute.Bool("queue.rejected", true))
+		writeError(w, http.StatusServiceUnavailable, reason)
+		return
+	}
+
+	var res poolResult
+	select {
+	case res = <-job.done:
+	case <-req.Context().Done():
+		return
+	}
+
+	response := newResponse(req, "worker_pool", start)
+	response.Counter = res.counter
+	response.Result = res.result
+	response.SnapshotSize = len(res.snapshot)
+
+	if includeData {
+		addSnapshot(response, res.snapshot)
+	}
+	writeEncoded(w, http.StatusOK, response, r.enc)
+}
+
+/*
+evictExpired supprime les entrées dont LastModified précède cutoff.
+Appelée par le janitor, sous le mutex le temps du seul balayage.
+
+@params:
+  - cutoff: time.Time date limite de dernière modification
+
+@returns: int - Nombre d'entrées supprimées
+*/
+func (r *PoolRepository) evictExpired(cutoff time.Time) int {
+	r.mu.Lock()
+	evicted := 0
+	for key, entry := range r.data {
+		if entry.LastModified.Before(cutoff) {
+			delete(r.data, key)
+			evicted++
+		}
+	}
+	r.mu.Unlock()
+	return evicted
+}
+
+/*
+GetDataHandler retourne l'entrée {id} en JSON, ou 404 si elle est absente.
+Le mutex n'est tenu que le temps de copier l'entrée, l'encodage se fait après.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+
+@returns: JSON DataStruct
+*/
+func (r *PoolRepository) GetDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.mu.Lock()
+	var entry DataStruct
+	v, found := r.data[id]
+	if found {
+		entry = *v
+	}
+	r.mu.Unlock()
+
+	writeData(w, id, entry, found)
+}
+
+/*
+DeleteDataHandler supprime l'entrée {id} : 204 si supprimée, 404 si absente.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+*/
+func (r *PoolRepository) DeleteDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.mu.Lock()
+	_, found := r.data[id]
+	delete(r.data, id)
+	r.mu.Unlock()
+
+	writeDeleted(w, id, found)
+}
+
+/*
+StatsHandler retourne les statistiques actuelles du serveur.
+Utilise correctement le mutex uniquement pour la lecture des données.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON contenant total_requests, data_size, rejected et queue_length
+*/
+func (r *PoolRepository) StatsHandler(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	stats := map[string]interface{}{
+		"total_requests": r.counter,
+		"data_size":      len(r.data),
+	}
+	r.mu.Unlock()
+	stats["rejected"] = r.rejected.Load()
+	stats["queue_length"] = len(r.jobs)
+
+	writeStats(w, stats)
+}
+
+/*
+Router configure les routes du serveur avec gorilla/mux.
+
+@returns: *mux.Router - Routeur prêt à être servi
+
+@endpoints:
+  - GET /process : Handler déposant le travail dans le pool de workers
+  - GET /data/{id} : Lecture d'une entrée (404 si absente)
+  - DELETE /data/{id} : Suppression d'une entrée
+  - GET /stats : Statistiques du serveur
+  - GET /debug/allocs : Compteurs d'allocation du processus
+  - GET /debug/contention : Résumé du profil de contention des mutex
+*/
+func (r *PoolRepository) Router() *mux.Router {
+	router := newRouter()
+	router.HandleFunc("/process", r.PoolHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.GetDataHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.DeleteDataHandler).Methods("DELETE")
+	router.HandleFunc("/stats", r.StatsHandler).Methods("GET")
+	router.HandleFunc("/debug/allocs", AllocsHandler).Methods("GET")
+	router.HandleFunc("/debug/contention", ContentionHandler).Methods("GET")
+	return router
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+/*
+TestPoolCloseStopsWorkers vérifie que fermer le handler de la variante pool
+arrête ses workers : Close rend la main une fois tous sortis, reste
+idempotent, et une requête arrivée après la fermeture reçoit un 503 au lieu
+de paniquer sur la file fermée.
+*/
+func TestPoolCloseStopsWorkers(t *testing.T) {
+	var pool Variant
+	for _, v := range Variants {
+		if v.Name == "pool" {
+			pool = v
+		}
+	}
+	handler := pool.NewHandler(Options{PoolWorkers: 4, PoolQueue: 8})
+	process := func() int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/process", nil))
+		return rec.Code
+	}
+	if code := process(); code != http.StatusOK {
+		t.Fatalf("status before Close = %d, want 200", code)
+	}
+
+	closer, ok := handler.(io.Closer)
+	if !ok {
+		t.Fatal("pool handler does not implement io.Closer")
+	}
+	closed := make(chan struct{})
+	go func() {
+		closer.Close()
+		closer.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not return: workers still running")
+	}
+
+	if code := process(); code != http.StatusServiceUnavailable {
+		t.Errorf("status after Close = %d, want 503", code)
+	}
+}
+
+/*
+TestPoolUsesSharedWork vérifie que les workers exécutent le traitement des
+options, comme les autres variantes, et que l'écriture donne son payload à
+la nouvelle entrée en respectant la borne de la map.
+*/
+func TestPoolUsesSharedWork(t *testing.T) {
+	var calls atomic.Int64
+	work := func(context.Context) int {
+		calls.Add(1)
+		return 42
+	}
+	repo := newPoolRepository(newRepoOptions(WithWorkFunc(work), WithPayload(16), WithMapCap(1)), 2, 4)
+	defer repo.Close()
+	handler := repo.Router()
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/process", nil))
+		var resp struct {
+			Result int `json:"result"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if resp.Result != 42 {
+			t.Errorf("result = %d, want 42 from the work function", resp.Result)
+		}
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("work called %d times, want 3", n)
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if len(repo.data) != 1 {
+		t.Errorf("data size = %d, want 1 with a map cap of 1", len(repo.data))
+	}
+	for key, entry := range repo.data {
+		if len(entry.Payload) != 16 {
+			t.Errorf("%s payload = %d bytes, want 16", key, len(entry.Payload))
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// prometheusPath est la route ajoutée par -prom à chaque serveur
+const prometheusPath = "/stats/prometheus"
+
+// prometheusContentType est le type du format texte d'exposition Prometheus (version 0.0.4)
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+/*
+promMetric associe un champ de /stats à une métrique Prometheus.
+
+@fields:
+  - key: Champ du JSON de /stats
+  - name: Nom de la métrique ([a-zA-Z_:][a-zA-Z0-9_:]*)
+  - kind: Type annoncé par la ligne # TYPE (counter ou gauge)
+  - help: Description annoncée par la ligne # HELP
+*/
+type promMetric struct {
+	key, name, kind, help string
+}
+
+// promMetrics sont les métriques exposées, quand la variante les publie dans /stats
+var promMetrics = []promMetric{
+	{"total_requests", "mutex_benchmark_requests_total", "counter", "Requêtes /process reçues"},
+	{"data_size", "mutex_benchmark_data_size", "gauge", "Entrées dans la map partagée"},
+	{"avg_lock_hold_us", "mutex_benchmark_avg_lock_hold_microseconds", "gauge", "Durée moyenne de détention du mutex"},
+}
+
+/*
+bufferedResponse capture la réponse d'un handler appelé en interne.
+
+@fields:
+  - header: En-têtes posés par le handler
+  - status: Code HTTP (0 tant que rien n'est écrit)
+  - body: Corps de la réponse
+*/
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// Header retourne les en-têtes de la réponse capturée
+func (r *bufferedResponse) Header() http.Header { return r.header }
+
+// WriteHeader enregistre le premier code envoyé
+func (r *bufferedResponse) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+// Write capture le corps, avec un 200 implicite
+func (r *bufferedResponse) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(b)
+}
+
+/*
+writePrometheus écrit les métriques de promMetrics présentes dans stats au
+format texte d'exposition Prometheus, chacune précédée de ses lignes # HELP
+et # TYPE, avec le label variant.
+
+	# HELP mutex_benchmark_requests_total Requêtes /process reçues
+	# TYPE mutex_benchmark_requests_total counter
+	mutex_benchmark_requests_total{variant="good"} 42
+
+@params:
+  - buf: *bytes.Buffer destination
+  - variant: string nom de la variante (valeur du label)
+  - stats: map[string]any JSON de /stats décodé avec UseNumber
+
+@note: Un champ absent ou non numérique est omis, ses lignes # HELP et
+# TYPE comprises : Prometheus refuse une valeur qui n'est pas un nombre.
+*/
+func writePrometheus(buf *bytes.Buffer, variant string, stats map[string]any) {
+	label := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(variant)
+	for _, m := range promMetrics {
+		value, ok := stats[m.key].(json.Number)
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(buf, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(buf, "# TYPE %s %s\n", m.name, m.kind)
+		fmt.Fprintf(buf, "%s{variant=\"%s\"} %s\n", m.name, label, value)
+	}
+}
+
+/*
+withPrometheus ajoute GET /stats/prometheus devant le handler d'une variante
+(flag -prom), sans dépendre de la bibliothèque client Prometheus : la route
+appelle le /stats de la variante en interne et en réécrit les métriques
+principales au format texte. Les autres requêtes passent au handler.
+
+@params:
+  - next: http.Handler handler de la variante (son routeur)
+  - variant: string nom de la variante, exposé dans le label variant
+
+@returns: http.Handler - Handler servant aussi /stats/prometheus
+*/
+func withPrometheus(next http.Handler, variant string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != prometheusPath || req.Method != http.MethodGet {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		statsReq, err := http.NewRequestWithContext(req.Context(), http.MethodGet, "/stats", nil)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		statsReq.Header.Set("Accept", "application/json")
+		rec := &bufferedResponse{header: make(http.Header)}
+		next.ServeHTTP(rec, statsReq)
+		if rec.status != http.StatusOK {
+			writeError(w, http.StatusBadGateway, fmt.Sprintf("/stats returned %d", rec.status))
+			return
+		}
+
+		stats := map[string]any{}
+		dec := json.NewDecoder(&rec.body)
+		dec.UseNumber()
+		if err := dec.Decode(&stats); err != nil {
+			writeError(w, http.StatusBadGateway, "/stats: "+err.Error())
+			return
+		}
+
+		var buf bytes.Buffer
+		writePrometheus(&buf, variant, stats)
+		w.Header().Set("Content-Type", prometheusContentType)
+		w.Write(buf.Bytes())
+	})
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// promSample reconnaît une ligne d'échantillon : nom{labels} valeur
+var promSample = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)\{variant="([^"\\]*)"\} (\S+)$`)
+
+// promComment reconnaît les lignes # HELP et # TYPE
+var promComment = regexp.MustCompile(`^# (HELP|TYPE) ([a-zA-Z_:][a-zA-Z0-9_:]*) (.+)$`)
+
+/*
+parsePrometheus vérifie un corps au format texte Prometheus : chaque
+échantillon suit la ligne # TYPE de sa métrique, de type counter ou gauge,
+et porte une valeur numérique.
+
+@params:
+  - t: *testing.T test propriétaire
+  - body: string corps de /stats/prometheus
+
+@returns: map[string]float64 - Valeur de chaque métrique
+*/
+func parsePrometheus(t *testing.T, body string) map[string]float64 {
+	t.Helper()
+	values := map[string]float64{}
+	typed := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := promComment.FindStringSubmatch(line); m != nil {
+			if m[1] == "TYPE" {
+				if m[3] != "counter" && m[3] != "gauge" {
+					t.Errorf("metric %s has type %q", m[2], m[3])
+				}
+				typed[m[2]] = true
+			}
+			continue
+		}
+		m := promSample.FindStringSubmatch(line)
+		if m == nil {
+			t.Errorf("invalid exposition line %q", line)
+			continue
+		}
+		if !typed[m[1]] {
+			t.Errorf("sample %s before its # TYPE line", m[1])
+		}
+		v, err := strconv.ParseFloat(m[3], 64)
+		if err != nil {
+			t.Errorf("sample %s: value %q is not a number", m[1], m[3])
+		}
+		values[m[1]] = v
+	}
+	return values
+}
+
+/*
+TestPrometheusEndpoint vérifie sur chaque variante que /stats/prometheus
+expose total_requests au format texte, avec la même valeur que /stats, et
+data_size et avg_lock_hold_us quand la variante les publie.
+*/
+func TestPrometheusEndpoint(t *testing.T) {
+	opts := Options{SemaphoreLimit: 2, PoolWorkers: 2, PoolQueue: 4}
+
+	for _, v := range Variants {
+		t.Run(v.Name, func(t *testing.T) {
+			inner := v.NewHandler(opts)
+			defer inner.(io.Closer).Close()
+			handler := withPrometheus(inner, v.Name)
+
+			target := "/process"
+			if v.Name == "dcl" || v.Name == "singleflight" {
+				target = "/process?key=k"
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, prometheusPath, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != prometheusContentType {
+				t.Errorf("Content-Type = %q, want %q", ct, prometheusContentType)
+			}
+			if !strings.Contains(rec.Body.String(), `{variant="`+v.Name+`"}`) {
+				t.Errorf("samples lack the variant label:\n%s", rec.Body)
+			}
+			values := parsePrometheus(t, rec.Body.String())
+
+			statsRec := httptest.NewRecorder()
+			handler.ServeHTTP(statsRec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+			var stats map[string]any
+			if err := json.Unmarshal(statsRec.Body.Bytes(), &stats); err != nil {
+				t.Fatalf("decode /stats: %v", err)
+			}
+			for _, m := range promMetrics {
+				want, published := stats[m.key].(float64)
+				got, exposed := values[m.name]
+				switch {
+				case published != exposed:
+					t.Errorf("%s: published in /stats = %v, exposed = %v", m.key, published, exposed)
+				case m.key == "total_requests" && got != want:
+					t.Errorf("%s = %v, /stats has %v", m.name, got, want)
+				}
+			}
+			if _, ok := values["mutex_benchmark_requests_total"]; !ok {
+				t.Error("mutex_benchmark_requests_total missing")
+			}
+		})
+	}
+}
+
+/*
+TestWritePrometheusSkipsNonNumeric vérifie qu'un champ absent ou non
+numérique est omis avec ses lignes # HELP et # TYPE, et que le label est
+échappé.
+*/
+func TestWritePrometheusSkipsNonNumeric(t *testing.T) {
+	var buf bytes.Buffer
+	writePrometheus(&buf, `a"b`, map[string]any{
+		"total_requests":   json.Number("3"),
+		"avg_lock_hold_us": "n/a",
+	})
+	want := "# HELP mutex_benchmark_requests_total Requêtes /process reçues\n" +
+		"# TYPE mutex_benchmark_requests_total counter\n" +
+		"mutex_benchmark_requests_total{variant=\"a\\\"b\"} 3\n"
+	if buf.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	// readPathEntries est la taille de la map avant la mesure
+	readPathEntries = 1000
+	// readPathReadPct est la part de lectures du mélange, le cas favorable à sync.Map
+	readPathReadPct = 95
+)
+
+/*
+BenchmarkReadPath compare le chemin de lecture d'une map protégée par un
+RWMutex à celui d'un sync.Map et d'une map copy-on-write (AtomicValue),
+sous 95 % de lectures et 5 % d'écritures. Le traitement lourd est désactivé
+(WithWork(0, 0)) et la copie aussi : seule la synchronisation de la map
+distingue les variantes. Avec AtomicValue, write-ns/op inclut la copie
+complète de la map à chaque écriture.
+
+	go test ./pkg/server -run '^$' -bench ReadPath -cpu 1,4,8
+
+@behavior:
+  - La map est pré-remplie avec readPathEntries entrées via POST /seed
+  - Lectures : GET /data/seed_N sur une clé existante tirée au hasard
+  - Écritures : GET /process, qui insère une nouvelle entrée
+
+@metrics:
+  - read-ns/op, write-ns/op: Latence moyenne des lectures et des écritures
+  - read-%: Part effective de lectures
+*/
+func BenchmarkReadPath(b *testing.B) {
+	for _, strategy := range []LockStrategy{RWMutex, SyncMap, AtomicValue} {
+		b.Run(strategy.String(), func(b *testing.B) {
+			repo := NewRepository(WithLockStrategy(strategy), WithCopyData(false), WithWork(0, 0))
+			defer repo.Close()
+			rec := httptest.NewRecorder()
+			repo.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/seed?n=%d", readPathEntries), nil))
+			if rec.Code != http.StatusOK {
+				b.Fatalf("POST /seed status = %d", rec.Code)
+			}
+
+			var readNs, writeNs, reads, writes, seeds atomic.Int64
+			b.SetParallelism(*directParallelism)
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				rng := rand.New(rand.NewSource(seeds.Add(1)))
+				for pb.Next() {
+					read := rng.Intn(100) < readPathReadPct
+					target := "/process"
+					if read {
+						target = fmt.Sprintf("/data/seed_%d", rng.Intn(readPathEntries))
+					}
+
+					rec := httptest.NewRecorder()
+					req := httptest.NewRequest(http.MethodGet, target, nil)
+					start := time.Now()
+					repo.ServeHTTP(rec, req)
+					elapsed := int64(time.Since(start))
+					if rec.Code != http.StatusOK {
+						b.Errorf("GET %s status %d", target, rec.Code)
+						return
+					}
+					if read {
+						readNs.Add(elapsed)
+						reads.Add(1)
+					} else {
+						writeNs.Add(elapsed)
+						writes.Add(1)
+					}
+				}
+			})
+			b.StopTimer()
+
+			if n := reads.Load(); n > 0 {
+				b.ReportMetric(float64(readNs.Load())/float64(n), "read-ns/op")
+			}
+			if n := writes.Load(); n > 0 {
+				b.ReportMetric(float64(writeNs.Load())/float64(n), "write-ns/op")
+			}
+			if total := reads.Load() + writes.Load(); total > 0 {
+				b.ReportMetric(float64(reads.Load())/float64(total)*100, "read-%")
+			}
+		})
+	}
+}
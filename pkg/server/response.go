@@ -0,0 +1,492 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// requestIDHeader porte l'identifiant de corrélation d'une requête, reçu du client ou généré
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen borne la longueur d'un identifiant fourni par le client
+const maxRequestIDLen = 128
+
+// requestIDKey est la clé de contexte de l'identifiant posé par requestIDMW
+type requestIDKey struct{}
+
+/*
+errorResponse est le corps JSON de toutes les réponses d'erreur.
+
+@fields:
+  - SchemaVersion: Version du contrat des réponses (SchemaVersion)
+  - Error: Message décrivant l'erreur
+  - Status: Code HTTP, répété dans le corps pour les clients qui ne lisent que le JSON
+*/
+type errorResponse struct {
+	SchemaVersion int    `json:"schema_version"`
+	Error         string `json:"error"`
+	Status        int    `json:"status"`
+}
+
+// maxPooledBuffer borne la capacité d'un buffer rendu à responsePool (une réponse /data avec gros payloads ne reste pas en mémoire)
+const maxPooledBuffer = 64 << 10
+
+/*
+responseBuffer est un buffer de réponse réutilisable, avec l'encodeur qui
+écrit dedans : ni l'un ni l'autre n'est réalloué d'une requête à l'autre.
+
+@fields:
+  - buf: Corps encodé de la réponse en cours
+  - enc: Encodeur JSON lié à buf
+*/
+type responseBuffer struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+// responsePool fournit les responseBuffer de writeJSON
+var responsePool = sync.Pool{
+	New: func() any {
+		b := &responseBuffer{}
+		b.enc = json.NewEncoder(&b.buf)
+		return b
+	},
+}
+
+/*
+getResponseBuffer prend un responseBuffer vide dans responsePool.
+
+@returns: *responseBuffer - Buffer à rendre avec putResponseBuffer
+*/
+func getResponseBuffer() *responseBuffer {
+	return responsePool.Get().(*responseBuffer)
+}
+
+/*
+putResponseBuffer vide b et le rend à responsePool, sauf s'il a trop grossi :
+garder un buffer de plusieurs Mo pour des réponses de quelques centaines
+d'octets retiendrait cette mémoire indéfiniment.
+
+@params:
+  - b: *responseBuffer buffer obtenu par getResponseBuffer, à ne plus utiliser ensuite
+*/
+func putResponseBuffer(b *responseBuffer) {
+	if b.buf.Cap() > maxPooledBuffer {
+		return
+	}
+	b.buf.Reset()
+	responsePool.Put(b)
+}
+
+// Write ajoute p au corps : un Encoder écrit dans le buffer comme dans tout io.Writer
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	return b.buf.Write(p)
+}
+
+/*
+writeJSON encode v avec encoding/json et l'envoie avec le code status.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - status: int code HTTP de la réponse
+  - v: any valeur à encoder en JSON
+*/
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	writeEncoded(w, status, v, stdlibEncoder{})
+}
+
+/*
+writeEncoded encode v avec enc et l'envoie avec le code status.
+L'encodage est fait avant d'écrire l'en-tête : en cas d'échec, le client
+reçoit un 500 au lieu d'un 200 au corps tronqué.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - status: int code HTTP de la réponse
+  - v: any valeur à encoder en JSON
+  - enc: Encoder encodeur de la réponse (voir -encoder)
+
+@note: Le corps est encodé dans un buffer de responsePool, rendu une fois
+la réponse écrite : au débit des serveurs good et syncmap, le []byte de
+json.Marshal et sa copie pour le '\n' final étaient alloués à chaque requête.
+*/
+func writeEncoded(w http.ResponseWriter, status int, v any, enc Encoder) {
+	b := getResponseBuffer()
+	defer putResponseBuffer(b)
+	if err := enc.Encode(b, v); err != nil {
+		writeError(w, http.StatusInternalServerError, "encoding response: "+err.Error())
+		return
+	}
+	if wantsText(w) {
+		writeText(w, status, b.buf.Bytes())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(b.buf.Bytes()); err != nil {
+		// L'en-tête est parti : le client s'est probablement déconnecté
+		log.Printf("écriture de la réponse: %v", err)
+	}
+}
+
+/*
+writeError envoie une erreur au format JSON {"schema_version": ..., "error": ..., "status": ...}.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - status: int code HTTP de l'erreur (4xx ou 5xx)
+  - msg: string message destiné au client
+*/
+func writeError(w http.ResponseWriter, status int, msg string) {
+	// errorResponse ne contient que des types encodables : Marshal ne peut pas échouer
+	body, _ := json.Marshal(errorResponse{SchemaVersion: SchemaVersion, Error: msg, Status: status})
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if wantsText(w) {
+		writeText(w, status, body)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+/*
+writeText envoie une réponse déjà encodée en JSON sous forme de lignes
+key=value triées, lisibles avec curl sans jq. Les objets imbriqués et les
+tableaux sont aplatis avec des clés pointées : buckets.0.le_ms=0.01.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - status: int code HTTP de la réponse
+  - body: []byte JSON valide produit par json.Marshal ou un json.Encoder
+*/
+func writeText(w http.ResponseWriter, status int, body []byte) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber() // Garde les nombres tels qu'encodés, sans passer par float64
+	var v any
+	dec.Decode(&v)
+
+	var buf bytes.Buffer
+	flattenText(&buf, "", v)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		log.Printf("écriture de la réponse: %v", err)
+	}
+}
+
+/*
+flattenText écrit v en lignes key=value, les clés d'objet dans l'ordre alphabétique.
+
+@params:
+  - buf: *bytes.Buffer destination
+  - key: string préfixe de clé ("" à la racine)
+  - v: any valeur décodée par encoding/json
+*/
+func flattenText(buf *bytes.Buffer, key string, v any) {
+	join := func(k string) string {
+		if key == "" {
+			return k
+		}
+		return key + "." + k
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			flattenText(buf, join(k), v[k])
+		}
+	case []any:
+		for i, item := range v {
+			flattenText(buf, join(strconv.Itoa(i)), item)
+		}
+	case string:
+		// Une valeur multiligne casserait le format : elle est citée
+		if strings.ContainsAny(v, "\r\n") {
+			v = strconv.Quote(v)
+		}
+		fmt.Fprintf(buf, "%s=%s\n", textKey(key), v)
+	case nil:
+		fmt.Fprintf(buf, "%s=\n", textKey(key))
+	default:
+		fmt.Fprintf(buf, "%s=%v\n", textKey(key), v)
+	}
+}
+
+// textKey nomme "value" une réponse qui n'est pas un objet
+func textKey(key string) string {
+	if key == "" {
+		return "value"
+	}
+	return key
+}
+
+/*
+prefersText indique si un en-tête Accept préfère text/plain à JSON.
+Sans en-tête, avec un joker (tout type) ou à qualité égale, JSON reste le
+format par défaut.
+
+@params:
+  - accept: string valeur de l'en-tête Accept
+
+@returns: bool - true si text/plain a une qualité strictement supérieure à JSON
+*/
+func prefersText(accept string) bool {
+	textQ, jsonQ := 0.0, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(raw, 64); err == nil {
+				q = parsed
+			}
+		}
+		switch mediaType {
+		case "text/plain":
+			textQ = max(textQ, q)
+		case "application/json", "application/*", "*/*":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return textQ > jsonQ
+}
+
+/*
+textResponseWriter marque une réponse pour laquelle le client a demandé
+text/plain. Flush est relayé pour que les flux NDJSON restent possibles.
+*/
+type textResponseWriter struct {
+	http.ResponseWriter
+}
+
+// Flush relaie le Flush du ResponseWriter sous-jacent, s'il en a un
+func (w textResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// wantsText indique si negotiateContent a retenu text/plain pour cette réponse
+func wantsText(w http.ResponseWriter) bool {
+	_, ok := w.(textResponseWriter)
+	return ok
+}
+
+/*
+negotiateContent est le middleware de négociation de contenu des routeurs :
+avec Accept: text/plain, les réponses de writeJSON et writeError sont
+rendues en key=value au lieu de JSON.
+
+@params:
+  - next: http.Handler handler de la route
+
+@returns: http.Handler - Handler négociant le format de réponse
+*/
+func negotiateContent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		if prefersText(req.Header.Get("Accept")) {
+			w = textResponseWriter{w}
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+/*
+recoverMW est le middleware de récupération des routeurs : une panic d'un
+handler est journalisée avec sa pile et transformée en 500, au lieu de
+couper la connexion du client.
+
+@params:
+  - next: http.Handler handler de la route
+
+@returns: http.Handler - Handler qui récupère les panics
+
+@note: recover ne libère pas un mutex : une panic survenue entre Lock et un
+Unlock explicite (motif du serveur good) laisse le verrou pris, et toutes
+les requêtes suivantes bloquent malgré le 500. Le code qui peut paniquer doit
+rester hors des sections critiques, ou celles-ci doivent différer leur
+Unlock dans une fonction anonyme (serveur scoped). http.ErrAbortHandler est
+relancé, c'est l'abandon volontaire d'une réponse.
+*/
+func recoverMW(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			log.Printf("panic sur %s %s (request_id=%s): %v\n%s", req.Method, req.URL.Path, requestID(req), err, debug.Stack())
+			// Sans effet sur le code si la réponse était déjà commencée (flux NDJSON)
+			writeError(w, http.StatusInternalServerError, "internal error")
+		}()
+		next.ServeHTTP(w, req)
+	})
+}
+
+/*
+validRequestID indique si un identifiant fourni par le client peut être
+repris tel quel : non vide, borné par maxRequestIDLen et limité à l'ASCII
+imprimable, pour qu'il ne puisse pas injecter de lignes dans les logs.
+
+@params:
+  - id: string valeur de l'en-tête X-Request-ID
+
+@returns: bool - true si l'identifiant est repris, false s'il faut en générer un
+*/
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+requestIDMW est le middleware de corrélation des routeurs : l'en-tête
+X-Request-ID du client est repris, ou un UUID est généré s'il est absent ou
+invalide. L'identifiant est renvoyé dans l'en-tête de la réponse et placé
+dans le contexte de la requête, où les handlers le lisent avec requestID.
+
+@params:
+  - next: http.Handler handler de la route
+
+@returns: http.Handler - Handler posant l'identifiant de corrélation
+*/
+func requestIDMW(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), requestIDKey{}, id)))
+	})
+}
+
+/*
+requestID retourne l'identifiant de corrélation posé par requestIDMW.
+
+@params:
+  - req: *http.Request requête servie par un routeur newRouter
+
+@returns: string - Identifiant de la requête ("" hors d'un routeur newRouter)
+*/
+func requestID(req *http.Request) string {
+	id, _ := req.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+/*
+statusRecorder note le code HTTP envoyé par un handler pour logRequests.
+Flush est relayé pour que les flux NDJSON restent possibles.
+*/
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader note le code avant de l'envoyer
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write note un 200 implicite si aucun code n'a été envoyé
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush relaie le Flush du ResponseWriter sous-jacent, s'il en a un
+func (w *statusRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+/*
+logRequests journalise chaque requête servie (méthode, chemin, code, durée
+et identifiant de corrélation), activé par -log. L'identifiant est lu dans
+l'en-tête X-Request-ID de la réponse, posé par requestIDMW : la ligne de log
+et la réponse reçue par le client portent la même valeur.
+
+@params:
+  - next: http.Handler handler de la variante (son routeur)
+
+@returns: http.Handler - Handler journalisant ses requêtes
+
+@note: Une ligne de log par requête sérialise les goroutines sur le mutex
+du logger : à activer pour corréler un incident, pas pendant une mesure.
+*/
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, req)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		log.Printf("%s %s %d %s request_id=%s", req.Method, req.URL.Path, rec.status,
+			time.Since(start).Round(time.Microsecond), w.Header().Get(requestIDHeader))
+	})
+}
+
+/*
+newRouter crée un routeur gorilla/mux dont les erreurs de routage (404 et
+405) suivent le même format JSON que celles des handlers. Les routes
+négocient leur format avec l'en-tête Accept (voir negotiateContent), une
+panic d'un handler répond 500 (voir recoverMW), chaque réponse porte un
+identifiant de corrélation X-Request-ID (voir requestIDMW) et la durée de ses
+phases dans Server-Timing (voir serverTimingMW), et les grandes réponses
+sont compressées pour les clients qui acceptent gzip (voir gzipMW).
+
+@returns: *mux.Router - Routeur sans route, à compléter par la variante
+*/
+func newRouter() *mux.Router {
+	router := mux.NewRouter()
+	// recoverMW est interne à negotiateContent : le 500 d'une panic est négocié lui aussi
+	// requestIDMW est le premier : la trace d'une panic journalisée par recoverMW porte l'identifiant.
+	// serverTimingMW et gzipMW précèdent negotiateContent, dont le textResponseWriter doit rester le plus interne
+	router.Use(requestIDMW, serverTimingMW, gzipMW, negotiateContent, recoverMW)
+	// Les middlewares ne s'appliquent qu'aux routes trouvées : les erreurs de routage les appliquent elles-mêmes
+	router.NotFoundHandler = requestIDMW(negotiateContent(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeError(w, http.StatusNotFound, "no route for "+req.URL.Path)
+	})))
+	router.MethodNotAllowedHandler = requestIDMW(negotiateContent(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeError(w, http.StatusMethodNotAllowed, "method "+req.Method+" not allowed on "+req.URL.Path)
+	})))
+	return router
+}
@@ -0,0 +1,414 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+/*
+TestErrorResponses vérifie que les erreurs de routage, de décodage et
+d'encodage renvoient le bon code avec un corps JSON {"error", "status"}.
+*/
+func TestErrorResponses(t *testing.T) {
+	handler := NewGoodRepository(false).Router()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"wrong method", http.MethodPost, "/process", "", http.StatusMethodNotAllowed},
+		{"unknown route", http.MethodGet, "/nope", "", http.StatusNotFound},
+		{"malformed batch", http.MethodPost, "/process/batch", "{", http.StatusBadRequest},
+		{"empty batch", http.MethodPost, "/process/batch", "[]", http.StatusBadRequest},
+		{"unknown field", http.MethodPost, "/process/batch", `[{"nom": "a"}]`, http.StatusBadRequest},
+		{"missing name", http.MethodPost, "/process/batch", `[{"name": ""}]`, http.StatusBadRequest},
+		{"trailing data", http.MethodPost, "/process/batch", `[{"name": "a"}] []`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			var body errorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if body.Status != tt.status || body.Error == "" {
+				t.Errorf("body = %+v, want status %d and a message", body, tt.status)
+			}
+		})
+	}
+
+	t.Run("encoding failure", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		writeJSON(rec, http.StatusOK, map[string]float64{"value": math.NaN()})
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d, want 500", rec.Code)
+		}
+	})
+}
+
+/*
+TestRoutingErrorsAllVariants vérifie sur chaque variante qu'un chemin inconnu
+et une méthode non prévue répondent 404 et 405 au format de writeError, et
+non par la page vide par défaut de gorilla/mux.
+*/
+func TestRoutingErrorsAllVariants(t *testing.T) {
+	opts := Options{CopyData: true, SemaphoreLimit: 2, PoolWorkers: 2, PoolQueue: 4, AdmitBudget: time.Second, Prometheus: true}
+	for _, v := range Variants {
+		t.Run(v.Name, func(t *testing.T) {
+			handler := v.NewHandler(opts)
+			defer handler.(io.Closer).Close()
+
+			for _, c := range []struct {
+				method, path string
+				status       int
+			}{
+				{http.MethodGet, "/nope", http.StatusNotFound},
+				{http.MethodPut, "/process", http.StatusMethodNotAllowed},
+			} {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(c.method, c.path, nil))
+				var body errorResponse
+				if rec.Code != c.status || rec.Header().Get("Content-Type") != "application/json" {
+					t.Errorf("%s %s: status %d, Content-Type %q; want %d, application/json",
+						c.method, c.path, rec.Code, rec.Header().Get("Content-Type"), c.status)
+				} else if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Status != c.status || body.Error == "" {
+					t.Errorf("%s %s: body %+v (%v), want status %d and a message", c.method, c.path, body, err, c.status)
+				}
+				if rec.Header().Get("X-Request-ID") == "" {
+					t.Errorf("%s %s: no X-Request-ID", c.method, c.path)
+				}
+			}
+		})
+	}
+}
+
+/*
+TestPrefersText vérifie la lecture de l'en-tête Accept : text/plain n'est
+retenu que s'il est préféré strictement à JSON.
+*/
+func TestPrefersText(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"text/plain", true},
+		{"text/plain; charset=utf-8", true},
+		{"text/plain, application/json", false},
+		{"application/json;q=0.5, text/plain", true},
+		{"text/plain;q=0.2, */*;q=0.1", true},
+		{"text/plain;q=0.1, */*", false},
+		{"text/html", false},
+	}
+	for _, tt := range tests {
+		if got := prefersText(tt.accept); got != tt.want {
+			t.Errorf("prefersText(%q) = %v, want %v", tt.accept, got, tt.want)
+		}
+	}
+}
+
+/*
+TestTextResponses vérifie qu'avec Accept: text/plain les réponses et les
+erreurs sont rendues en lignes key=value triées, objets imbriqués aplatis.
+*/
+func TestTextResponses(t *testing.T) {
+	handler := NewGoodRepository(false).Router()
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", "text/plain")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+			t.Errorf("GET %s Content-Type = %q, want text/plain", path, ct)
+		}
+		return rec
+	}
+
+	body := get("/stats").Body.String()
+	if !strings.Contains(body, "\ndata_size=0\n") || !strings.HasPrefix(body, "avg_lock_hold_us=") {
+		t.Errorf("/stats body = %q, want sorted key=value lines", body)
+	}
+	if body := get("/debug/lockwait").Body.String(); !strings.Contains(body, "buckets.0.le_ms=0.01\n") {
+		t.Errorf("/debug/lockwait body = %q, want flattened buckets.0.le_ms", body)
+	}
+
+	rec := get("/data/absent")
+	if rec.Code != http.StatusNotFound || rec.Body.String() != "error=data not found: absent\nschema_version=1\nstatus=404\n" {
+		t.Errorf("error = %d %q, want 404 with error, schema_version and status lines", rec.Code, rec.Body.String())
+	}
+	if rec := get("/nope"); rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "status=404\n") {
+		t.Errorf("unknown route = %d %q, want a text 404", rec.Code, rec.Body.String())
+	}
+}
+
+/*
+TestRecoverMW vérifie qu'une panic dans un handler devient un 500 au format
+des erreurs, que sa pile est journalisée, et que la requête suivante aboutit
+quand la section critique diffère son Unlock. http.ErrAbortHandler doit
+être relancé.
+*/
+func TestRecoverMW(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	var mu sync.Mutex
+	router := newRouter()
+	router.HandleFunc("/panic", func(w http.ResponseWriter, req *http.Request) {
+		func() {
+			mu.Lock()
+			defer mu.Unlock()
+			panic("bug in critical section")
+		}()
+	})
+	router.HandleFunc("/abort", func(w http.ResponseWriter, req *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+	router.HandleFunc("/ok", func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	var body errorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("invalid JSON error body: %v", err)
+	}
+	if rec.Code != http.StatusInternalServerError || body.Status != http.StatusInternalServerError {
+		t.Errorf("GET /panic: status = %d, body %+v, want 500", rec.Code, body)
+	}
+	if !strings.Contains(logs.String(), "panic sur GET /panic (request_id=") || !strings.Contains(logs.String(), "): bug in critical section") || !strings.Contains(logs.String(), "goroutine") {
+		t.Errorf("log misses the panic or its stack:\n%s", logs.String())
+	}
+
+	done := make(chan int, 1)
+	go func() {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ok", nil))
+		done <- rec.Code
+	}()
+	select {
+	case code := <-done:
+		if code != http.StatusOK {
+			t.Errorf("GET /ok after panic: status = %d, want 200", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GET /ok after panic blocked: mutex left locked")
+	}
+
+	defer func() {
+		if err := recover(); err != http.ErrAbortHandler {
+			t.Errorf("GET /abort: recovered %v, want http.ErrAbortHandler re-panicked", err)
+		}
+	}()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+}
+
+/*
+TestRequestID vérifie sur chaque variante que /process reprend l'en-tête
+X-Request-ID du client, dans l'en-tête de la réponse comme dans le JSON, et
+génère un UUID s'il est absent ou invalide.
+*/
+func TestRequestID(t *testing.T) {
+	opts := Options{CopyData: true, SemaphoreLimit: 2, PoolWorkers: 2, PoolQueue: 4}
+
+	for _, v := range Variants {
+		t.Run(v.Name, func(t *testing.T) {
+			handler := v.NewHandler(opts)
+			defer handler.(io.Closer).Close()
+
+			process := func(id string) (string, string) {
+				t.Helper()
+				req := httptest.NewRequest(http.MethodGet, "/process?key=k", nil)
+				if id != "" {
+					req.Header.Set(requestIDHeader, id)
+				}
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("GET /process status = %d, want 200: %s", rec.Code, rec.Body)
+				}
+				var body struct {
+					RequestID string `json:"request_id"`
+				}
+				if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+					t.Fatalf("invalid JSON: %v", err)
+				}
+				return rec.Header().Get(requestIDHeader), body.RequestID
+			}
+
+			if header, body := process("trace-42"); header != "trace-42" || body != "trace-42" {
+				t.Errorf("X-Request-ID trace-42: header %q, body %q, want it echoed", header, body)
+			}
+			for _, id := range []string{"", "bad id\r\nforged: line", strings.Repeat("x", maxRequestIDLen+1)} {
+				header, body := process(id)
+				if _, err := uuid.Parse(header); err != nil || body != header {
+					t.Errorf("X-Request-ID %q: header %q, body %q, want a generated UUID in both", id, header, body)
+				}
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if rec.Code != http.StatusNotFound || rec.Header().Get(requestIDHeader) == "" {
+		t.Errorf("GET /missing: status %d, X-Request-ID %q, want 404 with an identifier", rec.Code, rec.Header().Get(requestIDHeader))
+	}
+}
+
+/*
+TestLogRequests vérifie que la ligne de log d'une requête porte son code et
+le même identifiant que la réponse, y compris pour un flux NDJSON qui doit
+rester flushable à travers le middleware.
+*/
+func TestLogRequests(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	router := newRouter()
+	router.HandleFunc("/stream", func(w http.ResponseWriter, req *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("ResponseWriter lost http.Flusher through logRequests")
+		}
+		w.Write([]byte("{}\n"))
+	})
+	handler := logRequests(router)
+
+	for _, c := range []struct {
+		target string
+		want   int
+	}{
+		{"/stream", http.StatusOK},
+		{"/missing", http.StatusNotFound},
+	} {
+		logs.Reset()
+		req := httptest.NewRequest(http.MethodGet, c.target, nil)
+		req.Header.Set(requestIDHeader, "corr-"+strings.TrimPrefix(c.target, "/"))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		want := fmt.Sprintf("GET %s %d ", c.target, c.want)
+		if line := logs.String(); !strings.Contains(line, want) || !strings.HasSuffix(strings.TrimSpace(line), "request_id="+rec.Header().Get(requestIDHeader)) {
+			t.Errorf("log line %q, want %q and request_id=%s", line, want, rec.Header().Get(requestIDHeader))
+		}
+	}
+}
+
+/*
+TestWriteJSONReusesBuffers vérifie qu'un buffer rendu au pool est vidé :
+une petite réponse écrite après une grande, ou en parallèle avec d'autres,
+ne contient que son propre JSON, et un buffer devenu trop grand n'y
+retourne pas.
+*/
+func TestWriteJSONReusesBuffers(t *testing.T) {
+	big := httptest.NewRecorder()
+	writeJSON(big, http.StatusOK, map[string]string{"value": strings.Repeat("x", 4096)})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				rec := httptest.NewRecorder()
+				writeJSON(rec, http.StatusOK, map[string]int{"id": i*1000 + j})
+				if want := fmt.Sprintf("{\"id\":%d}\n", i*1000+j); rec.Body.String() != want {
+					t.Errorf("body = %q, want %q", rec.Body.String(), want)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Un buffer trop grand n'est ni vidé ni rendu : il sera libéré par le GC
+	huge := getResponseBuffer()
+	huge.buf.WriteString(strings.Repeat("x", 2*maxPooledBuffer))
+	putResponseBuffer(huge)
+	if huge.buf.Len() != 2*maxPooledBuffer {
+		t.Errorf("oversized buffer was reset (len %d): it went back to the pool", huge.buf.Len())
+	}
+}
+
+// discardResponse est un http.ResponseWriter sans allocation, pour ne mesurer que l'encodage
+type discardResponse struct{ header http.Header }
+
+func (w *discardResponse) Header() http.Header         { return w.header }
+func (w *discardResponse) WriteHeader(int)             {}
+func (w *discardResponse) Write(b []byte) (int, error) { return len(b), nil }
+
+/*
+BenchmarkWriteJSON compare l'encodage de la réponse de /process du serveur
+good avec json.Marshal (l'ancien writeJSON) et avec les buffers de
+responsePool : allocs/op et B/op baissent, la map de la réponse restant
+allouée par le handler.
+
+	go test ./pkg/server -run '^$' -bench WriteJSON -benchmem
+*/
+func BenchmarkWriteJSON(b *testing.B) {
+	response := map[string]interface{}{
+		"method":        "good_no_defer",
+		"request_id":    "0b6a3b8e-3c69-4a6f-9d3f-5e0b2c7a1d42",
+		"counter":       int64(123456),
+		"result":        71,
+		"snapshot_size": 1000,
+		"duration":      int64(10342),
+	}
+	w := &discardResponse{header: make(http.Header)}
+
+	b.Run("marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				body, err := json.Marshal(response)
+				if err != nil {
+					b.Error(err)
+					return
+				}
+				w.Write(append(body, '\n'))
+			}
+		})
+	})
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				buf := getResponseBuffer()
+				if err := buf.enc.Encode(response); err != nil {
+					b.Error(err)
+					return
+				}
+				w.Write(buf.buf.Bytes())
+				putResponseBuffer(buf)
+			}
+		})
+	})
+}
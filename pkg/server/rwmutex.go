@@ -0,0 +1,278 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+/*
+RWMutexRepository reprend le serveur good avec un sync.RWMutex : les lectures
+(copie des données, GET /data/{id}) prennent le verrou partagé et s'exécutent
+en parallèle, seules les écritures prennent le verrou exclusif.
+
+@fields:
+  - mu: RWMutex protégeant la map (lectures concurrentes, écriture exclusive)
+  - counter: Compteur atomique, pour que la phase de lecture n'ait besoin que du RLock
+  - data: Map simulant des données métier partagées avec structure complexe
+  - copyData: Active la copie des données avant traitement (workload "lecture puis traitement")
+  - mapCap: Nombre maximum d'entrées de data (0 = illimité)
+  - work: Traitement lourd de /process
+  - payload: Taille en octets du blob Payload de chaque entrée écrite, copié sous le verrou de lecture
+  - enc: Encodeur des réponses de /process (voir -encoder)
+*/
+type RWMutexRepository struct {
+	mu       sync.RWMutex
+	counter  atomic.Int64
+	data     map[string]*DataStruct
+	copyData bool
+	mapCap   int
+	work     WorkFunc
+	payload  int
+	enc      Encoder
+}
+
+/*
+NewRWMutexRepository crée et initialise un nouveau repository.
+
+@params:
+  - copyData: bool active la copie des données partagées à chaque requête
+
+@returns: *RWMutexRepository - Nouvelle instance avec la map initialisée
+*/
+func NewRWMutexRepository(copyData bool) *RWMutexRepository {
+	return newRWMutexRepository(newRepoOptions(WithCopyData(copyData)))
+}
+
+/*
+newRWMutexRepository crée un repository à partir d'options déjà appliquées.
+
+@params:
+  - o: repoOptions configuration (copie, borne de la map, traitement, payload, encodeur)
+
+@returns: *RWMutexRepository - Nouvelle instance avec la map initialisée
+*/
+func newRWMutexRepository(o repoOptions) *RWMutexRepository {
+	return &RWMutexRepository{
+		data:     make(map[string]*DataStruct),
+		copyData: o.copyData,
+		mapCap:   o.mapCap,
+		work:     o.work,
+		payload:  o.payload,
+		enc:      o.encoder,
+	}
+}
+
+/*
+RWMutexHandler applique la bonne pratique du serveur good avec un RWMutex.
+La copie des données ne fait que lire la map : elle se fait sous RLock, en
+parallèle avec les autres lectures.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@behavior:
+  1. Incrémente le compteur atomiquement
+  2. RLock pour la lecture/copie des données, RUnlock immédiat
+  3. Effectue le traitement lourd SANS verrou
+  4. Lock exclusif uniquement pour l'écriture finale
+
+@performance: Avantage sur le serveur good quand les lectures dominent
+*/
+func (r *RWMutexRepository) RWMutexHandler(w http.ResponseWriter, req *http.Request) {
+	includeData, ok := parseIncludeData(w, req)
+	if !ok {
+		return
+	}
+	start := time.Now()
+	currentCounter := int(r.counter.Add(1))
+	ctx, span := startSpan(req.Context(), "rwmutex.process")
+	defer span.End()
+
+	// Lecture sous verrou partagé
+	tracedLock(ctx, "mu.read", r.mu.RLock)
+	// La copie modélise un workload "lecture puis traitement" : son coût
+	// croît avec la taille de la map, elle est donc désactivable (-copy=false)
+	dataCopy := make(map[string]*DataStruct)
+	if r.copyData {
+		for k, v := range r.data {
+			dataCopy[k] = &DataStruct{
+				Identifier:   v.Identifier,
+				Name:         v.Name,
+				IsActive:     v.IsActive,
+				Counter:      v.Counter,
+				LastModified: v.LastModified,
+				Payload:      bytes.Clone(v.Payload),
+			}
+		}
+	}
+	r.mu.RUnlock() // Libération immédiate après la lecture
+
+	// Traitement lourd SANS verrou
+	heavySpan := startPhase(ctx, "process.heavy")
+	result := r.work(ctx) // Attente simulée puis calcul intensif
+	heavySpan.End()
+
+	// Verrou exclusif uniquement pour l'écriture
+	key := fmt.Sprintf("request_%d", currentCounter)
+	payload := newPayload(r.payload) // Allouée avant le verrou exclusif
+	writeSpan := startPhase(ctx, "write")
+	tracedLock(ctx, "mu.write", r.mu.Lock)
+	makeRoom(r.data, key, r.mapCap)
+	r.data[key] = &DataStruct{
+		Identifier:   key,
+		Name:         fmt.Sprintf("Request %d", currentCounter),
+		IsActive:     true,
+		Counter:      result,
+		LastModified: time.Now(),
+		Payload:      payload,
+	}
+	r.mu.Unlock() // Libération immédiate après l'écriture
+	writeSpan.End()
+
+	response := newResponse(req, "rwmutex", start)
+	response.Counter = currentCounter
+	response.Result = result
+	response.SnapshotSize = len(dataCopy)
+
+	if includeData {
+		addSnapshot(response, dataCopy)
+	}
+	writeEncoded(w, http.StatusOK, response, r.enc)
+}
+
+/*
+seed ajoute n entrées synthétiques (seed_0 à seed_n-1) sous le verrou
+exclusif, en une seule section critique.
+
+@params:
+  - n: int nombre d'entrées à insérer
+*/
+func (r *RWMutexRepository) seed(n int) {
+	r.mu.Lock()
+	for i := 0; i < n; i++ {
+		entry := seedEntry(i, r.payload)
+		makeRoom(r.data, entry.Identifier, r.mapCap)
+		r.data[entry.Identifier] = entry
+	}
+	r.mu.Unlock()
+}
+
+/*
+evictExpired supprime les entrées dont LastModified précède cutoff.
+Appelée par le janitor, sous le verrou exclusif le temps du seul balayage.
+
+@params:
+  - cutoff: time.Time date limite de dernière modification
+
+@returns: int - Nombre d'entrées supprimées
+*/
+func (r *RWMutexRepository) evictExpired(cutoff time.Time) int {
+	r.mu.Lock()
+	evicted := 0
+	for key, entry := range r.data {
+		if entry.LastModified.Before(cutoff) {
+			delete(r.data, key)
+			evicted++
+		}
+	}
+	r.mu.Unlock()
+	return evicted
+}
+
+/*
+GetDataHandler retourne l'entrée {id} en JSON, ou 404 si elle est absente.
+Le verrou partagé laisse les lectures concurrentes s'exécuter en parallèle.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+
+@returns: JSON DataStruct
+*/
+func (r *RWMutexRepository) GetDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.mu.RLock()
+	var entry DataStruct
+	v, found := r.data[id]
+	if found {
+		entry = *v
+	}
+	r.mu.RUnlock()
+
+	writeData(w, id, entry, found)
+}
+
+/*
+DeleteDataHandler supprime l'entrée {id} : 204 si supprimée, 404 si absente.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+*/
+func (r *RWMutexRepository) DeleteDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.mu.Lock()
+	_, found := r.data[id]
+	delete(r.data, id)
+	r.mu.Unlock()
+
+	writeDeleted(w, id, found)
+}
+
+/*
+StatsHandler retourne les statistiques actuelles du serveur.
+La taille de la map est lue sous le verrou partagé.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON contenant total_requests et data_size
+*/
+func (r *RWMutexRepository) StatsHandler(w http.ResponseWriter, req *http.Request) {
+	r.mu.RLock()
+	dataSize := len(r.data)
+	r.mu.RUnlock()
+
+	stats := map[string]interface{}{
+		"total_requests": r.counter.Load(),
+		"data_size":      dataSize,
+	}
+
+	writeStats(w, stats)
+}
+
+/*
+Router configure les routes du serveur avec gorilla/mux.
+
+@returns: *mux.Router - Routeur prêt à être servi
+
+@endpoints:
+  - GET /process : Lecture sous RLock, écriture sous Lock
+  - GET /data/{id} : Lecture d'une entrée sous RLock (404 si absente)
+  - DELETE /data/{id} : Suppression d'une entrée
+  - GET /stats : Statistiques du serveur
+  - GET /debug/allocs : Compteurs d'allocation du processus
+  - GET /debug/contention : Résumé du profil de contention des mutex
+  - POST /seed?n=N : Pré-remplissage avec N entrées synthétiques
+*/
+func (r *RWMutexRepository) Router() *mux.Router {
+	router := newRouter()
+	router.HandleFunc("/process", r.RWMutexHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.GetDataHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.DeleteDataHandler).Methods("DELETE")
+	router.HandleFunc("/stats", r.StatsHandler).Methods("GET")
+	router.HandleFunc("/debug/allocs", AllocsHandler).Methods("GET")
+	router.HandleFunc("/debug/contention", ContentionHandler).Methods("GET")
+	router.HandleFunc("/seed", seedHandler(r.seed)).Methods("POST")
+	return router
+}
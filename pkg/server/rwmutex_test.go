@@ -0,0 +1,80 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+TestRWMutexWriterNotStarved maintient le RWMutex du serveur rwmutex en
+lecture en continu, avec des lecteurs qui se chevauchent, puis vérifie qu'un
+écrivain obtient le verrou rapidement.
+
+Sans préférence pour les écrivains, il y aurait toujours un lecteur actif et
+l'écrivain attendrait indéfiniment. sync.RWMutex bloque les nouveaux RLock dès
+qu'un Lock est en attente : l'écrivain passe après les lectures en cours.
+*/
+func TestRWMutexWriterNotStarved(t *testing.T) {
+	const (
+		readers   = 16
+		readHold  = 5 * time.Millisecond
+		maxWriter = time.Second
+	)
+
+	repo := NewRWMutexRepository(false)
+	handler := repo.Router()
+
+	holding := make(chan struct{}, readers)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func(offset time.Duration) {
+			defer wg.Done()
+			// Départs décalés : les périodes de lecture se chevauchent
+			time.Sleep(offset)
+			signaled := false
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				repo.mu.RLock()
+				if !signaled {
+					holding <- struct{}{}
+					signaled = true
+				}
+				time.Sleep(readHold)
+				repo.mu.RUnlock()
+			}
+		}(time.Duration(i) * readHold / readers)
+	}
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+
+	// Attendre que tous les lecteurs aient tenu le RLock au moins une fois
+	for i := 0; i < readers; i++ {
+		<-holding
+	}
+
+	done := make(chan time.Duration, 1)
+	go func() {
+		start := time.Now()
+		// DELETE prend le Lock exclusif
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/data/absent", nil))
+		done <- time.Since(start)
+	}()
+
+	select {
+	case waited := <-done:
+		t.Logf("writer acquired the lock after %v with %d overlapping readers", waited, readers)
+	case <-time.After(maxWriter):
+		t.Fatalf("writer still blocked after %v: starved by readers", maxWriter)
+	}
+}
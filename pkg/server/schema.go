@@ -0,0 +1,97 @@
+package server
+
+import (
+	"net/http"
+	"time"
+)
+
+// SchemaVersion est la version du contrat des réponses (ResponseV1, erreurs, /stats), incrémentée à chaque changement incompatible
+const SchemaVersion = 1
+
+/*
+ResponseV1 est le corps JSON commun des réponses de traitement de toutes
+les variantes : /process, /process/batch, /process/upstream, /snapshot et
+la dernière ligne de /process/stream.
+Les serveurs mutex et sync.Map renvoient donc la même structure, et un
+client la décode sans savoir quelle variante lui répond.
+
+Les champs du socle sont toujours présents ; les champs propres à une route
+ou à une variante sont omis quand ils ne s'appliquent pas. Ajouter un champ
+omis par défaut reste compatible ; renommer, retirer ou changer le type d'un
+champ impose d'incrémenter SchemaVersion.
+
+@fields:
+  - SchemaVersion: Version du contrat (SchemaVersion)
+  - Method: Variante et route ayant répondu (bad_defer, good_no_defer_batch, sync_map, ...)
+  - RequestID: Identifiant de corrélation (en-tête X-Request-ID)
+  - Counter: Valeur du compteur de requêtes attribuée à la requête (0 pour un lot, voir Counters)
+  - Result: Résultat du traitement lourd (0 si la route n'en fait pas)
+  - SnapshotSize: Nombre d'entrées de la copie des données (0 sans copie)
+  - Duration: Durée de traitement côté serveur (µs)
+  - Key: Clé du cache lue (dcl, singleflight)
+  - Cached: La clé était déjà en cache (dcl)
+  - Shared: Le calcul a été partagé avec une requête concurrente (singleflight)
+  - BatchSize: Nombre d'éléments du lot (/process/batch)
+  - Counters: Compteur attribué à chaque élément du lot (/process/batch)
+  - UpstreamStatus: Code HTTP renvoyé par l'upstream (/process/upstream)
+  - UpstreamBytes: Octets du corps de l'upstream (/process/upstream)
+  - Data: Copie des données, voir addSnapshot (includeData, /snapshot)
+  - DataTruncated: Data compte moins d'entrées que SnapshotSize (includeData, /snapshot)
+  - Done: Marque la dernière ligne du flux (/process/stream)
+*/
+type ResponseV1 struct {
+	SchemaVersion int    `json:"schema_version"`
+	Method        string `json:"method"`
+	RequestID     string `json:"request_id"`
+	Counter       int    `json:"counter"`
+	Result        int    `json:"result"`
+	SnapshotSize  int    `json:"snapshot_size"`
+	Duration      int64  `json:"duration"`
+
+	Key            string        `json:"key,omitempty"`
+	Cached         *bool         `json:"cached,omitempty"`
+	Shared         *bool         `json:"shared,omitempty"`
+	BatchSize      int           `json:"batch_size,omitempty"`
+	Counters       []int         `json:"counters,omitempty"`
+	UpstreamStatus int           `json:"upstream_status,omitempty"`
+	UpstreamBytes  int64         `json:"upstream_bytes,omitempty"`
+	Data           []*DataStruct `json:"data,omitempty"`
+	DataTruncated  *bool         `json:"data_truncated,omitempty"`
+	Done           bool          `json:"done,omitempty"`
+}
+
+/*
+newResponse construit la réponse de traitement d'une requête, avec le socle
+renseigné : version, méthode, identifiant et durée écoulée depuis start. À
+appeler juste avant d'écrire la réponse, pour que la durée couvre tout le
+traitement.
+
+@params:
+  - req: *http.Request requête traitée (porte l'identifiant de requestIDMW)
+  - method: string valeur du champ method
+  - start: time.Time début du traitement
+
+@returns: *ResponseV1 - Réponse à compléter par le handler
+*/
+func newResponse(req *http.Request, method string, start time.Time) *ResponseV1 {
+	return &ResponseV1{
+		SchemaVersion: SchemaVersion,
+		Method:        method,
+		RequestID:     requestID(req),
+		Duration:      time.Since(start).Microseconds(),
+	}
+}
+
+/*
+writeStats envoie les statistiques d'une variante (/stats) avec le champ
+schema_version : leur contenu varie d'une variante à l'autre, mais leur
+version suit celle des réponses.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - stats: map[string]interface{} statistiques de la variante
+*/
+func writeStats(w http.ResponseWriter, stats map[string]interface{}) {
+	stats["schema_version"] = SchemaVersion
+	writeJSON(w, http.StatusOK, stats)
+}
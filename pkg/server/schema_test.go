@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+/*
+TestResponseSchemaAllVariants vérifie le contrat sur chaque variante : la
+réponse de /process se décode dans ResponseV1 sans champ inconnu et porte
+le socle de champs, comme /stats et les erreurs portent schema_version.
+*/
+func TestResponseSchemaAllVariants(t *testing.T) {
+	opts := Options{CopyData: true, SemaphoreLimit: 2, PoolWorkers: 2, PoolQueue: 4, AdmitBudget: time.Second}
+	core := []string{"schema_version", "method", "request_id", "counter", "result", "snapshot_size", "duration"}
+	for _, v := range Variants {
+		t.Run(v.Name, func(t *testing.T) {
+			handler := v.NewHandler(opts)
+			defer handler.(io.Closer).Close()
+			get := func(path string) *httptest.ResponseRecorder {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+				return rec
+			}
+
+			rec := get("/process")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("/process status = %d, want 200", rec.Code)
+			}
+			raw := rec.Body.Bytes()
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &fields); err != nil {
+				t.Fatalf("/process: invalid JSON %q: %v", raw, err)
+			}
+			for _, key := range core {
+				if _, ok := fields[key]; !ok {
+					t.Errorf("/process: no %q field in %s", key, raw)
+				}
+			}
+			dec := json.NewDecoder(rec.Body)
+			dec.DisallowUnknownFields()
+			var resp ResponseV1
+			if err := dec.Decode(&resp); err != nil {
+				t.Errorf("/process does not match ResponseV1: %v", err)
+			}
+			if resp.SchemaVersion != SchemaVersion || resp.Method == "" || resp.RequestID != rec.Header().Get(requestIDHeader) {
+				t.Errorf("/process = %+v, want schema_version %d, a method and the X-Request-ID", resp, SchemaVersion)
+			}
+
+			for _, path := range []string{"/stats", "/nope"} {
+				var body struct {
+					SchemaVersion int `json:"schema_version"`
+				}
+				if err := json.NewDecoder(get(path).Body).Decode(&body); err != nil || body.SchemaVersion != SchemaVersion {
+					t.Errorf("%s: schema_version = %d (%v), want %d", path, body.SchemaVersion, err, SchemaVersion)
+				}
+			}
+		})
+	}
+}
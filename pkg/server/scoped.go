@@ -0,0 +1,293 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+/*
+ScopedRepository reprend le serveur good, mais chaque section critique est
+une fonction anonyme qui verrouille avec defer :
+
+	func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		// ... section critique ...
+	}()
+
+Le defer s'exécute à la sortie de la fonction anonyme, pas du handler : le
+mutex est libéré aussi tôt qu'avec un Unlock explicite, et reste libéré même
+si la section critique panique ou retourne plus tôt.
+
+@fields:
+  - mu: Mutex pour protéger l'accès concurrent aux données
+  - counter: Compteur global des requêtes traitées
+  - data: Map simulant des données métier partagées avec structure complexe
+  - copyData: Active la copie des données avant traitement (workload "lecture puis traitement")
+  - work: Traitement lourd de /process, hors mutex
+  - mapCap: Nombre maximum d'entrées de data (0 = illimité)
+  - payload: Taille en octets du blob Payload de chaque entrée écrite
+  - enc: Encodeur des réponses de /process (voir -encoder)
+  - lockHold: Durées de détention du mutex sur /process
+*/
+type ScopedRepository struct {
+	mu       sync.Mutex
+	counter  int
+	data     map[string]*DataStruct
+	copyData bool
+	work     WorkFunc
+	mapCap   int
+	payload  int
+	enc      Encoder
+	lockHold lockHoldStats
+}
+
+/*
+NewScopedRepository crée et initialise un nouveau repository.
+
+@params:
+  - copyData: bool active la copie des données partagées à chaque requête
+
+@returns: *ScopedRepository - Nouvelle instance avec la map initialisée
+*/
+func NewScopedRepository(copyData bool) *ScopedRepository {
+	return newScopedRepository(newRepoOptions(WithCopyData(copyData)))
+}
+
+/*
+newScopedRepository crée le repository à partir d'options déjà appliquées,
+pour comparer ses sections critiques à celles du serveur good à traitement
+identique.
+
+@params:
+  - o: repoOptions configuration (copie, borne de la map, traitement, payload, encodeur)
+
+@returns: *ScopedRepository - Nouvelle instance avec la map initialisée
+*/
+func newScopedRepository(o repoOptions) *ScopedRepository {
+	return &ScopedRepository{
+		data:     make(map[string]*DataStruct),
+		copyData: o.copyData,
+		work:     o.work,
+		mapCap:   o.mapCap,
+		payload:  o.payload,
+		enc:      o.encoder,
+	}
+}
+
+/*
+ScopedHandler a le même découpage que GoodHandler, avec des sections
+critiques délimitées par des fonctions anonymes.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@behavior:
+  1. Fonction anonyme : Lock, defer Unlock, lecture/copie des données
+  2. Traitement lourd SANS le mutex, hors de toute fonction anonyme
+  3. Fonction anonyme : Lock, defer Unlock, écriture finale
+
+@performance: Même throughput que le serveur good : defer n'est pas en cause
+quand la portée verrouillée est petite
+*/
+func (r *ScopedRepository) ScopedHandler(w http.ResponseWriter, req *http.Request) {
+	includeData, ok := parseIncludeData(w, req)
+	if !ok {
+		return
+	}
+	start := time.Now()
+	ctx, span := startSpan(req.Context(), "scoped.process")
+	defer span.End()
+
+	var currentCounter int
+	dataCopy := make(map[string]*DataStruct)
+	func() {
+		tracedLock(ctx, "mu.read", r.mu.Lock)
+		defer r.mu.Unlock()
+		// Les defer s'exécutent en ordre inverse : la mesure précède le Unlock
+		lockStart := time.Now()
+		defer func() { r.lockHold.record(time.Since(lockStart)) }()
+
+		r.counter++
+		currentCounter = r.counter
+		// La copie modélise un workload "lecture puis traitement" : son coût
+		// croît avec la taille de la map, elle est donc désactivable (-copy=false)
+		if r.copyData {
+			for k, v := range r.data {
+				dataCopy[k] = &DataStruct{
+					Identifier:   v.Identifier,
+					Name:         v.Name,
+					IsActive:     v.IsActive,
+					Counter:      v.Counter,
+					LastModified: v.LastModified,
+					Payload:      bytes.Clone(v.Payload),
+				}
+			}
+		}
+	}() // Mutex libéré ici, à la sortie de la fonction anonyme
+
+	// Traitement lourd SANS le mutex
+	heavySpan := startPhase(ctx, "process.heavy")
+	result := r.work(ctx) // Attente simulée puis calcul intensif
+	heavySpan.End()
+
+	key := fmt.Sprintf("request_%d", currentCounter)
+	payload := newPayload(r.payload) // Allouée avant de reprendre le mutex
+	writeSpan := startPhase(ctx, "write")
+	func() {
+		tracedLock(ctx, "mu.write", r.mu.Lock)
+		defer r.mu.Unlock()
+		lockStart := time.Now()
+		defer func() { r.lockHold.record(time.Since(lockStart)) }()
+
+		makeRoom(r.data, key, r.mapCap)
+		r.data[key] = &DataStruct{
+			Identifier:   key,
+			Name:         fmt.Sprintf("Request %d", currentCounter),
+			IsActive:     true,
+			Counter:      result,
+			LastModified: time.Now(),
+			Payload:      payload,
+		}
+	}()
+	writeSpan.End()
+
+	response := newResponse(req, "scoped_defer", start)
+	response.Counter = currentCounter
+	response.Result = result
+	response.SnapshotSize = len(dataCopy)
+
+	if includeData {
+		addSnapshot(response, dataCopy)
+	}
+	writeEncoded(w, http.StatusOK, response, r.enc)
+}
+
+/*
+evictExpired supprime les entrées dont LastModified précède cutoff.
+Le balayage est la seule section critique : defer en fin de méthode suffit.
+
+@params:
+  - cutoff: time.Time date limite de dernière modification
+
+@returns: int - Nombre d'entrées supprimées
+*/
+func (r *ScopedRepository) evictExpired(cutoff time.Time) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	evicted := 0
+	for key, entry := range r.data {
+		if entry.LastModified.Before(cutoff) {
+			delete(r.data, key)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+/*
+GetDataHandler retourne l'entrée {id} en JSON, ou 404 si elle est absente.
+La copie se fait dans une fonction anonyme, l'encodage après le Unlock.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+
+@returns: JSON DataStruct
+*/
+func (r *ScopedRepository) GetDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	var entry DataStruct
+	var found bool
+	func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		var v *DataStruct
+		if v, found = r.data[id]; found {
+			entry = *v
+		}
+	}()
+
+	writeData(w, id, entry, found)
+}
+
+/*
+DeleteDataHandler supprime l'entrée {id} : 204 si supprimée, 404 si absente.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+*/
+func (r *ScopedRepository) DeleteDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	var found bool
+	func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		_, found = r.data[id]
+		delete(r.data, id)
+	}()
+
+	writeDeleted(w, id, found)
+}
+
+/*
+StatsHandler retourne les statistiques actuelles du serveur.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON contenant total_requests, data_size, avg_lock_hold_us et max_lock_hold_us
+*/
+func (r *ScopedRepository) StatsHandler(w http.ResponseWriter, req *http.Request) {
+	avgHold, maxHold := r.lockHold.snapshot()
+
+	var counter, dataSize int
+	func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		counter, dataSize = r.counter, len(r.data)
+	}()
+
+	stats := map[string]interface{}{
+		"total_requests":   counter,
+		"data_size":        dataSize,
+		"avg_lock_hold_us": avgHold,
+		"max_lock_hold_us": maxHold,
+	}
+
+	writeStats(w, stats)
+}
+
+/*
+Router configure les routes du serveur avec gorilla/mux.
+
+@returns: *mux.Router - Routeur prêt à être servi
+
+@endpoints:
+  - GET /process : Sections critiques délimitées par des fonctions anonymes avec defer
+  - GET /data/{id} : Lecture d'une entrée (404 si absente)
+  - DELETE /data/{id} : Suppression d'une entrée
+  - GET /stats : Statistiques du serveur
+  - GET /debug/allocs : Compteurs d'allocation du processus
+  - GET /debug/contention : Résumé du profil de contention des mutex
+*/
+func (r *ScopedRepository) Router() *mux.Router {
+	router := newRouter()
+	router.HandleFunc("/process", r.ScopedHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.GetDataHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.DeleteDataHandler).Methods("DELETE")
+	router.HandleFunc("/stats", r.StatsHandler).Methods("GET")
+	router.HandleFunc("/debug/allocs", AllocsHandler).Methods("GET")
+	router.HandleFunc("/debug/contention", ContentionHandler).Methods("GET")
+	return router
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"mutex-benchmark/pkg/latency"
+)
+
+// benchPath est la route ajoutée par -selfBench à chaque serveur
+const benchPath = "/bench"
+
+// Valeurs par défaut et bornes des paramètres de /bench : le serveur se charge lui-même, la charge reste petite
+const (
+	defaultBenchConcurrency = 10
+	defaultBenchRequests    = 100
+	maxBenchConcurrency     = 256
+	maxBenchRequests        = 10000
+)
+
+/*
+benchResponse est le corps JSON de /bench : la mesure de latency.Measure
+sur le /process du serveur.
+
+@fields:
+  - SchemaVersion: Version du contrat des réponses (SchemaVersion)
+  - Concurrency: Requêtes en vol pendant la mesure
+  - Requests: Requêtes envoyées
+  - Success, Rejected, Failed: Répartition des requêtes (voir latency.Stats)
+  - AvgMs, P95Ms, P99Ms: Latence moyenne et percentiles des requêtes réussies (ms)
+  - ReqPerSec: Requêtes réussies par seconde
+  - ElapsedMs: Durée totale de la mesure (ms)
+*/
+type benchResponse struct {
+	SchemaVersion int     `json:"schema_version"`
+	Concurrency   int     `json:"concurrency"`
+	Requests      int     `json:"requests"`
+	Success       int     `json:"success"`
+	Rejected      int     `json:"rejected"`
+	Failed        int     `json:"failed"`
+	AvgMs         float64 `json:"avg_ms"`
+	P95Ms         float64 `json:"p95_ms"`
+	P99Ms         float64 `json:"p99_ms"`
+	ReqPerSec     float64 `json:"req_per_sec"`
+	ElapsedMs     float64 `json:"elapsed_ms"`
+}
+
+/*
+benchParam lit un paramètre entier de /bench.
+
+@params:
+  - req: *http.Request requête /bench
+  - name: string nom du paramètre
+  - def: int valeur si le paramètre est absent
+  - limit: int valeur maximum acceptée
+
+@returns: (int, error) - Valeur dans [1, limit], ou erreur destinée au client
+*/
+func benchParam(req *http.Request, name string, def, limit int) (int, error) {
+	raw := req.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > limit {
+		return 0, fmt.Errorf("%s must be an integer between 1 and %d", name, limit)
+	}
+	return n, nil
+}
+
+/*
+withSelfBench ajoute GET /bench?concurrency=C&n=N devant le handler d'une
+variante (flag -selfBench) : le serveur envoie N requêtes, C en vol, à son
+propre /process et renvoie la mesure, pour évaluer une instance déployée
+sans lancer le harnais de test. Les autres requêtes passent au handler.
+
+@params:
+  - next: http.Handler handler de la variante (son routeur)
+
+@returns: http.Handler - Handler servant aussi /bench
+
+@behavior:
+  - concurrency (défaut 10, au plus 256) et n (défaut 100 ou concurrency, au plus 10000) : 400 hors bornes ou si n < concurrency
+  - Une seule mesure à la fois : une seconde requête /bench reçoit un 429
+  - La charge passe par un listener local, sur le même handler : elle
+    partage la map et le mutex du trafic réel, et se mesure comme un client
+    HTTP, connexions et encodage compris
+  - L'annulation de la requête /bench arrête la mesure
+
+@note: La mesure charge le serveur qu'elle mesure : le trafic réel ralentit
+pendant la mesure, et il fausse la mesure en retour. Le flag est désactivé
+par défaut.
+*/
+func withSelfBench(next http.Handler) http.Handler {
+	var running atomic.Bool
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != benchPath || req.Method != http.MethodGet {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		concurrency, err := benchParam(req, "concurrency", defaultBenchConcurrency, maxBenchConcurrency)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		n, err := benchParam(req, "n", max(defaultBenchRequests, concurrency), maxBenchRequests)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if n < concurrency {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("n (%d) must be at least concurrency (%d)", n, concurrency))
+			return
+		}
+		if !running.CompareAndSwap(false, true) {
+			writeError(w, http.StatusTooManyRequests, "a /bench run is already in progress")
+			return
+		}
+		defer running.Store(false)
+
+		// Listener local sur le handler de la variante, sans /bench : la charge ne peut pas relancer une mesure
+		target := httptest.NewServer(next)
+		defer target.Close()
+		stats := latency.MeasureContext(req.Context(), target.URL+"/process", concurrency, n, 0)
+
+		writeJSON(w, http.StatusOK, benchResponse{
+			SchemaVersion: SchemaVersion,
+			Concurrency:   concurrency,
+			Requests:      n,
+			Success:       stats.Success,
+			Rejected:      stats.Rejected,
+			Failed:        stats.Failed,
+			AvgMs:         stats.AvgMs,
+			P95Ms:         stats.P95Ms,
+			P99Ms:         stats.P99Ms,
+			ReqPerSec:     stats.ReqPerSec(),
+			ElapsedMs:     float64(stats.Elapsed) / float64(time.Millisecond),
+		})
+	})
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+/*
+TestSelfBench vérifie /bench sur le serveur good : la mesure envoie les n
+requêtes demandées au /process du même repository (son compteur avance
+d'autant), les paramètres hors bornes donnent un 400 et les autres routes
+passent au handler.
+*/
+func TestSelfBench(t *testing.T) {
+	repo := NewGoodRepository(false)
+	handler := withSelfBench(repo.Router())
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := get("/bench?concurrency=4&n=12")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/bench status = %d (%s), want 200", rec.Code, rec.Body.String())
+	}
+	var got benchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.SchemaVersion != SchemaVersion || got.Concurrency != 4 || got.Requests != 12 || got.Success != 12 {
+		t.Errorf("/bench = %+v, want concurrency 4 and 12 successful requests", got)
+	}
+	if got.AvgMs <= 0 || got.P99Ms < got.AvgMs || got.ReqPerSec <= 0 {
+		t.Errorf("/bench latency = %+v, want positive latencies and throughput", got)
+	}
+	if repo.counter != 12 {
+		t.Errorf("repository counter = %d after /bench, want 12", repo.counter)
+	}
+
+	for _, target := range []string{"/bench?concurrency=0", "/bench?n=abc", "/bench?concurrency=8&n=4", "/bench?n=10001"} {
+		if rec := get(target); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s status = %d, want 400", target, rec.Code)
+		}
+	}
+	if rec := get("/stats"); rec.Code != http.StatusOK {
+		t.Errorf("/stats through withSelfBench status = %d, want 200", rec.Code)
+	}
+}
+
+// TestSelfBenchSingleRun vérifie qu'une seconde mesure lancée pendant la première reçoit un 429
+func TestSelfBenchSingleRun(t *testing.T) {
+	arrived := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	handler := withSelfBench(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		once.Do(func() { close(arrived) })
+		<-release
+	}))
+
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bench?concurrency=1&n=1", nil))
+		done <- rec.Code
+	}()
+	<-arrived
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bench", nil))
+	close(release)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("concurrent /bench status = %d, want 429", rec.Code)
+	}
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("first /bench status = %d, want 200", code)
+	}
+}
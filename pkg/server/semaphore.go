@@ -0,0 +1,275 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/sync/semaphore"
+)
+
+/*
+SemaphoreRepository contient les données partagées protégées par un mutex,
+avec un sémaphore pondéré qui borne le nombre de traitements lourds simultanés.
+
+@fields:
+  - mu: Mutex pour protéger l'accès concurrent aux données
+  - counter: Compteur global des requêtes traitées
+  - data: Map simulant des données métier partagées avec structure complexe
+  - copyData: Active la copie des données avant traitement (workload "lecture puis traitement")
+  - work: Traitement lourd borné par le sémaphore, hors mutex
+  - mapCap: Nombre maximum d'entrées de data (0 = illimité)
+  - payload: Taille en octets du blob Payload de chaque entrée écrite
+  - enc: Encodeur des réponses de /process (voir -encoder)
+  - sem: Sémaphore limitant le parallélisme de la section lourde
+*/
+type SemaphoreRepository struct {
+	mu       sync.Mutex
+	counter  int
+	data     map[string]*DataStruct
+	copyData bool
+	work     WorkFunc
+	mapCap   int
+	payload  int
+	enc      Encoder
+	sem      *semaphore.Weighted
+}
+
+/*
+NewSemaphoreRepository crée et initialise un nouveau repository borné.
+
+@params:
+  - copyData: bool active la copie des données partagées à chaque requête
+  - limit: int64 nombre maximum de traitements lourds simultanés
+
+@returns: *SemaphoreRepository - Nouvelle instance avec la map et le sémaphore initialisés
+*/
+func NewSemaphoreRepository(copyData bool, limit int64) *SemaphoreRepository {
+	return newSemaphoreRepository(newRepoOptions(WithCopyData(copyData)), limit)
+}
+
+/*
+newSemaphoreRepository crée le repository à partir d'options déjà appliquées,
+pour que les jetons du sémaphore bornent le même traitement que les autres
+variantes.
+
+@params:
+  - o: repoOptions configuration (copie, borne de la map, traitement, payload, encodeur)
+  - limit: int64 nombre maximum de traitements lourds simultanés
+
+@returns: *SemaphoreRepository - Nouvelle instance avec la map et le sémaphore initialisés
+*/
+func newSemaphoreRepository(o repoOptions, limit int64) *SemaphoreRepository {
+	return &SemaphoreRepository{
+		data:     make(map[string]*DataStruct),
+		copyData: o.copyData,
+		work:     o.work,
+		mapCap:   o.mapCap,
+		payload:  o.payload,
+		enc:      o.encoder,
+		sem:      semaphore.NewWeighted(limit),
+	}
+}
+
+/*
+SemaphoreHandler combine la bonne pratique du mutex avec un parallélisme borné.
+Les sections critiques restent minimales, et le traitement lourd n'est lancé
+qu'après acquisition d'un jeton du sémaphore : au-delà de la limite, les
+requêtes attendent leur tour au lieu de s'empiler sur le CPU.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@behavior:
+  1. Verrouille le mutex pour la lecture/copie des données, puis le libère
+  2. Acquiert un jeton du sémaphore (abandon si le client se déconnecte)
+  3. Effectue le traitement lourd SANS le mutex, avec parallélisme borné
+  4. Libère le jeton puis re-verrouille uniquement pour l'écriture finale
+
+@performance: Latence prévisible sous forte charge, au prix d'une file d'attente
+*/
+func (r *SemaphoreRepository) SemaphoreHandler(w http.ResponseWriter, req *http.Request) {
+	includeData, ok := parseIncludeData(w, req)
+	if !ok {
+		return
+	}
+	start := time.Now()
+	ctx, span := startSpan(req.Context(), "semaphore.process")
+	defer span.End()
+
+	// Première acquisition du mutex pour lecture
+	tracedLock(ctx, "mu.read", r.mu.Lock)
+	r.counter++
+	currentCounter := r.counter
+	// La copie modélise un workload "lecture puis traitement" : son coût
+	// croît avec la taille de la map, elle est donc désactivable (-copy=false)
+	dataCopy := make(map[string]*DataStruct)
+	if r.copyData {
+		for k, v := range r.data {
+			dataCopy[k] = &DataStruct{
+				Identifier:   v.Identifier,
+				Name:         v.Name,
+				IsActive:     v.IsActive,
+				Counter:      v.Counter,
+				LastModified: v.LastModified,
+				Payload:      bytes.Clone(v.Payload),
+			}
+		}
+	}
+	r.mu.Unlock() // Libération immédiate après la lecture
+
+	// Acquisition d'un jeton : borne le nombre de traitements lourds simultanés
+	semSpan := startPhase(ctx, "semaphore.acquire")
+	err := r.sem.Acquire(ctx, 1)
+	semSpan.End()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "request cancelled while waiting for semaphore")
+		return
+	}
+
+	// Traitement lourd SANS le mutex
+	heavySpan := startPhase(ctx, "process.heavy")
+	result := r.work(ctx) // Attente simulée puis calcul intensif
+	heavySpan.End()
+	r.sem.Release(1)
+
+	// Deuxième acquisition du mutex uniquement pour l'écriture
+	key := fmt.Sprintf("request_%d", currentCounter)
+	payload := newPayload(r.payload) // Allouée avant de reprendre le mutex
+	writeSpan := startPhase(ctx, "write")
+	tracedLock(ctx, "mu.write", r.mu.Lock)
+	makeRoom(r.data, key, r.mapCap)
+	r.data[key] = &DataStruct{
+		Identifier:   key,
+		Name:         fmt.Sprintf("Request %d", currentCounter),
+		IsActive:     true,
+		Counter:      result,
+		LastModified: time.Now(),
+		Payload:      payload,
+	}
+	r.mu.Unlock() // Libération immédiate après l'écriture
+	writeSpan.End()
+
+	response := newResponse(req, "semaphore", start)
+	response.Counter = currentCounter
+	response.Result = result
+	response.SnapshotSize = len(dataCopy)
+
+	if includeData {
+		addSnapshot(response, dataCopy)
+	}
+	writeEncoded(w, http.StatusOK, response, r.enc)
+}
+
+/*
+evictExpired supprime les entrées dont LastModified précède cutoff.
+Appelée par le janitor, sous le mutex le temps du seul balayage.
+
+@params:
+  - cutoff: time.Time date limite de dernière modification
+
+@returns: int - Nombre d'entrées supprimées
+*/
+func (r *SemaphoreRepository) evictExpired(cutoff time.Time) int {
+	r.mu.Lock()
+	evicted := 0
+	for key, entry := range r.data {
+		if entry.LastModified.Before(cutoff) {
+			delete(r.data, key)
+			evicted++
+		}
+	}
+	r.mu.Unlock()
+	return evicted
+}
+
+/*
+GetDataHandler retourne l'entrée {id} en JSON, ou 404 si elle est absente.
+Le mutex n'est tenu que le temps de copier l'entrée, l'encodage se fait après.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+
+@returns: JSON DataStruct
+*/
+func (r *SemaphoreRepository) GetDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.mu.Lock()
+	var entry DataStruct
+	v, found := r.data[id]
+	if found {
+		entry = *v
+	}
+	r.mu.Unlock()
+
+	writeData(w, id, entry, found)
+}
+
+/*
+DeleteDataHandler supprime l'entrée {id} : 204 si supprimée, 404 si absente.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+*/
+func (r *SemaphoreRepository) DeleteDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.mu.Lock()
+	_, found := r.data[id]
+	delete(r.data, id)
+	r.mu.Unlock()
+
+	writeDeleted(w, id, found)
+}
+
+/*
+StatsHandler retourne les statistiques actuelles du serveur.
+Utilise correctement le mutex uniquement pour la lecture des données.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON contenant total_requests et data_size
+*/
+func (r *SemaphoreRepository) StatsHandler(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	stats := map[string]interface{}{
+		"total_requests": r.counter,
+		"data_size":      len(r.data),
+	}
+	r.mu.Unlock()
+
+	writeStats(w, stats)
+}
+
+/*
+Router configure les routes du serveur avec gorilla/mux.
+
+@returns: *mux.Router - Routeur prêt à être servi
+
+@endpoints:
+  - GET /process : Handler avec mutex et parallélisme borné par sémaphore
+  - GET /data/{id} : Lecture d'une entrée (404 si absente)
+  - DELETE /data/{id} : Suppression d'une entrée
+  - GET /stats : Statistiques du serveur
+  - GET /debug/allocs : Compteurs d'allocation du processus
+  - GET /debug/contention : Résumé du profil de contention des mutex
+*/
+func (r *SemaphoreRepository) Router() *mux.Router {
+	router := newRouter()
+	router.HandleFunc("/process", r.SemaphoreHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.GetDataHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.DeleteDataHandler).Methods("DELETE")
+	router.HandleFunc("/stats", r.StatsHandler).Methods("GET")
+	router.HandleFunc("/debug/allocs", AllocsHandler).Methods("GET")
+	router.HandleFunc("/debug/contention", ContentionHandler).Methods("GET")
+	return router
+}
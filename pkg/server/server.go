@@ -0,0 +1,675 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// shutdownTimeout borne l'attente des requêtes en cours lors de l'arrêt
+const shutdownTimeout = 5 * time.Second
+
+/*
+Options regroupe les paramètres communs à tous les serveurs.
+
+@fields:
+  - Addr: Adresse d'écoute ("" = port par défaut de la variante)
+  - CopyData: Copie les données partagées avant le traitement
+  - Payload: Taille en octets du blob de chaque entrée (tous les serveurs sauf floor)
+  - Upstream: URL appelée par /process/upstream (serveurs bad et good, "" = non configuré)
+  - SemaphoreLimit: Nombre de traitements lourds simultanés (serveurs semaphore et admission)
+  - AdmitBudget: Attente maximum d'admission avant un 503 (serveur admission, 0 = fail-fast)
+  - PoolWorkers: Nombre de workers (serveur pool)
+  - PoolQueue: Profondeur de la file d'attente (serveur pool)
+  - MutexProfileFraction: Fraction d'échantillonnage du profil de contention (0 désactive)
+  - TTL: Âge maximum des entrées stockées avant éviction par le janitor (0 désactive)
+  - OTelEndpoint: Collecteur OTLP/HTTP recevant les traces ("" = tracer no-op)
+  - Socket: Chemin d'une socket Unix sur laquelle écouter à la place de TCP ("" = TCP)
+  - TLS: Écoute en HTTPS, avec CertFile/KeyFile ou un certificat auto-signé
+  - CertFile, KeyFile: Certificat et clé PEM du serveur TLS ("" = auto-signé)
+  - AccessLog: Journalise chaque requête avec son identifiant X-Request-ID
+  - CPUOnly: Traitement lourd purement CPU, calibré au démarrage (tous les serveurs)
+  - Prometheus: Ajoute GET /stats/prometheus, les métriques principales de /stats au format texte Prometheus
+  - Encoder: Encodeur JSON des réponses de /process (tous les serveurs, "" = stdlib)
+  - PinWork: Exécute le traitement lourd verrouillé à un thread système, mode expérimental (mêmes serveurs que CPUOnly)
+  - Fanout: Goroutines entre lesquelles la boucle de calcul d'une requête est répartie (mêmes serveurs que CPUOnly, 0 ou 1 = aucune)
+  - SelfBench: Ajoute GET /bench, une mesure de latence du serveur sur son propre /process
+  - NoDelay: TCP_NODELAY sur les connexions acceptées (true désactive Nagle, false le réactive)
+  - MapCap: Nombre maximum d'entrées de la map, au-delà une écriture évince une entrée (tous les serveurs sauf floor, 0 = illimité)
+  - WorkSleep: Attente au début du traitement lourd (tous les serveurs, ignorée avec CPUOnly)
+  - WorkIters: Itérations de la boucle du traitement lourd (tous les serveurs, ignorées avec CPUOnly)
+*/
+type Options struct {
+	Addr                 string
+	CopyData             bool
+	Payload              int
+	Upstream             string
+	SemaphoreLimit       int64
+	AdmitBudget          time.Duration
+	PoolWorkers          int
+	PoolQueue            int
+	MutexProfileFraction int
+	TTL                  time.Duration
+	OTelEndpoint         string
+	Socket               string
+	TLS                  bool
+	CertFile             string
+	KeyFile              string
+	AccessLog            bool
+	CPUOnly              bool
+	Prometheus           bool
+	Encoder              string
+	PinWork              bool
+	Fanout               int
+	SelfBench            bool
+	NoDelay              bool
+	MapCap               int
+	WorkSleep            time.Duration
+	WorkIters            int
+}
+
+/*
+RegisterFlags déclare les flags correspondant aux options sur un FlagSet.
+
+@params:
+  - fs: *flag.FlagSet sur lequel enregistrer les flags
+*/
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	d := DefaultConfig()
+	fs.StringVar(&o.Addr, "addr", d.Addr, "Adresse d'écoute (défaut : port de la variante)")
+	fs.BoolVar(&o.CopyData, "copy", d.Copy, "Copie les données partagées avant le traitement (workload lecture puis traitement)")
+	fs.IntVar(&o.Payload, "payload", d.Payload, "Taille en octets du blob ajouté à chaque entrée, copié sous le verrou (tous les serveurs sauf floor)")
+	fs.StringVar(&o.Upstream, "upstream", d.Upstream, "URL appelée par /process/upstream des serveurs bad et good, ex: http://localhost:9000/ (vide = désactivé)")
+	fs.Int64Var(&o.SemaphoreLimit, "limit", d.Limit, "Traitements lourds simultanés pour les serveurs semaphore et admission")
+	fs.DurationVar(&o.AdmitBudget, "budget", time.Duration(d.Budget), "Attente maximum d'admission du serveur admission avant un 503 (0 = fail-fast)")
+	fs.IntVar(&o.PoolWorkers, "workers", d.Workers, "Nombre de workers du serveur pool")
+	fs.IntVar(&o.PoolQueue, "queue", d.Queue, "Profondeur de la file d'attente du serveur pool")
+	fs.DurationVar(&o.TTL, "ttl", time.Duration(d.TTL), "Évince les entrées non modifiées depuis cette durée (0 = désactivé)")
+	fs.IntVar(&o.MutexProfileFraction, "mutexfraction", d.MutexFraction, "Échantillonne 1 contention de mutex sur N pour /debug/contention (0 désactive)")
+	fs.StringVar(&o.OTelEndpoint, "otel", d.OTel, "Exporte les traces OpenTelemetry vers ce collecteur OTLP/HTTP, ex: localhost:4318 (vide = désactivé)")
+	fs.StringVar(&o.Socket, "socket", d.Socket, "Écoute sur cette socket Unix au lieu de TCP, ex: /tmp/bad.sock (exclusif avec -addr)")
+	fs.BoolVar(&o.TLS, "tls", d.TLS, "Écoute en HTTPS (certificat auto-signé sans -cert/-key)")
+	fs.StringVar(&o.CertFile, "cert", d.Cert, "Certificat PEM du serveur TLS (avec -tls et -key)")
+	fs.StringVar(&o.KeyFile, "key", d.Key, "Clé privée PEM du serveur TLS (avec -tls et -cert)")
+	fs.BoolVar(&o.AccessLog, "log", d.Log, "Journalise chaque requête : méthode, chemin, code, durée et X-Request-ID")
+	fs.BoolVar(&o.CPUOnly, "cpuOnly", d.CPUOnly, "Traitement lourd purement CPU calibré à 10ms, sans time.Sleep (tous les serveurs)")
+	fs.BoolVar(&o.Prometheus, "prom", d.Prom, "Expose GET /stats/prometheus : total_requests, data_size et avg_lock_hold_us au format texte Prometheus")
+	fs.StringVar(&o.Encoder, "encoder", d.Encoder, "Encodeur JSON des réponses de /process : stdlib, ou jsoniter pour un binaire compilé avec -tags jsoniter")
+	fs.BoolVar(&o.PinWork, "pinWork", d.PinWork, "Expérimental : traitement lourd verrouillé à un thread système (runtime.LockOSThread), réduit le parallélisme")
+	fs.IntVar(&o.Fanout, "fanout", d.Fanout, "Répartit la boucle de calcul de chaque requête entre G goroutines (tous les serveurs)")
+	fs.BoolVar(&o.SelfBench, "selfBench", d.SelfBench, "Expose GET /bench?concurrency=C&n=N : le serveur mesure la latence de son propre /process (charge le serveur)")
+	fs.BoolVar(&o.NoDelay, "nodelay", d.NoDelay, "TCP_NODELAY sur chaque connexion acceptée : réponses envoyées sans attendre l'algorithme de Nagle (false le réactive)")
+	fs.IntVar(&o.MapCap, "mapCap", d.MapCap, "Nombre maximum d'entrées de la map : au-delà, une écriture évince une entrée arbitraire (0 = illimité)")
+	fs.DurationVar(&o.WorkSleep, "sleep", time.Duration(d.Sleep), "Attente simulant un appel externe au début du traitement lourd (ignorée avec -cpuOnly)")
+	fs.IntVar(&o.WorkIters, "iters", d.Iters, "Itérations de la boucle de calcul du traitement lourd (ignorées avec -cpuOnly)")
+}
+
+/*
+workFunc retourne le traitement lourd choisi par les options : l'attente
+WorkSleep puis WorkIters itérations de la boucle (10ms et 1M par défaut),
+que -cpuOnly remplace par la boucle calibrée seule. -fanout répartit la
+boucle entre plusieurs goroutines, -pinWork épingle le traitement à un
+thread système.
+
+@returns: WorkFunc - Traitement à passer à WithWorkFunc
+*/
+func (o Options) workFunc() WorkFunc {
+	sleep, iters := o.WorkSleep, o.WorkIters
+	if o.CPUOnly {
+		sleep, iters = 0, cpuWorkIters()
+	}
+	work := fanOutWork(sleep, iters, o.Fanout)
+	if o.PinWork {
+		work = pinnedWork(work)
+	}
+	return work
+}
+
+/*
+Variant décrit une variante de serveur de démonstration.
+
+@fields:
+  - Name: Nom court (sous-commande de cmd/servers)
+  - Addr: Adresse d'écoute par défaut
+  - Title: Titre affiché au démarrage
+  - Endpoints: Description des endpoints affichée au démarrage
+  - NewHandler: Construit le handler HTTP à partir des options
+*/
+type Variant struct {
+	Name       string
+	Addr       string
+	Title      string
+	Endpoints  []string
+	NewHandler func(opts Options) http.Handler
+}
+
+// bigCopySeed est le nombre d'entrées pré-remplies du serveur bigcopy, copiées à chaque /process
+const bigCopySeed = 4000
+
+// bigCopyPayload est la taille du blob des entrées du serveur bigcopy quand -payload vaut 0
+const bigCopyPayload = 4096
+
+/*
+Variants liste les serveurs disponibles, dans l'ordre des ports.
+*/
+var Variants = []Variant{
+	{
+		Name:  "bad",
+		Addr:  ":8081",
+		Title: "BAD Server (avec defer)",
+		Endpoints: []string{
+			"GET /process - Mauvaise utilisation avec defer",
+			"POST /process/batch - Lot entier traité sous le mutex",
+			"GET /process/stream - Progression NDJSON, mutex tenu pendant le flux",
+			"GET /process/upstream - Appel à -upstream, mutex tenu pendant l'appel",
+			"GET /data/{id} - Lire une entrée (404 si absente)",
+			"DELETE /data/{id} - Supprimer une entrée",
+			"GET /stats   - Voir les statistiques",
+			"GET /debug/allocs - Compteurs d'allocation du processus",
+			"GET /debug/contention - Profil de contention des mutex (JSON)",
+			"GET /debug/lockwait - Histogramme des attentes du mutex (JSON)",
+			"POST /reset - Remettre à zéro les mesures de verrouillage",
+			"POST /seed?n=N - Pré-remplir avec N entrées synthétiques",
+		},
+		NewHandler: func(opts Options) http.Handler {
+			return NewRepository(WithLockStrategy(DeferMutex), WithCopyData(opts.CopyData), WithPayload(opts.Payload), WithMapCap(opts.MapCap), WithWorkFunc(opts.workFunc()), WithEncoder(opts.encoder()), WithUpstream(opts.Upstream), WithTTL(opts.TTL))
+		},
+	},
+	{
+		Name:  "good",
+		Addr:  ":8082",
+		Title: "GOOD Server (sans defer)",
+		Endpoints: []string{
+			"GET /process - Bonne utilisation sans defer",
+			"POST /process/batch - Lot traité hors mutex, écriture groupée",
+			"GET /process/stream - Progression NDJSON, mutex libéré avant le flux",
+			"GET /process/upstream - Appel à -upstream, mutex libéré avant l'appel",
+			"GET /data/{id} - Lire une entrée (404 si absente)",
+			"DELETE /data/{id} - Supprimer une entrée",
+			"GET /stats   - Voir les statistiques",
+			"GET /debug/allocs - Compteurs d'allocation du processus",
+			"GET /debug/contention - Profil de contention des mutex (JSON)",
+			"GET /debug/lockwait - Histogramme des attentes du mutex (JSON)",
+			"POST /reset - Remettre à zéro les mesures de verrouillage",
+			"POST /seed?n=N - Pré-remplir avec N entrées synthétiques",
+		},
+		NewHandler: func(opts Options) http.Handler {
+			return NewRepository(WithLockStrategy(Mutex), WithCopyData(opts.CopyData), WithPayload(opts.Payload), WithMapCap(opts.MapCap), WithWorkFunc(opts.workFunc()), WithEncoder(opts.encoder()), WithUpstream(opts.Upstream), WithTTL(opts.TTL))
+		},
+	},
+	{
+		Name:  "syncmap",
+		Addr:  ":8083",
+		Title: "SYNC.MAP Server (sans mutex manuel)",
+		Endpoints: []string{
+			"GET /process - Utilisation avec sync.Map",
+			"GET /data/{id} - Lire une entrée (404 si absente)",
+			"DELETE /data/{id} - Supprimer une entrée",
+			"GET /stats   - Voir les statistiques",
+			"GET /debug/allocs - Compteurs d'allocation du processus",
+			"GET /debug/contention - Profil de contention des mutex (JSON)",
+			"POST /seed?n=N - Pré-remplir avec N entrées synthétiques",
+		},
+		NewHandler: func(opts Options) http.Handler {
+			return NewRepository(WithLockStrategy(SyncMap), WithCopyData(opts.CopyData), WithPayload(opts.Payload), WithMapCap(opts.MapCap), WithWorkFunc(opts.workFunc()), WithEncoder(opts.encoder()), WithTTL(opts.TTL))
+		},
+	},
+	{
+		Name:  "semaphore",
+		Addr:  ":8084",
+		Title: "SEMAPHORE Server (parallélisme borné)",
+		Endpoints: []string{
+			"GET /process - Mutex court + sémaphore sur le traitement lourd",
+			"GET /data/{id} - Lire une entrée (404 si absente)",
+			"DELETE /data/{id} - Supprimer une entrée",
+			"GET /stats   - Voir les statistiques",
+			"GET /debug/allocs - Compteurs d'allocation du processus",
+			"GET /debug/contention - Profil de contention des mutex (JSON)",
+		},
+		NewHandler: func(opts Options) http.Handler {
+			repo := newSemaphoreRepository(newRepoOptions(WithCopyData(opts.CopyData), WithPayload(opts.Payload), WithMapCap(opts.MapCap), WithWorkFunc(opts.workFunc()), WithEncoder(opts.encoder())), opts.SemaphoreLimit)
+			return withJanitor(repo.Router(), opts.TTL, repo.evictExpired)
+		},
+	},
+	{
+		Name:  "pool",
+		Addr:  ":8085",
+		Title: "POOL Server (workers + file bornée)",
+		Endpoints: []string{
+			"GET /process - File bornée consommée par un pool de workers (503 si pleine)",
+			"GET /data/{id} - Lire une entrée (404 si absente)",
+			"DELETE /data/{id} - Supprimer une entrée",
+			"GET /stats   - Voir les statistiques",
+			"GET /debug/allocs - Compteurs d'allocation du processus",
+			"GET /debug/contention - Profil de contention des mutex (JSON)",
+		},
+		NewHandler: func(opts Options) http.Handler {
+			repo := newPoolRepository(newRepoOptions(WithCopyData(opts.CopyData), WithPayload(opts.Payload), WithMapCap(opts.MapCap), WithWorkFunc(opts.workFunc()), WithEncoder(opts.encoder())), opts.PoolWorkers, opts.PoolQueue)
+			stopJanitor := startJanitor(opts.TTL, repo.evictExpired)
+			// Close arrête le janitor puis les workers du pool
+			return managedHandler{Handler: repo.Router(), stop: func() {
+				stopJanitor()
+				repo.Close()
+			}}
+		},
+	},
+	{
+		Name:  "dcl",
+		Addr:  ":8086",
+		Title: "DCL Server (double-checked locking)",
+		Endpoints: []string{
+			"GET /process?key=X - Cache RWMutex avec double-checked locking",
+			"GET /data/{id} - Lire une entrée (404 si absente)",
+			"DELETE /data/{id} - Supprimer une entrée",
+			"GET /stats   - Voir les statistiques (hits/misses)",
+			"GET /debug/allocs - Compteurs d'allocation du processus",
+			"GET /debug/contention - Profil de contention des mutex (JSON)",
+		},
+		NewHandler: func(opts Options) http.Handler {
+			repo := newDCLRepository(newRepoOptions(WithPayload(opts.Payload), WithMapCap(opts.MapCap), WithWorkFunc(opts.workFunc()), WithEncoder(opts.encoder())))
+			return withJanitor(repo.Router(), opts.TTL, repo.evictExpired)
+		},
+	},
+	{
+		Name:  "singleflight",
+		Addr:  ":8087",
+		Title: "SINGLEFLIGHT Server (calculs dédupliqués)",
+		Endpoints: []string{
+			"GET /process?key=X - Requêtes identiques concurrentes partageant un calcul",
+			"GET /data/{id} - Lire une entrée (404 si absente)",
+			"DELETE /data/{id} - Supprimer une entrée",
+			"GET /stats   - Voir les statistiques (computations/shared)",
+			"GET /debug/allocs - Compteurs d'allocation du processus",
+			"GET /debug/contention - Profil de contention des mutex (JSON)",
+		},
+		NewHandler: func(opts Options) http.Handler {
+			repo := newSingleflightRepository(newRepoOptions(WithPayload(opts.Payload), WithMapCap(opts.MapCap), WithWorkFunc(opts.workFunc()), WithEncoder(opts.encoder())))
+			return withJanitor(repo.Router(), opts.TTL, repo.evictExpired)
+		},
+	},
+	{
+		Name:  "rwmutex",
+		Addr:  ":8088",
+		Title: "RWMUTEX Server (lectures parallèles)",
+		Endpoints: []string{
+			"GET /process - Lecture sous RLock, écriture sous Lock",
+			"GET /data/{id} - Lire une entrée (404 si absente)",
+			"DELETE /data/{id} - Supprimer une entrée",
+			"GET /stats   - Voir les statistiques",
+			"GET /debug/allocs - Compteurs d'allocation du processus",
+			"GET /debug/contention - Profil de contention des mutex (JSON)",
+			"POST /seed?n=N - Pré-remplir avec N entrées synthétiques",
+		},
+		NewHandler: func(opts Options) http.Handler {
+			return NewRepository(WithLockStrategy(RWMutex), WithCopyData(opts.CopyData), WithPayload(opts.Payload), WithMapCap(opts.MapCap), WithWorkFunc(opts.workFunc()), WithEncoder(opts.encoder()), WithTTL(opts.TTL))
+		},
+	},
+	{
+		Name:  "trylock",
+		Addr:  ":8089",
+		Title: "TRYLOCK Server (admission fail-fast)",
+		Endpoints: []string{
+			"GET /process - TryLock, 429 si le mutex est contendu",
+			"GET /data/{id} - Lire une entrée (404 si absente)",
+			"DELETE /data/{id} - Supprimer une entrée",
+			"GET /stats   - Voir les statistiques (rejection_rate)",
+			"GET /debug/allocs - Compteurs d'allocation du processus",
+			"GET /debug/contention - Profil de contention des mutex (JSON)",
+		},
+		NewHandler: func(opts Options) http.Handler {
+			repo := newTryLockRepository(newRepoOptions(WithCopyData(opts.CopyData), WithPayload(opts.Payload), WithMapCap(opts.MapCap), WithWorkFunc(opts.workFunc()), WithEncoder(opts.encoder())))
+			return withJanitor(repo.Router(), opts.TTL, repo.evictExpired)
+		},
+	},
+	{
+		Name:  "fair",
+		Addr:  ":8090",
+		Title: "FAIR Server (même section, defer ou Unlock)",
+		Endpoints: []string{
+			"GET /process?release=defer - Section critique du bad, libérée par defer",
+			"GET /process?release=unlock - Même section, Unlock explicite au même point",
+			"GET /data/{id} - Lire une entrée (404 si absente)",
+			"DELETE /data/{id} - Supprimer une entrée",
+			"GET /stats   - Voir les statistiques",
+			"GET /debug/allocs - Compteurs d'allocation du processus",
+			"GET /debug/contention - Profil de contention des mutex (JSON)",
+		},
+		NewHandler: func(opts Options) http.Handler {
+			repo := newFairRepository(newRepoOptions(WithCopyData(opts.CopyData), WithPayload(opts.Payload), WithMapCap(opts.MapCap), WithWorkFunc(opts.workFunc()), WithEncoder(opts.encoder())))
+			return withJanitor(repo.Router(), opts.TTL, repo.evictExpired)
+		},
+	},
+	{
+		Name:  "scoped",
+		Addr:  ":8091",
+		Title: "SCOPED Server (defer dans une fonction anonyme)",
+		Endpoints: []string{
+			"GET /process - Sections critiques func() { Lock; defer Unlock }()",
+			"GET /data/{id} - Lire une entrée (404 si absente)",
+			"DELETE /data/{id} - Supprimer une entrée",
+			"GET /stats   - Voir les statistiques",
+			"GET /debug/allocs - Compteurs d'allocation du processus",
+			"GET /debug/contention - Profil de contention des mutex (JSON)",
+		},
+		NewHandler: func(opts Options) http.Handler {
+			repo := newScopedRepository(newRepoOptions(WithCopyData(opts.CopyData), WithPayload(opts.Payload), WithMapCap(opts.MapCap), WithWorkFunc(opts.workFunc()), WithEncoder(opts.encoder())))
+			return withJanitor(repo.Router(), opts.TTL, repo.evictExpired)
+		},
+	},
+	{
+		Name:  "admission",
+		Addr:  ":8092",
+		Title: "ADMISSION Server (délestage 503 + Retry-After)",
+		Endpoints: []string{
+			"GET /process - Admission bornée par -budget, 503 + Retry-After au-delà",
+			"GET /data/{id} - Lire une entrée (404 si absente)",
+			"DELETE /data/{id} - Supprimer une entrée",
+			"GET /stats   - Voir les statistiques (admitted, rejected)",
+			"GET /debug/allocs - Compteurs d'allocation du processus",
+			"GET /debug/contention - Profil de contention des mutex (JSON)",
+		},
+		NewHandler: func(opts Options) http.Handler {
+			repo := newAdmissionRepository(newRepoOptions(WithCopyData(opts.CopyData), WithPayload(opts.Payload), WithMapCap(opts.MapCap), WithWorkFunc(opts.workFunc()), WithEncoder(opts.encoder())), opts.SemaphoreLimit, opts.AdmitBudget)
+			return withJanitor(repo.Router(), opts.TTL, repo.evictExpired)
+		},
+	},
+	{
+		Name:  "atomicvalue",
+		Addr:  ":8093",
+		Title: "ATOMICVALUE Server (copy-on-write, lectures sans verrou)",
+		Endpoints: []string{
+			"GET /process - Lecture sans verrou, écriture par copie de la map",
+			"GET /data/{id} - Lire une entrée sans verrou (404 si absente)",
+			"DELETE /data/{id} - Supprimer une entrée",
+			"GET /stats   - Voir les statistiques (map_copies, copied_per_write)",
+			"GET /debug/allocs - Compteurs d'allocation du processus",
+			"GET /debug/contention - Profil de contention des mutex (JSON)",
+			"POST /seed?n=N - Pré-remplir avec N entrées synthétiques",
+		},
+		NewHandler: func(opts Options) http.Handler {
+			return NewRepository(WithLockStrategy(AtomicValue), WithCopyData(opts.CopyData), WithPayload(opts.Payload), WithMapCap(opts.MapCap), WithWorkFunc(opts.workFunc()), WithEncoder(opts.encoder()), WithTTL(opts.TTL))
+		},
+	},
+	{
+		Name:  "bigcopy",
+		Addr:  ":8094",
+		Title: "BIGCOPY Server (good, copie lourde sous le verrou)",
+		Endpoints: []string{
+			"GET /process - Écriture courte, mais copie de toute la map sous le mutex",
+			"POST /process/batch - Lot traité hors mutex, écriture groupée",
+			"GET /process/stream - Progression NDJSON, mutex libéré avant le flux",
+			"GET /data/{id} - Lire une entrée (404 si absente)",
+			"DELETE /data/{id} - Supprimer une entrée",
+			"GET /stats   - Voir les statistiques (avg_lock_hold_us)",
+			"GET /debug/allocs - Compteurs d'allocation du processus",
+			"GET /debug/contention - Profil de contention des mutex (JSON)",
+			"GET /debug/lockwait - Histogramme des attentes du mutex (JSON)",
+			"POST /reset - Remettre à zéro les mesures de verrouillage",
+			"POST /seed?n=N - Pré-remplir avec N entrées synthétiques",
+		},
+		NewHandler: func(opts Options) http.Handler {
+			payload := opts.Payload
+			if payload <= 0 {
+				payload = bigCopyPayload
+			}
+			return NewRepository(WithLockStrategy(Mutex), WithCopyData(true), WithPayload(payload), WithMapCap(opts.MapCap), WithSeed(bigCopySeed), WithWorkFunc(opts.workFunc()), WithEncoder(opts.encoder()), WithUpstream(opts.Upstream), WithTTL(opts.TTL))
+		},
+	},
+	{
+		Name:  "floor",
+		Addr:  ":8095",
+		Title: "FLOOR Server (compteur atomique seul, ni map ni verrou)",
+		Endpoints: []string{
+			"GET /process - Compteur atomique et traitement lourd, sans map ni verrou",
+			"GET /stats   - Voir les statistiques",
+			"GET /debug/allocs - Compteurs d'allocation du processus",
+		},
+		NewHandler: func(opts Options) http.Handler {
+			repo := newFloorRepository(newRepoOptions(WithWorkFunc(opts.workFunc()), WithEncoder(opts.encoder())))
+			// Ni map ni janitor : rien à arrêter à la fermeture
+			return managedHandler{Handler: repo.Router(), stop: func() {}}
+		},
+	},
+}
+
+/*
+Lookup retourne la variante portant le nom donné.
+
+@params:
+  - name: string nom court de la variante
+
+@returns: (Variant, bool) - La variante et true si elle existe
+*/
+func Lookup(name string) (Variant, bool) {
+	for _, v := range Variants {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Variant{}, false
+}
+
+/*
+PrintBanner affiche le titre et les endpoints de la variante.
+
+@params:
+  - addr: string adresse d'écoute effective
+*/
+func (v Variant) PrintBanner(addr string) {
+	fmt.Printf("%s starting on %s\n", v.Title, addr)
+	fmt.Println("Endpoints:")
+	for _, e := range v.Endpoints {
+		fmt.Printf("  %s\n", e)
+	}
+}
+
+// unixPrefix préfixe les adresses d'écoute désignant une socket Unix
+const unixPrefix = "unix:"
+
+/*
+ListenAddr retourne l'adresse d'écoute de la variante : "unix:" suivi de
+opts.Socket si elle est renseignée, sinon opts.Addr, sinon le port par
+défaut de la variante.
+
+@params:
+  - opts: Options options communes des serveurs
+
+@returns: string adresse d'écoute
+*/
+func (v Variant) ListenAddr(opts Options) string {
+	if opts.Socket != "" {
+		return unixPrefix + opts.Socket
+	}
+	if opts.Addr != "" {
+		return opts.Addr
+	}
+	return v.Addr
+}
+
+/*
+NewServer construit le serveur HTTP de la variante. Si le handler possède des
+goroutines de fond (io.Closer), elles sont arrêtées au Shutdown du serveur.
+Avec opts.TLS, le serveur reçoit sa configuration TLS et Run le sert en HTTPS.
+Avec opts.Prometheus, le serveur expose aussi /stats/prometheus (voir
+withPrometheus). Avec opts.SelfBench, il expose /bench (voir withSelfBench).
+Avec opts.AccessLog, chaque requête est journalisée (voir logRequests).
+Chaque connexion TCP acceptée reçoit TCP_NODELAY selon opts.NoDelay (voir
+noDelayConn).
+Les requêtes en cours sont comptées pour le drain de Run (voir withInFlight).
+
+@params:
+  - addr: string adresse d'écoute
+  - opts: Options options communes des serveurs
+
+@returns: *http.Server - Serveur prêt à être passé à Run
+*/
+func (v Variant) NewServer(addr string, opts Options) *http.Server {
+	handler := v.NewHandler(opts)
+	srv := &http.Server{Addr: addr, Handler: handler, ConnContext: noDelayConn(opts.NoDelay)}
+	if opts.Prometheus {
+		srv.Handler = withPrometheus(srv.Handler, v.Name)
+	}
+	if opts.SelfBench {
+		srv.Handler = withSelfBench(srv.Handler)
+	}
+	if opts.AccessLog {
+		srv.Handler = logRequests(srv.Handler)
+	}
+	srv.Handler = withInFlight(srv.Handler)
+	if opts.TLS {
+		srv.TLSConfig = serverTLSConfig(opts)
+	}
+	if closer, ok := handler.(io.Closer); ok {
+		srv.RegisterOnShutdown(func() { closer.Close() })
+	}
+	return srv
+}
+
+/*
+noDelayConn construit le ConnContext qui règle TCP_NODELAY sur chaque
+connexion acceptée, avant sa première requête. Go l'active déjà par défaut
+sur les connexions TCP : le fixer explicitement garantit la même
+configuration à toutes les variantes, et -nodelay=false réactive Nagle pour
+en mesurer l'effet.
+
+@params:
+  - noDelay: bool true désactive l'algorithme de Nagle, false le réactive
+
+@returns: func(context.Context, net.Conn) context.Context - ConnContext du
+serveur, contexte inchangé
+
+@note: Avec Nagle, un petit segment attend l'acquittement du précédent ;
+si le pair retarde ses ACK (jusqu'à 40ms sous Linux), une réponse JSON
+écrite en deux fois prend des dizaines de millisecondes sans rapport avec
+le mutex. Les sockets Unix ne sont pas concernées.
+*/
+func noDelayConn(noDelay bool) func(ctx context.Context, c net.Conn) context.Context {
+	return func(ctx context.Context, c net.Conn) context.Context {
+		// ServeTLS passe la connexion chiffrée : l'option se règle sur la connexion TCP dessous
+		if tc, ok := c.(*tls.Conn); ok {
+			c = tc.NetConn()
+		}
+		if tcp, ok := c.(*net.TCPConn); ok {
+			if err := tcp.SetNoDelay(noDelay); err != nil {
+				log.Printf("TCP_NODELAY sur %s: %v", c.RemoteAddr(), err)
+			}
+		}
+		return ctx
+	}
+}
+
+/*
+listen ouvre le listener d'une adresse d'écoute : TCP, ou socket Unix si
+l'adresse commence par "unix:". Une socket restée d'un processus interrompu
+(plus personne n'y écoute) est supprimée avant l'écoute ; à la fermeture du listener, net supprime le
+fichier de la socket.
+
+@params:
+  - addr: string adresse d'écoute (":8081" ou "unix:/tmp/bad.sock")
+
+@returns: (net.Listener, error) - Listener ouvert, ou erreur d'écoute
+*/
+func listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, unixPrefix)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		// Une socket qui accepte encore les connexions appartient à un serveur vivant
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+		} else {
+			os.Remove(path)
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+/*
+Run démarre les serveurs donnés et les arrête proprement sur SIGINT/SIGTERM.
+Les listeners sont ouverts avant de servir : une erreur de port occupé est
+remontée immédiatement, sinon les adresses effectivement à l'écoute sont
+affichées. Un serveur doté d'une TLSConfig (voir NewServer) est servi en HTTPS.
+À l'arrêt, tous les serveurs cessent d'accepter des connexions en même temps
+et attendent leurs requêtes en cours, au plus shutdownTimeout, en
+journalisant la progression (voir drain).
+
+@params:
+  - servers: []*http.Server serveurs à démarrer
+
+@returns: error - Première erreur de démarrage ou de service, nil après un arrêt propre
+*/
+func Run(servers []*http.Server) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	listeners := make([]net.Listener, 0, len(servers))
+	for _, srv := range servers {
+		ln, err := listen(srv.Addr)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return fmt.Errorf("écoute sur %s: %w", srv.Addr, err)
+		}
+		listeners = append(listeners, ln)
+	}
+	for i, ln := range listeners {
+		if servers[i].TLSConfig != nil {
+			fmt.Printf("✓ Écoute active sur %s (TLS)\n", ln.Addr())
+		} else {
+			fmt.Printf("✓ Écoute active sur %s\n", ln.Addr())
+		}
+	}
+
+	errCh := make(chan error, len(servers))
+	for i, srv := range servers {
+		go func(srv *http.Server, ln net.Listener) {
+			serve := srv.Serve
+			if srv.TLSConfig != nil {
+				// Le certificat est dans TLSConfig : pas de fichiers à charger ici
+				serve = func(ln net.Listener) error { return srv.ServeTLS(ln, "", "") }
+			}
+			if err := serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- err
+			}
+		}(srv, listeners[i])
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+	case runErr = <-errCh:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	drainErrs := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, srv := range servers {
+		wg.Add(1)
+		go func(i int, srv *http.Server) {
+			defer wg.Done()
+			drainErrs[i] = drain(shutdownCtx, srv, log.Printf)
+		}(i, srv)
+	}
+	wg.Wait()
+	for _, err := range drainErrs {
+		if err != nil && runErr == nil {
+			runErr = err
+		}
+	}
+	return runErr
+}
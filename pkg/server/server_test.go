@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/*
+TestListenUnixSocket vérifie l'écoute sur socket Unix : un client dont le
+DialContext vise la socket atteint le serveur quelle que soit l'URL, et le
+fichier de la socket disparaît à l'arrêt.
+*/
+func TestListenUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.sock")
+	variant, _ := Lookup("bad")
+	addr := variant.ListenAddr(Options{Socket: path})
+	if addr != "unix:"+path {
+		t.Fatalf("ListenAddr = %q, want unix:%s", addr, path)
+	}
+
+	ln, err := listen(addr)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := &http.Server{Handler: NewBadRepository(false).Router()}
+	go srv.Serve(ln)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://unix/stats")
+	if err != nil {
+		t.Fatalf("GET over socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /stats status = %d, want 200", resp.StatusCode)
+	}
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket file still present after shutdown (stat err = %v)", err)
+	}
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/singleflight"
+)
+
+/*
+SingleflightRepository regroupe les calculs identiques concurrents : les
+requêtes simultanées pour une même clé partagent un seul traitement lourd au
+lieu de le refaire chacune.
+
+@fields:
+  - mu: Mutex protégeant uniquement l'écriture finale dans la map
+  - data: Map des derniers résultats calculés, indexée par clé
+  - work: Traitement lourd partagé par les requêtes fusionnées
+  - mapCap: Nombre maximum d'entrées de data (0 = illimité)
+  - payload: Taille en octets du blob Payload de chaque entrée écrite
+  - enc: Encodeur des réponses de /process (voir -encoder)
+  - group: Groupe singleflight dédupliquant les calculs en vol
+  - counter: Nombre total de requêtes traitées
+  - computations: Nombre de traitements lourds effectivement exécutés
+  - shared: Nombre de requêtes ayant reçu un résultat partagé
+*/
+type SingleflightRepository struct {
+	mu           sync.Mutex
+	data         map[string]*DataStruct
+	work         WorkFunc
+	mapCap       int
+	payload      int
+	enc          Encoder
+	group        singleflight.Group
+	counter      atomic.Int64
+	computations atomic.Int64
+	shared       atomic.Int64
+}
+
+/*
+NewSingleflightRepository crée et initialise un nouveau repository.
+
+@returns: *SingleflightRepository - Nouvelle instance avec la map initialisée
+*/
+func NewSingleflightRepository() *SingleflightRepository {
+	return newSingleflightRepository(newRepoOptions())
+}
+
+/*
+newSingleflightRepository crée le repository à partir d'options déjà
+appliquées : le calcul partagé est le traitement injecté (WithWorkFunc).
+
+@params:
+  - o: repoOptions configuration (borne de la map, traitement, payload, encodeur)
+
+@returns: *SingleflightRepository - Nouvelle instance avec la map initialisée
+*/
+func newSingleflightRepository(o repoOptions) *SingleflightRepository {
+	return &SingleflightRepository{
+		data:    make(map[string]*DataStruct),
+		work:    o.work,
+		mapCap:  o.mapCap,
+		payload: o.payload,
+		enc:     o.encoder,
+	}
+}
+
+/*
+SingleflightHandler calcule le résultat de la clé ?key= en fusionnant les
+requêtes concurrentes identiques.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP (paramètre key, "default" si absent)
+
+@behavior:
+  1. group.Do(key, ...) : seule la première requête pour une clé exécute la fonction,
+     les suivantes arrivées pendant le calcul attendent et reçoivent le même résultat
+  2. Le traitement lourd se fait SANS mutex
+  3. L'écriture finale dans la map est faite DANS la fonction partagée : une seule
+     écriture par calcul, et non une par requête fusionnée
+  4. Une fois le calcul terminé, la clé est oubliée : singleflight déduplique les
+     calculs en vol, ce n'est pas un cache
+
+@performance: N requêtes simultanées sur une clé = 1 traitement lourd et 1 écriture
+*/
+func (r *SingleflightRepository) SingleflightHandler(w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	currentCounter := r.counter.Add(1)
+	ctx, span := startSpan(req.Context(), "singleflight.process")
+	defer span.End()
+
+	key := req.URL.Query().Get("key")
+	if key == "" {
+		key = "default"
+	}
+
+	v, _, shared := r.group.Do(key, func() (interface{}, error) {
+		r.computations.Add(1)
+
+		// Traitement lourd SANS le mutex, exécuté une fois pour toutes les requêtes en vol
+		// (le span est rattaché à la requête qui a lancé le calcul)
+		heavySpan := startPhase(ctx, "process.heavy")
+		result := r.work(ctx) // Attente simulée puis calcul intensif
+		heavySpan.End()
+
+		entry := &DataStruct{
+			Identifier:   key,
+			Name:         fmt.Sprintf("Computed %s", key),
+			IsActive:     true,
+			Counter:      result,
+			LastModified: time.Now(),
+			Payload:      newPayload(r.payload), // Allouée avant de prendre le mutex
+		}
+
+		// Écriture unique sous le mutex, partagée par toutes les requêtes fusionnées
+		tracedLock(ctx, "mu.write", r.mu.Lock)
+		makeRoom(r.data, key, r.mapCap)
+		r.data[key] = entry
+		r.mu.Unlock()
+
+		return entry, nil
+	})
+	if shared {
+		r.shared.Add(1)
+	}
+	span.SetAttributes(attribute.Bool("singleflight.shared", shared))
+	entry := v.(*DataStruct)
+
+	response := newResponse(req, "singleflight", start)
+	response.Counter = int(currentCounter)
+	response.Result = entry.Counter
+	response.Key = key
+	response.Shared = &shared
+
+	writeEncoded(w, http.StatusOK, response, r.enc)
+}
+
+/*
+evictExpired supprime les entrées dont LastModified précède cutoff.
+Appelée par le janitor, sous le mutex le temps du seul balayage.
+
+@params:
+  - cutoff: time.Time date limite de dernière modification
+
+@returns: int - Nombre d'entrées supprimées
+*/
+func (r *SingleflightRepository) evictExpired(cutoff time.Time) int {
+	r.mu.Lock()
+	evicted := 0
+	for key, entry := range r.data {
+		if entry.LastModified.Before(cutoff) {
+			delete(r.data, key)
+			evicted++
+		}
+	}
+	r.mu.Unlock()
+	return evicted
+}
+
+/*
+GetDataHandler retourne l'entrée {id} en JSON, ou 404 si elle est absente.
+Le mutex n'est tenu que le temps de copier l'entrée, l'encodage se fait après.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+
+@returns: JSON DataStruct
+*/
+func (r *SingleflightRepository) GetDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.mu.Lock()
+	var entry DataStruct
+	v, found := r.data[id]
+	if found {
+		entry = *v
+	}
+	r.mu.Unlock()
+
+	writeData(w, id, entry, found)
+}
+
+/*
+DeleteDataHandler supprime l'entrée {id} : 204 si supprimée, 404 si absente.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+*/
+func (r *SingleflightRepository) DeleteDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.mu.Lock()
+	_, found := r.data[id]
+	delete(r.data, id)
+	r.mu.Unlock()
+
+	writeDeleted(w, id, found)
+}
+
+/*
+StatsHandler retourne les statistiques actuelles du serveur.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON contenant total_requests, data_size, computations et shared_results
+*/
+func (r *SingleflightRepository) StatsHandler(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	dataSize := len(r.data)
+	r.mu.Unlock()
+
+	stats := map[string]interface{}{
+		"total_requests": r.counter.Load(),
+		"data_size":      dataSize,
+		"computations":   r.computations.Load(),
+		"shared_results": r.shared.Load(),
+	}
+
+	writeStats(w, stats)
+}
+
+/*
+Router configure les routes du serveur avec gorilla/mux.
+
+@returns: *mux.Router - Routeur prêt à être servi
+
+@endpoints:
+  - GET /process?key=X : Calcul partagé entre requêtes concurrentes identiques
+  - GET /data/{id} : Lecture d'une entrée (404 si absente)
+  - DELETE /data/{id} : Suppression d'une entrée
+  - GET /stats : Statistiques du serveur
+  - GET /debug/allocs : Compteurs d'allocation du processus
+  - GET /debug/contention : Résumé du profil de contention des mutex
+*/
+func (r *SingleflightRepository) Router() *mux.Router {
+	router := newRouter()
+	router.HandleFunc("/process", r.SingleflightHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.GetDataHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.DeleteDataHandler).Methods("DELETE")
+	router.HandleFunc("/stats", r.StatsHandler).Methods("GET")
+	router.HandleFunc("/debug/allocs", AllocsHandler).Methods("GET")
+	router.HandleFunc("/debug/contention", ContentionHandler).Methods("GET")
+	return router
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// streamSteps est le nombre de lignes de progression envoyées par /process/stream
+const streamSteps = 10
+
+/*
+streamProgress est une ligne NDJSON envoyée sur /process/stream. La ligne
+finale est une ResponseV1 avec Done.
+
+@fields:
+  - SchemaVersion: Version du contrat (SchemaVersion)
+  - Step: Numéro de l'étape terminée (1 à streamSteps)
+  - Steps: Nombre total d'étapes
+  - Partial: Résultat partiel du calcul à cette étape
+*/
+type streamProgress struct {
+	SchemaVersion int `json:"schema_version"`
+	Step          int `json:"step"`
+	Steps         int `json:"steps"`
+	Partial       int `json:"partial"`
+}
+
+/*
+startStream prépare une réponse NDJSON diffusée au fil de l'eau.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+
+@returns: (http.Flusher, bool) - Flusher de la réponse ; false (et 500 envoyé)
+si le ResponseWriter ne permet pas de diffuser
+*/
+func startStream(w http.ResponseWriter) (http.Flusher, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return nil, false
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	return flusher, true
+}
+
+/*
+streamHeavyWork effectue le traitement lourd en streamSteps étapes et envoie
+une ligne de progression, vidée immédiatement vers le client, après chacune.
+Chaque étape exécute une tranche du traitement de /process (withWorkStep) :
+le coût total est celui d'une requête /process.
+
+@params:
+  - ctx: context.Context contexte de la requête
+  - w: http.ResponseWriter de la réponse, déjà démarrée par startStream
+  - flusher: http.Flusher de la même réponse
+  - work: WorkFunc traitement lourd du repository
+
+@returns: int - Résultat du calcul
+
+@note: Chaque Flush attend que le client accepte les données : un client lent
+allonge le traitement, et donc la détention du mutex s'il est tenu
+*/
+func streamHeavyWork(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, work WorkFunc) int {
+	enc := json.NewEncoder(w)
+	result := 0
+	for step := 1; step <= streamSteps; step++ {
+		result += work(withWorkStep(ctx, step, streamSteps)) // Tranche de l'attente et du calcul
+
+		// Erreur d'écriture = client parti : le calcul est terminé quand même,
+		// pour que l'écriture finale ait lieu comme sur /process
+		enc.Encode(streamProgress{SchemaVersion: SchemaVersion, Step: step, Steps: streamSteps, Partial: result})
+		flusher.Flush()
+	}
+	return result
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+/*
+TestStreamHandlers vérifie que /process/stream envoie une ligne NDJSON par
+étape puis une ligne finale, avec le même résultat que /process, toutes
+portant schema_version.
+*/
+func TestStreamHandlers(t *testing.T) {
+	servers := []struct {
+		name    string
+		handler http.Handler
+	}{
+		{"bad", NewBadRepository(false).Router()},
+		{"good", NewGoodRepository(false).Router()},
+	}
+
+	for _, srv := range servers {
+		t.Run(srv.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			srv.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/process/stream", nil))
+			if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+				t.Fatalf("status = %d, Content-Type = %q", rec.Code, rec.Header().Get("Content-Type"))
+			}
+			if !rec.Flushed {
+				t.Error("response was never flushed")
+			}
+
+			var lines []map[string]interface{}
+			scanner := bufio.NewScanner(rec.Body)
+			for scanner.Scan() {
+				var line map[string]interface{}
+				if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+					t.Fatalf("invalid NDJSON line %q: %v", scanner.Text(), err)
+				}
+				if line["schema_version"] != float64(SchemaVersion) {
+					t.Errorf("line %q: schema_version = %v, want %d", scanner.Text(), line["schema_version"], SchemaVersion)
+				}
+				lines = append(lines, line)
+			}
+			if len(lines) != streamSteps+1 {
+				t.Fatalf("got %d lines, want %d progress lines + 1 final line", len(lines), streamSteps)
+			}
+			last := lines[len(lines)-1]
+			if last["done"] != true || last["result"] != float64(499999500000) {
+				t.Errorf("final line = %v, want done with result 499999500000", last)
+			}
+			if size := dataSize(t, srv.handler); size != 1 {
+				t.Errorf("data_size = %d, want 1", size)
+			}
+		})
+	}
+}
+
+/*
+TestStreamUsesRepositoryWork vérifie que /process/stream découpe le
+traitement du repository (WithWork) en étapes au lieu du traitement par
+défaut : le premier résultat partiel est celui de la première tranche et le
+résultat final celui du traitement entier.
+*/
+func TestStreamUsesRepositoryWork(t *testing.T) {
+	const iters = 1000
+	for _, strategy := range []LockStrategy{DeferMutex, Mutex} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			repo := NewRepository(WithLockStrategy(strategy), WithWork(0, iters))
+			defer repo.Close()
+			rec := httptest.NewRecorder()
+			repo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/process/stream", nil))
+
+			var lines [][]byte
+			scanner := bufio.NewScanner(rec.Body)
+			for scanner.Scan() {
+				lines = append(lines, append([]byte(nil), scanner.Bytes()...))
+			}
+			if len(lines) != streamSteps+1 {
+				t.Fatalf("got %d lines, want %d", len(lines), streamSteps+1)
+			}
+			var first streamProgress
+			var last ResponseV1
+			if err := json.Unmarshal(lines[0], &first); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(lines[streamSteps], &last); err != nil {
+				t.Fatal(err)
+			}
+			if want := spinRange(0, iters/streamSteps); first.Partial != want {
+				t.Errorf("first partial = %d, want %d", first.Partial, want)
+			}
+			if !last.Done || last.Result != spin(iters) {
+				t.Errorf("final line = %+v, want done with result %d", last, spin(iters))
+			}
+		})
+	}
+}
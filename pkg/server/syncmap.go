@@ -0,0 +1,366 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+/*
+SyncMapRepository utilise sync.Map pour une gestion thread-safe sans mutex explicite.
+sync.Map est optimisée pour deux cas d'usage:
+1) Peu d'écritures mais beaucoup de lectures
+2) Plusieurs goroutines lisent/écrivent des clés disjointes
+
+@fields:
+  - counter: Compteur atomique pour éviter les mutex
+  - data: sync.Map pour stocker les données de manière thread-safe
+  - size: Nombre de clés distinctes dans data, maintenu pour un comptage O(1)
+  - copyData: Active la copie des données avant traitement (workload "lecture puis traitement")
+  - mapCap: Nombre maximum d'entrées de data (0 = illimité)
+  - work: Traitement lourd de /process
+  - payload: Taille en octets du blob Payload de chaque entrée écrite
+  - enc: Encodeur des réponses de /process (voir -encoder)
+*/
+type SyncMapRepository struct {
+	counter  int64        // Utilise atomic pour éviter le mutex
+	data     sync.Map     // Thread-safe map sans mutex manuel
+	size     atomic.Int64 // sync.Map n'expose pas sa taille
+	copyData bool
+	mapCap   int
+	work     WorkFunc
+	payload  int
+	enc      Encoder
+}
+
+/*
+NewSyncMapRepository crée et initialise un nouveau repository avec sync.Map.
+
+@params:
+  - copyData: bool active la copie des données partagées à chaque requête
+
+@returns: *SyncMapRepository - Nouvelle instance utilisant sync.Map
+*/
+func NewSyncMapRepository(copyData bool) *SyncMapRepository {
+	return newSyncMapRepository(newRepoOptions(WithCopyData(copyData)))
+}
+
+/*
+newSyncMapRepository crée un repository à partir d'options déjà appliquées.
+
+@params:
+  - o: repoOptions configuration (copie, borne de la map, traitement, payload, encodeur)
+
+@returns: *SyncMapRepository - Nouvelle instance utilisant sync.Map
+*/
+func newSyncMapRepository(o repoOptions) *SyncMapRepository {
+	return &SyncMapRepository{copyData: o.copyData, mapCap: o.mapCap, work: o.work, payload: o.payload, enc: o.encoder}
+}
+
+/*
+SyncMapHandler démontre l'utilisation de sync.Map pour la concurrence.
+sync.Map gère automatiquement la synchronisation sans mutex explicite.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@behavior:
+  1. Incrémente le compteur atomiquement
+  2. Copie l'entrée de la requête précédente via sync.Map.Load (thread-safe)
+  3. Effectue le traitement lourd sans bloquer d'autres opérations
+  4. Écrit les résultats dans sync.Map (thread-safe)
+
+@performance: sync.Map optimise automatiquement l'accès concurrent
+*/
+func (r *SyncMapRepository) SyncMapHandler(w http.ResponseWriter, req *http.Request) {
+	includeData, ok := parseIncludeData(w, req)
+	if !ok {
+		return
+	}
+	start := time.Now()
+	ctx, span := startSpan(req.Context(), "syncmap.process")
+	defer span.End()
+
+	// Incrémentation atomique du compteur
+	currentCounter := atomic.AddInt64(&r.counter, 1)
+
+	// Lecture par clé, comme un handler réel : l'entrée de la requête
+	// précédente, via Load. Un Range à chaque requête serait O(n) (voir
+	// BenchmarkSyncMapRange) ; la copie complète est servie par /snapshot
+	var dataCopy map[string]*DataStruct
+	if r.copyData {
+		dataCopy = r.loadCopy(fmt.Sprintf("request_%d", currentCounter-1))
+	}
+
+	// Traitement lourd (pas de mutex à gérer)
+	heavySpan := startPhase(ctx, "process.heavy")
+	result := r.work(ctx) // Attente simulée puis calcul intensif
+	heavySpan.End()
+
+	// Écriture dans sync.Map (thread-safe automatiquement)
+	key := fmt.Sprintf("request_%d", currentCounter)
+	writeSpan := startPhase(ctx, "write")
+	r.store(key, &DataStruct{
+		Identifier:   key,
+		Name:         fmt.Sprintf("Request %d", currentCounter),
+		IsActive:     true,
+		Counter:      result,
+		LastModified: time.Now(),
+		Payload:      newPayload(r.payload),
+	})
+	writeSpan.End()
+
+	response := newResponse(req, "sync_map", start)
+	response.Counter = int(currentCounter)
+	response.Result = result
+	response.SnapshotSize = len(dataCopy)
+
+	if includeData {
+		addSnapshot(response, dataCopy)
+	}
+	writeEncoded(w, http.StatusOK, response, r.enc)
+}
+
+// cloneEntry copie une entrée, payload compris, détachée de la sync.Map
+func cloneEntry(ds *DataStruct) *DataStruct {
+	return &DataStruct{
+		Identifier:   ds.Identifier,
+		Name:         ds.Name,
+		IsActive:     ds.IsActive,
+		Counter:      ds.Counter,
+		LastModified: ds.LastModified,
+		Payload:      bytes.Clone(ds.Payload),
+	}
+}
+
+/*
+loadCopy copie l'entrée key via sync.Map.Load, en O(1) quelle que soit la
+taille de la map.
+
+@params:
+  - key: string clé à lire
+
+@returns: map[string]*DataStruct - Copie de l'entrée, vide si la clé est absente
+*/
+func (r *SyncMapRepository) loadCopy(key string) map[string]*DataStruct {
+	dataCopy := make(map[string]*DataStruct, 1)
+	if v, ok := r.data.Load(key); ok {
+		dataCopy[key] = cloneEntry(v.(*DataStruct))
+	}
+	return dataCopy
+}
+
+/*
+snapshot copie toutes les entrées de data via sync.Map.Range. Le coût croît
+avec la taille de la map, sans verrou mais à chaque appel : sur le chemin de
+chaque requête, Range ferait paraître sync.Map plus lente que les serveurs à
+mutex dès que la map grossit. Seul /snapshot y fait appel.
+
+@returns: map[string]*DataStruct - Copie détachée des entrées
+*/
+func (r *SyncMapRepository) snapshot() map[string]*DataStruct {
+	dataCopy := make(map[string]*DataStruct)
+	r.data.Range(func(key, value interface{}) bool {
+		if ds, ok := value.(*DataStruct); ok {
+			dataCopy[key.(string)] = cloneEntry(ds)
+		}
+		return true // Continue l'itération
+	})
+	return dataCopy
+}
+
+/*
+SnapshotHandler retourne la copie complète des données, hors du chemin de
+/process.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON ResponseV1 contenant snapshot_size, data (au plus
+maxSnapshotEntries entrées, voir addSnapshot) et data_truncated
+*/
+func (r *SyncMapRepository) SnapshotHandler(w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	dataCopy := r.snapshot()
+	response := newResponse(req, "sync_map_snapshot", start)
+	response.SnapshotSize = len(dataCopy)
+	addSnapshot(response, dataCopy)
+	writeJSON(w, http.StatusOK, response)
+}
+
+/*
+store écrit une valeur dans sync.Map en maintenant le compteur de taille.
+Swap indique atomiquement si la clé existait : seul un ajout incrémente
+size, un écrasement de clé existante ne compte pas deux fois, même si le
+janitor supprime la clé en parallèle. Un ajout qui dépasse mapCap évince
+une autre entrée ; sans verrou, la borne peut être dépassée brièvement par
+des ajouts simultanés.
+
+@params:
+  - key: string clé de l'élément
+  - value: *DataStruct valeur à stocker
+*/
+func (r *SyncMapRepository) store(key string, value *DataStruct) {
+	if _, loaded := r.data.Swap(key, value); !loaded {
+		if size := r.size.Add(1); r.mapCap > 0 && size > int64(r.mapCap) {
+			r.evictOne(key)
+		}
+	}
+}
+
+/*
+evictOne supprime la première entrée rencontrée autre que keep.
+
+@params:
+  - keep: string clé qui vient d'être écrite, à conserver
+*/
+func (r *SyncMapRepository) evictOne(keep string) {
+	r.data.Range(func(key, value any) bool {
+		if key == keep {
+			return true
+		}
+		if r.data.CompareAndDelete(key, value) {
+			r.size.Add(-1)
+			return false
+		}
+		return true
+	})
+}
+
+/*
+seed ajoute n entrées synthétiques (seed_0 à seed_n-1) via store, pour
+mesurer l'effet de la taille de la map sur la copie et le GC.
+
+@params:
+  - n: int nombre d'entrées à insérer
+*/
+func (r *SyncMapRepository) seed(n int) {
+	for i := 0; i < n; i++ {
+		entry := seedEntry(i, r.payload)
+		r.store(entry.Identifier, entry)
+	}
+}
+
+/*
+evictExpired supprime les entrées dont LastModified précède cutoff.
+CompareAndDelete ne supprime que la valeur examinée : une entrée réécrite
+pendant le balayage est conservée.
+
+@params:
+  - cutoff: time.Time date limite de dernière modification
+
+@returns: int - Nombre d'entrées supprimées
+*/
+func (r *SyncMapRepository) evictExpired(cutoff time.Time) int {
+	evicted := 0
+	r.data.Range(func(key, value any) bool {
+		if value.(*DataStruct).LastModified.Before(cutoff) && r.data.CompareAndDelete(key, value) {
+			r.size.Add(-1)
+			evicted++
+		}
+		return true
+	})
+	return evicted
+}
+
+/*
+GetDataHandler retourne l'entrée {id} en JSON, ou 404 si elle est absente.
+Load ne prend aucun verrou sur le chemin de lecture des clés stables.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+
+@returns: JSON DataStruct
+*/
+func (r *SyncMapRepository) GetDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	var entry DataStruct
+	v, found := r.data.Load(id)
+	if found {
+		entry = *v.(*DataStruct)
+	}
+
+	writeData(w, id, entry, found)
+}
+
+/*
+DeleteDataHandler supprime l'entrée {id} : 204 si supprimée, 404 si absente.
+LoadAndDelete indique atomiquement si la clé existait, pour maintenir size.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+*/
+func (r *SyncMapRepository) DeleteDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	_, found := r.data.LoadAndDelete(id)
+	if found {
+		r.size.Add(-1)
+	}
+
+	writeDeleted(w, id, found)
+}
+
+/*
+StatsHandler retourne les statistiques actuelles du serveur.
+Utilise des compteurs atomiques pour un accès thread-safe en O(1).
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON contenant total_requests, data_size, goroutines et heap_alloc_bytes
+*/
+func (r *SyncMapRepository) StatsHandler(w http.ResponseWriter, req *http.Request) {
+	// Lecture atomique du compteur
+	counter := atomic.LoadInt64(&r.counter)
+	
+	// Taille maintenue à chaque ajout, pas de parcours de la sync.Map
+	dataSize := r.size.Load()
+
+	stats := map[string]interface{}{
+		"total_requests": counter,
+		"data_size":      dataSize,
+	}
+	addRuntimeGauges(stats)
+
+	writeStats(w, stats)
+}
+
+/*
+Router configure les routes du serveur avec gorilla/mux.
+
+@returns: *mux.Router - Routeur prêt à être servi
+
+@endpoints:
+  - GET /process : Handler avec sync.Map (pas de mutex manuel)
+  - GET /data/{id} : Lecture d'une entrée (404 si absente)
+  - DELETE /data/{id} : Suppression d'une entrée
+  - GET /stats : Statistiques du serveur
+  - GET /snapshot : Copie complète des données (Range, O(n))
+  - GET /debug/allocs : Compteurs d'allocation du processus
+  - GET /debug/contention : Résumé du profil de contention des mutex
+  - POST /seed?n=N : Pré-remplissage avec N entrées synthétiques
+*/
+func (r *SyncMapRepository) Router() *mux.Router {
+	router := newRouter()
+	router.HandleFunc("/process", r.SyncMapHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.GetDataHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.DeleteDataHandler).Methods("DELETE")
+	router.HandleFunc("/stats", r.StatsHandler).Methods("GET")
+	router.HandleFunc("/snapshot", r.SnapshotHandler).Methods("GET")
+	router.HandleFunc("/debug/allocs", AllocsHandler).Methods("GET")
+	router.HandleFunc("/debug/contention", ContentionHandler).Methods("GET")
+	router.HandleFunc("/seed", seedHandler(r.seed)).Methods("POST")
+	return router
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+/*
+TestSyncMapSizeConcurrent écrit en parallèle des clés partagées entre toutes
+les goroutines et des clés propres à chacune : data_size doit valoir
+exactement le nombre de clés distinctes, sans double comptage des clés
+partagées ni mise à jour perdue. À lancer avec -race.
+*/
+func TestSyncMapSizeConcurrent(t *testing.T) {
+	const (
+		goroutines = 32
+		shared     = 100
+		disjoint   = 50
+	)
+
+	repo := NewSyncMapRepository(false)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < shared; i++ {
+				// Décalage par goroutine : les écritures d'une même clé se chevauchent
+				key := fmt.Sprintf("shared_%d", (i+g)%shared)
+				repo.store(key, &DataStruct{Identifier: key, Counter: g})
+				if i < disjoint {
+					own := fmt.Sprintf("g%d_%d", g, i)
+					repo.store(own, &DataStruct{Identifier: own, Counter: g})
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	rec := httptest.NewRecorder()
+	repo.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	var stats struct {
+		DataSize int `json:"data_size"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	distinct := 0
+	repo.data.Range(func(_, _ any) bool {
+		distinct++
+		return true
+	})
+	if want := shared + goroutines*disjoint; distinct != want {
+		t.Fatalf("sync.Map holds %d keys, want %d", distinct, want)
+	}
+	if stats.DataSize != distinct {
+		t.Errorf("data_size = %d, want %d distinct keys", stats.DataSize, distinct)
+	}
+}
+
+/*
+TestSyncMapSnapshot vérifie que /process ne copie que l'entrée de la requête
+précédente, quelle que soit la taille de la map, et que /snapshot renvoie la
+copie complète.
+*/
+func TestSyncMapSnapshot(t *testing.T) {
+	repo := newSyncMapRepository(newRepoOptions(WithCopyData(true), WithWork(0, 0)))
+	repo.seed(10)
+	handler := repo.Router()
+
+	get := func(target string) (body struct {
+		SnapshotSize int          `json:"snapshot_size"`
+		Data         []DataStruct `json:"data"`
+	}) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d, want 200", target, rec.Code)
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("GET %s: invalid JSON: %v", target, err)
+		}
+		return body
+	}
+
+	if got := get("/process"); got.SnapshotSize != 0 {
+		t.Errorf("first /process snapshot_size = %d, want 0 (no previous request)", got.SnapshotSize)
+	}
+	if got := get("/process?includeData=true"); got.SnapshotSize != 1 || len(got.Data) != 1 || got.Data[0].Identifier != "request_1" {
+		t.Errorf("second /process: snapshot_size %d, data %+v; want request_1 only", got.SnapshotSize, got.Data)
+	}
+	if got := get("/snapshot"); got.SnapshotSize != 12 || len(got.Data) != 12 {
+		t.Errorf("/snapshot: snapshot_size %d, %d entries; want 12", got.SnapshotSize, len(got.Data))
+	}
+}
+
+/*
+BenchmarkSyncMapRange mesure le coût de sync.Map.Range selon la taille de la
+map : un parcours seul (range), puis la copie que faisait SyncMapHandler à
+chaque requête (snapshot). Les deux sont O(n) : appelé par requête, Range
+fait paraître sync.Map plus lente que les serveurs à mutex dès que la map
+grossit, d'où une copie réservée à /snapshot.
+
+	go test ./pkg/server -run '^$' -bench SyncMapRange
+
+@metrics:
+  - ns/entry: Coût du parcours par entrée de la map
+*/
+func BenchmarkSyncMapRange(b *testing.B) {
+	for _, entries := range []int{100, 1000, 10000, 100000} {
+		repo := NewSyncMapRepository(true)
+		repo.seed(entries)
+
+		b.Run(fmt.Sprintf("range/entries=%d", entries), func(b *testing.B) {
+			visited := 0
+			for i := 0; i < b.N; i++ {
+				repo.data.Range(func(_, _ any) bool {
+					visited++
+					return true
+				})
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(visited), "ns/entry")
+		})
+		b.Run(fmt.Sprintf("snapshot/entries=%d", entries), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if n := len(repo.snapshot()); n != entries {
+					b.Fatalf("snapshot holds %d entries, want %d", n, entries)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*entries), "ns/entry")
+		})
+	}
+}
+
+/*
+BenchmarkSyncMapProcess compare /process avant et après le retrait du Range
+du chemin de chaque requête, sur une map pré-remplie et sans traitement
+lourd : range copie toute la map comme le faisait l'ancien handler, load est
+le handler actuel, qui ne lit qu'une clé. range croît avec la map, load non.
+
+	go test ./pkg/server -run '^$' -bench SyncMapProcess
+*/
+func BenchmarkSyncMapProcess(b *testing.B) {
+	for _, entries := range []int{0, 1000, 10000, 100000} {
+		repo := newSyncMapRepository(newRepoOptions(WithCopyData(true), WithWork(0, 0)))
+		repo.seed(entries)
+		handler := repo.Router()
+
+		b.Run(fmt.Sprintf("range/entries=%d", entries), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				repo.snapshot()
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/process", nil))
+			}
+		})
+		b.Run(fmt.Sprintf("load/entries=%d", entries), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/process", nil))
+			}
+		})
+	}
+}
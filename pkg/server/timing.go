@@ -0,0 +1,201 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// serverTimingMetrics sont les métriques toujours présentes dans Server-Timing, à zéro si la requête ne les a pas mesurées
+var serverTimingMetrics = []string{"lockwait", "process", "write"}
+
+// phaseMetrics associe les phases de startPhase à leur métrique Server-Timing
+var phaseMetrics = map[string]string{
+	"process.heavy":     "process",
+	"write":             "write",
+	"upstream.get":      "upstream",
+	"admission.wait":    "admission",
+	"semaphore.acquire": "semaphore",
+}
+
+// serverTimingKey est la clé de contexte du serverTiming posé par serverTimingMW
+type serverTimingKey struct{}
+
+/*
+serverTiming cumule les durées des phases d'une requête pour l'en-tête
+Server-Timing. Le mutex ne protège que ce cumul : le serveur pool mesure ses
+phases dans un worker, pas dans la goroutine du handler.
+
+@fields:
+  - mu: Protège durations et order
+  - durations: Durée cumulée par métrique (deux attentes de verrou s'additionnent)
+  - order: Métriques hors serverTimingMetrics, dans l'ordre de leur première mesure
+*/
+type serverTiming struct {
+	mu        sync.Mutex
+	durations map[string]time.Duration
+	order     []string
+}
+
+/*
+add ajoute d à la métrique name. Sans serverTiming (requête servie hors d'un
+routeur newRouter, benchmark direct), la mesure est ignorée.
+
+@params:
+  - name: string nom de la métrique (ex: "lockwait")
+  - d: time.Duration durée à ajouter
+*/
+func (t *serverTiming) add(name string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	if _, seen := t.durations[name]; !seen && !isTimingMetric(name) {
+		t.order = append(t.order, name)
+	}
+	t.durations[name] += d
+	t.mu.Unlock()
+}
+
+// isTimingMetric indique si name fait partie de serverTimingMetrics
+func isTimingMetric(name string) bool {
+	for _, m := range serverTimingMetrics {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+header construit la valeur de l'en-tête à partir des durées cumulées :
+lockwait, process et write d'abord, puis les autres phases mesurées.
+
+@returns: string - Valeur de Server-Timing (voir serverTimingHeader)
+*/
+func (t *serverTiming) header() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	names := append(append([]string{}, serverTimingMetrics...), t.order...)
+	durations := make([]time.Duration, len(names))
+	for i, name := range names {
+		durations[i] = t.durations[name]
+	}
+	return serverTimingHeader(names, durations)
+}
+
+/*
+serverTimingHeader formate des métriques au format Server-Timing, durées en
+millisecondes, rendu par l'onglet Timing des devtools de Chrome.
+
+	lockwait;dur=9.874, process;dur=10.312, write;dur=0.004
+
+@params:
+  - names: []string noms des métriques (tokens sans espace)
+  - durations: []time.Duration durée de chaque métrique, même longueur que names
+
+@returns: string - Valeur de l'en-tête
+*/
+func serverTimingHeader(names []string, durations []time.Duration) string {
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s;dur=%.3f", name, float64(durations[i])/float64(time.Millisecond))
+	}
+	return strings.Join(parts, ", ")
+}
+
+/*
+timingFromContext retourne le serverTiming de la requête.
+
+@params:
+  - ctx: context.Context contexte de la requête ou d'un span qui en dérive
+
+@returns: *serverTiming - Cumul de la requête, nil hors d'un routeur newRouter
+*/
+func timingFromContext(ctx context.Context) *serverTiming {
+	t, _ := ctx.Value(serverTimingKey{}).(*serverTiming)
+	return t
+}
+
+/*
+timedPhase est le span d'une phase qui, en se terminant, ajoute aussi sa
+durée au serverTiming de la requête.
+*/
+type timedPhase struct {
+	trace.Span
+	timing *serverTiming
+	metric string
+	start  time.Time
+}
+
+// End ajoute la durée de la phase à sa métrique puis termine le span
+func (p timedPhase) End(options ...trace.SpanEndOption) {
+	p.timing.add(p.metric, time.Since(p.start))
+	p.Span.End(options...)
+}
+
+/*
+timingResponseWriter ajoute l'en-tête Server-Timing juste avant l'envoi des
+en-têtes, quand les phases de la requête sont terminées. Flush est relayé
+pour que les flux NDJSON restent possibles.
+*/
+type timingResponseWriter struct {
+	http.ResponseWriter
+	timing      *serverTiming
+	wroteHeader bool
+}
+
+// WriteHeader pose Server-Timing avant d'envoyer le code
+func (w *timingResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.Header().Set("Server-Timing", w.timing.header())
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write envoie un 200 implicite, avec Server-Timing, si aucun code n'a été envoyé
+func (w *timingResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush envoie les en-têtes s'ils ne sont pas partis, puis relaie le Flush sous-jacent
+func (w *timingResponseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+/*
+serverTimingMW est le middleware Server-Timing des routeurs : chaque réponse
+porte la durée de lockwait (attente des verrous, via tracedLock), process
+(traitement lourd) et write (écriture, attente du verrou d'écriture
+comprise), mesurées par les mêmes helpers que les spans OpenTelemetry.
+
+@params:
+  - next: http.Handler handler de la route
+
+@returns: http.Handler - Handler ajoutant Server-Timing à ses réponses
+
+@note: L'en-tête part avec le premier octet de la réponse : sur
+/process/stream, qui envoie sa première ligne avant le traitement, il ne
+contient que ce qui précède le flux.
+*/
+func serverTimingMW(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		timing := &serverTiming{durations: make(map[string]time.Duration)}
+		ctx := context.WithValue(req.Context(), serverTimingKey{}, timing)
+		next.ServeHTTP(&timingResponseWriter{ResponseWriter: w, timing: timing}, req.WithContext(ctx))
+	})
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+parseServerTiming décode une valeur Server-Timing produite par serverTimingHeader.
+
+@params:
+  - t: *testing.T test propriétaire
+  - value: string valeur de l'en-tête
+
+@returns: map[string]time.Duration - Durée par métrique
+*/
+func parseServerTiming(t *testing.T, value string) map[string]time.Duration {
+	t.Helper()
+	metrics := make(map[string]time.Duration)
+	for _, part := range strings.Split(value, ", ") {
+		name, dur, ok := strings.Cut(part, ";dur=")
+		ms, err := strconv.ParseFloat(dur, 64)
+		if !ok || err != nil {
+			t.Fatalf("invalid Server-Timing entry %q in %q", part, value)
+		}
+		metrics[name] = time.Duration(ms * float64(time.Millisecond))
+	}
+	return metrics
+}
+
+/*
+TestServerTimingHeader vérifie le format de l'en-tête : métriques séparées
+par des virgules, durées en millisecondes à la microseconde.
+*/
+func TestServerTimingHeader(t *testing.T) {
+	got := serverTimingHeader([]string{"lockwait", "process", "write"},
+		[]time.Duration{9874 * time.Microsecond, 10312500 * time.Nanosecond, 0})
+	if want := "lockwait;dur=9.874, process;dur=10.312, write;dur=0.000"; got != want {
+		t.Errorf("serverTimingHeader = %q, want %q", got, want)
+	}
+}
+
+/*
+TestServerTiming vérifie sur chaque variante que /process porte un en-tête
+Server-Timing avec lockwait, process et write, et que process couvre le
+traitement lourd de 10ms.
+*/
+func TestServerTiming(t *testing.T) {
+	opts := Options{CopyData: true, SemaphoreLimit: 2, PoolWorkers: 2, PoolQueue: 4, AdmitBudget: time.Second, WorkSleep: defaultWorkSleep, WorkIters: defaultWorkIters}
+
+	for _, v := range Variants {
+		t.Run(v.Name, func(t *testing.T) {
+			handler := v.NewHandler(opts)
+			defer handler.(io.Closer).Close()
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/process?key=k", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("GET /process status = %d, want 200", rec.Code)
+			}
+			metrics := parseServerTiming(t, rec.Header().Get("Server-Timing"))
+			for _, name := range serverTimingMetrics {
+				if _, ok := metrics[name]; !ok {
+					t.Errorf("Server-Timing %v misses %s", metrics, name)
+				}
+			}
+			if metrics["process"] < 10*time.Millisecond {
+				t.Errorf("process = %s, want at least the 10ms of heavy work", metrics["process"])
+			}
+		})
+	}
+}
+
+/*
+TestServerTimingLockWait envoie deux requêtes simultanées au serveur bad :
+la seconde attend le mutex pendant tout le traitement de la première, et
+son lockwait le montre.
+*/
+func TestServerTimingLockWait(t *testing.T) {
+	const work = 20 * time.Millisecond
+	repo := NewRepository(WithLockStrategy(DeferMutex), WithWork(work, 0))
+	defer repo.Close()
+
+	headers := make([]string, 2)
+	var wg sync.WaitGroup
+	for i := range headers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			repo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/process", nil))
+			headers[i] = rec.Header().Get("Server-Timing")
+		}(i)
+	}
+	wg.Wait()
+
+	waits := []time.Duration{parseServerTiming(t, headers[0])["lockwait"], parseServerTiming(t, headers[1])["lockwait"]}
+	if longest := max(waits[0], waits[1]); longest < work/2 {
+		t.Errorf("lockwait = %v, want one request to wait about %s behind the other", waits, work)
+	}
+}
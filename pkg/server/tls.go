@@ -0,0 +1,106 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+)
+
+// selfSignedValidity est la durée de validité du certificat généré par SelfSignedCert
+const selfSignedValidity = 24 * time.Hour
+
+// selfSigned génère une seule fois le certificat partagé par les serveurs lancés avec -tls sans -cert
+var selfSigned = sync.OnceValues(SelfSignedCert)
+
+/*
+SelfSignedCert génère un certificat auto-signé pour localhost, 127.0.0.1
+et ::1, valide selfSignedValidity. Il suffit pour mesurer le coût du
+handshake TLS, pas pour authentifier un serveur : un client doit désactiver
+la vérification (InsecureSkipVerify).
+
+@returns: (tls.Certificate, error) - Certificat et clé ECDSA P-256, ou erreur de génération
+*/
+func SelfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"mutex-benchmark"}},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+/*
+checkTLS vérifie la cohérence des options TLS d'une configuration ou des flags.
+
+@params:
+  - enabled: bool écoute en TLS demandée (-tls)
+  - cert: string fichier du certificat PEM ("" = auto-signé)
+  - key: string fichier de la clé PEM ("" = auto-signée)
+
+@returns: error - Erreur si un seul des deux fichiers est fourni, ou s'ils
+le sont sans TLS
+*/
+func checkTLS(enabled bool, cert, key string) error {
+	switch {
+	case (cert == "") != (key == ""):
+		return errors.New("cert and key must be set together")
+	case cert != "" && !enabled:
+		return errors.New("cert and key require tls")
+	}
+	return nil
+}
+
+/*
+serverTLSConfig construit la configuration TLS d'un serveur lancé avec -tls :
+le certificat -cert/-key, ou à défaut le certificat auto-signé du processus.
+
+@params:
+  - opts: Options options communes des serveurs
+
+@returns: *tls.Config - Configuration à placer dans http.Server.TLSConfig
+
+@note: ParseFlags vérifie déjà que la paire -cert/-key se charge ; une
+erreur survenue depuis est remontée à chaque handshake, dans le log
+d'erreurs du serveur.
+*/
+func serverTLSConfig(opts Options) *tls.Config {
+	var cert tls.Certificate
+	var err error
+	if opts.CertFile != "" {
+		cert, err = tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+	} else {
+		cert, err = selfSigned()
+	}
+	if err != nil {
+		return &tls.Config{GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return nil, err }}
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+/*
+writeCertFiles écrit un certificat auto-signé et sa clé en PEM, comme les
+fichiers attendus par -cert et -key.
+
+@params:
+  - t: *testing.T test propriétaire (fichiers dans t.TempDir)
+
+@returns: (string, string) - Chemins du certificat et de la clé
+*/
+func writeCertFiles(t *testing.T) (string, string) {
+	t.Helper()
+	cert, err := SelfSignedCert()
+	if err != nil {
+		t.Fatalf("SelfSignedCert: %v", err)
+	}
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+/*
+TestServeTLS démarre le serveur good en TLS, avec le certificat auto-signé
+puis avec des fichiers -cert/-key, et l'interroge avec un client qui ne
+vérifie pas le certificat, comme les benchmarks lancés avec -tls.
+*/
+func TestServeTLS(t *testing.T) {
+	certFile, keyFile := writeCertFiles(t)
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+	}
+	variant, _ := Lookup("good")
+
+	for _, c := range []struct {
+		name string
+		opts Options
+	}{
+		{"self-signed", Options{TLS: true}},
+		{"cert files", Options{TLS: true, CertFile: certFile, KeyFile: keyFile}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := variant.NewServer(ln.Addr().String(), c.opts)
+			if srv.TLSConfig == nil {
+				t.Fatal("NewServer with TLS: no TLSConfig")
+			}
+			go srv.ServeTLS(ln, "", "")
+			defer srv.Close()
+
+			resp, err := client.Get("https://" + ln.Addr().String() + "/stats")
+			if err != nil {
+				t.Fatalf("GET /stats over TLS: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK || resp.TLS == nil {
+				t.Errorf("GET /stats: status %d, TLS %v; want 200 over TLS", resp.StatusCode, resp.TLS != nil)
+			}
+		})
+	}
+}
+
+/*
+TestParseFlagsTLS vérifie la validation de -tls, -cert et -key : les fichiers
+vont ensemble, exigent -tls, et une paire illisible arrête le démarrage.
+*/
+func TestParseFlagsTLS(t *testing.T) {
+	certFile, keyFile := writeCertFiles(t)
+	for _, c := range []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"-tls"}, true},
+		{[]string{"-tls", "-cert", certFile, "-key", keyFile}, true},
+		{[]string{"-tls", "-cert", certFile}, false},
+		{[]string{"-cert", certFile, "-key", keyFile}, false},
+		{[]string{"-tls", "-cert", keyFile, "-key", certFile}, false},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		var opts Options
+		opts.RegisterFlags(fs)
+		if err := ParseFlags(fs, c.args, &opts); (err == nil) != c.ok {
+			t.Errorf("ParseFlags(%q) error = %v, want ok=%v", c.args, err, c.ok)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer délègue au TracerProvider global : no-op tant que SetupTracing n'a
+// pas été appelé, donc sans coût mesurable par défaut
+var tracer = otel.Tracer("mutex-benchmark/pkg/server")
+
+/*
+SetupTracing installe un TracerProvider exportant les spans en OTLP/HTTP.
+
+@params:
+  - ctx: context.Context contexte de création de l'exporteur
+  - endpoint: string hôte:port du collecteur OTLP/HTTP ("" laisse le tracer no-op)
+  - serviceName: string nom du service dans les traces
+
+@returns: (func(context.Context) error, error) - Vidage et arrêt de l'exporteur, à appeler
+à la sortie ; erreur si l'exporteur ne peut pas être créé
+*/
+func SetupTracing(ctx context.Context, endpoint, serviceName string) (func(context.Context) error, error) {
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpoint(endpoint),
+		otlptracehttp.WithInsecure(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+/*
+startSpan démarre le span racine d'une requête, rattaché au contexte de trace
+propagé par le client (traceparent) s'il existe.
+
+@params:
+  - ctx: context.Context contexte de la requête
+  - name: string nom du span
+
+@returns: (context.Context, trace.Span) - Contexte portant le span, et le span à terminer
+*/
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name)
+}
+
+/*
+tracedLock acquiert un verrou dans un span "lock.acquire" et enregistre le
+temps d'attente, en attribut du span et du span parent : sur le serveur bad,
+les traces montrent les requêtes qui attendent leur tour. L'attente est aussi
+ajoutée à la métrique lockwait de l'en-tête Server-Timing.
+
+@params:
+  - ctx: context.Context contexte portant le span de la requête
+  - name: string nom du verrou (ex: "mu", "mu.write")
+  - lock: func() fonction d'acquisition (mu.Lock, mu.RLock, ...)
+
+@returns: time.Duration - Temps d'attente avant l'acquisition
+*/
+func tracedLock(ctx context.Context, name string, lock func()) time.Duration {
+	_, span := tracer.Start(ctx, "lock.acquire", trace.WithAttributes(attribute.String("lock.name", name)))
+	waitStart := time.Now()
+	lock()
+	wait := time.Since(waitStart)
+	waitMs := float64(wait) / float64(time.Millisecond)
+	span.SetAttributes(attribute.Float64("lock.wait_ms", waitMs))
+	span.End()
+
+	trace.SpanFromContext(ctx).AddEvent("lock acquired", trace.WithAttributes(
+		attribute.String("lock.name", name),
+		attribute.Float64("lock.wait_ms", waitMs),
+	))
+	timingFromContext(ctx).add("lockwait", wait)
+	return wait
+}
+
+/*
+startPhase démarre le span d'une phase du traitement (lecture, traitement
+lourd, écriture). Une phase listée dans phaseMetrics ajoute aussi sa durée à
+l'en-tête Server-Timing quand son span se termine.
+
+@params:
+  - ctx: context.Context contexte portant le span de la requête
+  - name: string nom de la phase
+
+@returns: trace.Span - Span à terminer à la fin de la phase
+*/
+func startPhase(ctx context.Context, name string) trace.Span {
+	_, span := tracer.Start(ctx, name)
+	metric, ok := phaseMetrics[name]
+	timing := timingFromContext(ctx)
+	if !ok || timing == nil {
+		return span
+	}
+	return timedPhase{Span: span, timing: timing, metric: metric, start: time.Now()}
+}
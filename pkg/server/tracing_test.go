@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+/*
+TestBadHandlerSpans vérifie que /process produit un span par phase et que le
+temps d'attente du verrou est enregistré en attribut.
+*/
+func TestBadHandlerSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	rec := httptest.NewRecorder()
+	NewBadRepository(false).Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/process", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+
+	spans := map[string]bool{}
+	for _, s := range exporter.GetSpans() {
+		spans[s.Name] = true
+		if s.Name != "lock.acquire" {
+			continue
+		}
+		found := false
+		for _, attr := range s.Attributes {
+			if attr.Key == "lock.wait_ms" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("lock.acquire span has no lock.wait_ms attribute")
+		}
+	}
+	for _, name := range []string{"bad.process", "lock.acquire", "process.heavy", "write"} {
+		if !spans[name] {
+			t.Errorf("missing span %q, got %v", name, spans)
+		}
+	}
+}
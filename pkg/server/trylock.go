@@ -0,0 +1,277 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+/*
+TryLockRepository garde la section critique du serveur bad (mutex tenu pendant
+tout le traitement) mais change la politique d'admission : au lieu d'attendre
+le mutex, une requête qui le trouve verrouillé est rejetée immédiatement (429).
+
+@fields:
+  - mu: Mutex protégeant les données, acquis uniquement via TryLock sur /process
+  - counter: Compteur global des requêtes traitées
+  - data: Map simulant des données métier partagées avec structure complexe
+  - copyData: Active la copie des données avant traitement (workload "lecture puis traitement")
+  - work: Traitement lourd de /process, sous le mutex
+  - mapCap: Nombre maximum d'entrées de data (0 = illimité)
+  - payload: Taille en octets du blob Payload de chaque entrée écrite
+  - enc: Encodeur des réponses de /process (voir -encoder)
+  - attempts: Nombre de requêtes reçues sur /process
+  - rejected: Nombre de requêtes rejetées faute d'avoir obtenu le mutex
+*/
+type TryLockRepository struct {
+	mu       sync.Mutex
+	counter  int
+	data     map[string]*DataStruct
+	copyData bool
+	work     WorkFunc
+	mapCap   int
+	payload  int
+	enc      Encoder
+	attempts atomic.Int64
+	rejected atomic.Int64
+}
+
+/*
+NewTryLockRepository crée et initialise un nouveau repository.
+
+@params:
+  - copyData: bool active la copie des données partagées à chaque requête
+
+@returns: *TryLockRepository - Nouvelle instance avec la map initialisée
+*/
+func NewTryLockRepository(copyData bool) *TryLockRepository {
+	return newTryLockRepository(newRepoOptions(WithCopyData(copyData)))
+}
+
+/*
+newTryLockRepository crée le repository à partir d'options déjà appliquées :
+le mutex est tenu pendant le traitement injecté, comme sur le serveur bad.
+
+@params:
+  - o: repoOptions configuration (copie, borne de la map, traitement, payload, encodeur)
+
+@returns: *TryLockRepository - Nouvelle instance avec la map initialisée
+*/
+func newTryLockRepository(o repoOptions) *TryLockRepository {
+	return &TryLockRepository{
+		data:     make(map[string]*DataStruct),
+		copyData: o.copyData,
+		work:     o.work,
+		mapCap:   o.mapCap,
+		payload:  o.payload,
+		enc:      o.encoder,
+	}
+}
+
+/*
+TryLockHandler modélise une admission fail-fast avec Mutex.TryLock.
+Aucune file ne se forme derrière le mutex : la latence des requêtes admises
+reste celle d'une requête seule, au prix des requêtes rejetées.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@behavior:
+  1. TryLock : si le mutex est déjà verrouillé, répond 429 sans attendre
+  2. Sinon, traitement complet sous le mutex, comme le serveur bad
+  3. Le mutex n'est libéré qu'à la fin de la fonction
+
+@performance: Latence bornée sous surcharge, throughput limité à une requête à la fois
+*/
+func (r *TryLockRepository) TryLockHandler(w http.ResponseWriter, req *http.Request) {
+	includeData, ok := parseIncludeData(w, req)
+	if !ok {
+		return
+	}
+	start := time.Now()
+	r.attempts.Add(1)
+	ctx, span := startSpan(req.Context(), "trylock.process")
+	defer span.End()
+
+	// Admission fail-fast : pas d'attente si le mutex est contendu
+	if !r.mu.TryLock() {
+		r.rejected.Add(1)
+		span.SetAttributes(attribute.Bool("lock.rejected", true))
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusTooManyRequests, "resource busy, retry later")
+		return
+	}
+	defer r.mu.Unlock()
+
+	r.counter++
+	currentCounter := r.counter
+	// La copie modélise un workload "lecture puis traitement" : son coût
+	// croît avec la taille de la map, elle est donc désactivable (-copy=false)
+	dataCopy := make(map[string]*DataStruct)
+	if r.copyData {
+		for k, v := range r.data {
+			dataCopy[k] = &DataStruct{
+				Identifier:   v.Identifier,
+				Name:         v.Name,
+				IsActive:     v.IsActive,
+				Counter:      v.Counter,
+				LastModified: v.LastModified,
+				Payload:      bytes.Clone(v.Payload),
+			}
+		}
+	}
+
+	// Traitement lourd sous le mutex, comme le serveur bad
+	heavySpan := startPhase(ctx, "process.heavy")
+	result := r.work(ctx) // Attente simulée puis calcul intensif
+	heavySpan.End()
+
+	writeSpan := startPhase(ctx, "write")
+	key := fmt.Sprintf("request_%d", currentCounter)
+	makeRoom(r.data, key, r.mapCap)
+	r.data[key] = &DataStruct{
+		Identifier:   key,
+		Name:         fmt.Sprintf("Request %d", currentCounter),
+		IsActive:     true,
+		Counter:      result,
+		LastModified: time.Now(),
+		Payload:      newPayload(r.payload),
+	}
+	writeSpan.End()
+
+	response := newResponse(req, "trylock", start)
+	response.Counter = currentCounter
+	response.Result = result
+	response.SnapshotSize = len(dataCopy)
+
+	if includeData {
+		addSnapshot(response, dataCopy)
+	}
+	writeEncoded(w, http.StatusOK, response, r.enc)
+}
+
+/*
+evictExpired supprime les entrées dont LastModified précède cutoff.
+Le janitor attend le mutex : seules les requêtes /process sont fail-fast.
+
+@params:
+  - cutoff: time.Time date limite de dernière modification
+
+@returns: int - Nombre d'entrées supprimées
+*/
+func (r *TryLockRepository) evictExpired(cutoff time.Time) int {
+	r.mu.Lock()
+	evicted := 0
+	for key, entry := range r.data {
+		if entry.LastModified.Before(cutoff) {
+			delete(r.data, key)
+			evicted++
+		}
+	}
+	r.mu.Unlock()
+	return evicted
+}
+
+/*
+GetDataHandler retourne l'entrée {id} en JSON, ou 404 si elle est absente.
+La lecture attend le mutex ; elle peut donc attendre la fin d'un /process.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+
+@returns: JSON DataStruct
+*/
+func (r *TryLockRepository) GetDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.mu.Lock()
+	var entry DataStruct
+	v, found := r.data[id]
+	if found {
+		entry = *v
+	}
+	r.mu.Unlock()
+
+	writeData(w, id, entry, found)
+}
+
+/*
+DeleteDataHandler supprime l'entrée {id} : 204 si supprimée, 404 si absente.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la variable de chemin id
+*/
+func (r *TryLockRepository) DeleteDataHandler(w http.ResponseWriter, req *http.Request) {
+	id := dataID(req)
+
+	r.mu.Lock()
+	_, found := r.data[id]
+	delete(r.data, id)
+	r.mu.Unlock()
+
+	writeDeleted(w, id, found)
+}
+
+/*
+StatsHandler retourne les statistiques actuelles du serveur.
+Les compteurs d'admission sont atomiques et lus sans le mutex.
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - req: *http.Request contenant la requête HTTP
+
+@returns: JSON contenant total_requests, data_size, attempts, rejected et rejection_rate
+*/
+func (r *TryLockRepository) StatsHandler(w http.ResponseWriter, req *http.Request) {
+	attempts := r.attempts.Load()
+	rejected := r.rejected.Load()
+	rejectionRate := 0.0
+	if attempts > 0 {
+		rejectionRate = float64(rejected) / float64(attempts)
+	}
+
+	r.mu.Lock()
+	stats := map[string]interface{}{
+		"total_requests": r.counter,
+		"data_size":      len(r.data),
+		"attempts":       attempts,
+		"rejected":       rejected,
+		"rejection_rate": rejectionRate,
+	}
+	r.mu.Unlock()
+
+	writeStats(w, stats)
+}
+
+/*
+Router configure les routes du serveur avec gorilla/mux.
+
+@returns: *mux.Router - Routeur prêt à être servi
+
+@endpoints:
+  - GET /process : Admission par TryLock, 429 si le mutex est contendu
+  - GET /data/{id} : Lecture d'une entrée (404 si absente)
+  - DELETE /data/{id} : Suppression d'une entrée
+  - GET /stats : Statistiques du serveur (dont rejection_rate)
+  - GET /debug/allocs : Compteurs d'allocation du processus
+  - GET /debug/contention : Résumé du profil de contention des mutex
+*/
+func (r *TryLockRepository) Router() *mux.Router {
+	router := newRouter()
+	router.HandleFunc("/process", r.TryLockHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.GetDataHandler).Methods("GET")
+	router.HandleFunc("/data/{id}", r.DeleteDataHandler).Methods("DELETE")
+	router.HandleFunc("/stats", r.StatsHandler).Methods("GET")
+	router.HandleFunc("/debug/allocs", AllocsHandler).Methods("GET")
+	router.HandleFunc("/debug/contention", ContentionHandler).Methods("GET")
+	return router
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+/*
+TestTryLockRejectsWhenContended verrouille le mutex comme le ferait une
+requête en cours, puis vérifie que /process répond 429 sans attendre et que
+/stats reflète le taux de rejet.
+*/
+func TestTryLockRejectsWhenContended(t *testing.T) {
+	repo := NewTryLockRepository(true)
+	handler := repo.Router()
+	process := func() int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/process", nil))
+		return rec.Code
+	}
+
+	repo.mu.Lock()
+	if code := process(); code != http.StatusTooManyRequests {
+		t.Errorf("contended status = %d, want 429", code)
+	}
+	repo.mu.Unlock()
+
+	if code := process(); code != http.StatusOK {
+		t.Errorf("uncontended status = %d, want 200", code)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	var stats struct {
+		Attempts      int64   `json:"attempts"`
+		Rejected      int64   `json:"rejected"`
+		RejectionRate float64 `json:"rejection_rate"`
+		TotalRequests int     `json:"total_requests"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if stats.Attempts != 2 || stats.Rejected != 1 || stats.RejectionRate != 0.5 || stats.TotalRequests != 1 {
+		t.Errorf("stats = %+v, want 2 attempts, 1 rejected, rate 0.5, 1 processed", stats)
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// upstreamTimeout borne un appel à l'upstream de /process/upstream
+const upstreamTimeout = 5 * time.Second
+
+// upstreamClient est partagé par les serveurs : ses connexions keep-alive sont réutilisées entre requêtes
+var upstreamClient = &http.Client{Timeout: upstreamTimeout}
+
+/*
+upstreamResult est le résultat d'un appel à l'upstream.
+
+@fields:
+  - status: Code HTTP reçu de l'upstream
+  - bytes: Taille du corps lu
+*/
+type upstreamResult struct {
+	status int
+	bytes  int64
+}
+
+/*
+callUpstream effectue le GET sortant de /process/upstream et lit tout le
+corps : la durée de l'appel est celle de l'aller-retour réseau complet,
+latence de l'upstream comprise.
+
+@params:
+  - ctx: context.Context contexte de la requête entrante (annule l'appel si le client part)
+  - target: string URL de l'upstream
+
+@returns: (upstreamResult, error) - Code et taille de la réponse, ou erreur
+de transport, de lecture, ou code HTTP autre que 2xx
+*/
+func callUpstream(ctx context.Context, target string) (upstreamResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return upstreamResult{}, err
+	}
+	resp, err := upstreamClient.Do(req)
+	if err != nil {
+		return upstreamResult{}, err
+	}
+	defer resp.Body.Close()
+
+	n, err := io.Copy(io.Discard, resp.Body)
+	result := upstreamResult{status: resp.StatusCode, bytes: n}
+	if err != nil {
+		return result, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return result, fmt.Errorf("upstream returned %d", resp.StatusCode)
+	}
+	return result, nil
+}
+
+/*
+checkUpstream vérifie l'URL d'upstream d'une configuration ou d'un flag.
+
+@params:
+  - raw: string URL de l'upstream ("" = non configuré, accepté)
+
+@returns: error - Erreur si l'URL n'est pas une URL http(s) absolue
+*/
+func checkUpstream(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("upstream %q: want an absolute http(s) URL", raw)
+	}
+	return nil
+}
+
+/*
+upstreamConfigured répond 503 si aucun upstream n'a été configuré (-upstream).
+
+@params:
+  - w: http.ResponseWriter pour envoyer la réponse
+  - target: string URL de l'upstream ("" = non configuré)
+
+@returns: bool - true si l'appel peut avoir lieu
+*/
+func upstreamConfigured(w http.ResponseWriter, target string) bool {
+	if target == "" {
+		writeError(w, http.StatusServiceUnavailable, "no upstream configured (start the server with -upstream=URL)")
+		return false
+	}
+	return true
+}
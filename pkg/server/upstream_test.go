@@ -0,0 +1,150 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// upstreamLatency est la latence injectée dans l'upstream de test
+const upstreamLatency = 5 * time.Millisecond
+
+/*
+newStubUpstream démarre un upstream httptest qui répond après upstreamLatency
+et note le nombre maximum d'appels simultanés.
+
+@params:
+  - t: testing.TB test ou benchmark propriétaire (l'upstream est fermé à la fin)
+
+@returns: (string, *atomic.Int64) - URL de l'upstream et maximum d'appels en vol
+*/
+func newStubUpstream(t testing.TB) (string, *atomic.Int64) {
+	var active, maxActive atomic.Int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			m := maxActive.Load()
+			if n <= m || maxActive.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(upstreamLatency)
+		w.Write([]byte("upstream"))
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream.URL, &maxActive
+}
+
+/*
+TestUpstreamHandlers vérifie /process/upstream : le serveur bad garde le
+mutex pendant l'appel, les appels à l'upstream sont donc sérialisés ; le
+good le libère avant, les appels se recouvrent.
+*/
+func TestUpstreamHandlers(t *testing.T) {
+	const requests = 8
+
+	for _, c := range []struct {
+		strategy LockStrategy
+		parallel bool
+	}{
+		{DeferMutex, false},
+		{Mutex, true},
+	} {
+		t.Run(c.strategy.String(), func(t *testing.T) {
+			upstreamURL, maxActive := newStubUpstream(t)
+			repo := NewRepository(WithLockStrategy(c.strategy), WithUpstream(upstreamURL))
+			defer repo.Close()
+
+			var wg sync.WaitGroup
+			for i := 0; i < requests; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					rec := httptest.NewRecorder()
+					repo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/process/upstream", nil))
+					if rec.Code != http.StatusOK {
+						t.Errorf("GET /process/upstream status = %d, want 200: %s", rec.Code, rec.Body)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if got := maxActive.Load(); (got > 1) != c.parallel {
+				t.Errorf("max concurrent upstream calls = %d, want parallel=%v", got, c.parallel)
+			}
+			if size := dataSize(t, repo); size != requests {
+				t.Errorf("data_size = %d, want %d", size, requests)
+			}
+		})
+	}
+}
+
+/*
+TestUpstreamErrors vérifie les réponses d'erreur de /process/upstream :
+503 sans upstream configuré, 502 si l'upstream répond une erreur.
+*/
+func TestUpstreamErrors(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	for _, strategy := range []LockStrategy{DeferMutex, Mutex} {
+		for _, c := range []struct {
+			upstream string
+			want     int
+		}{
+			{"", http.StatusServiceUnavailable},
+			{failing.URL, http.StatusBadGateway},
+		} {
+			repo := NewRepository(WithLockStrategy(strategy), WithUpstream(c.upstream))
+			rec := httptest.NewRecorder()
+			repo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/process/upstream", nil))
+			repo.Close()
+			if rec.Code != c.want {
+				t.Errorf("%s, upstream %q: status = %d, want %d", strategy, c.upstream, rec.Code, c.want)
+			}
+		}
+	}
+}
+
+/*
+BenchmarkUpstream mesure /process/upstream face à un upstream qui répond en
+upstreamLatency. Le verrou tenu pendant l'appel réseau devient un point de
+sérialisation : sur le serveur bad, le temps par requête reste celui d'un
+aller-retour complet quel que soit le parallélisme, alors qu'il baisse avec
+le nombre de goroutines sur le good.
+
+	go test ./pkg/server -run '^$' -bench Upstream -direct.parallelism 16
+
+@metrics:
+  - upstream-concurrency: Nombre maximum d'appels simultanés vus par l'upstream
+*/
+func BenchmarkUpstream(b *testing.B) {
+	for _, strategy := range []LockStrategy{DeferMutex, Mutex} {
+		b.Run(strategy.String(), func(b *testing.B) {
+			upstreamURL, maxActive := newStubUpstream(b)
+			repo := NewRepository(WithLockStrategy(strategy), WithCopyData(false), WithUpstream(upstreamURL))
+			defer repo.Close()
+
+			b.SetParallelism(*directParallelism)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					rec := httptest.NewRecorder()
+					repo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/process/upstream", nil))
+					if rec.Code != http.StatusOK {
+						b.Errorf("GET /process/upstream status = %d", rec.Code)
+						return
+					}
+				}
+			})
+			b.StopTimer()
+			b.ReportMetric(float64(maxActive.Load()), "upstream-concurrency")
+		})
+	}
+}
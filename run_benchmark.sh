@@ -49,6 +49,18 @@ print_info "Nettoyage des processus existants..."
 pkill -f "bad_server" 2>/dev/null
 pkill -f "good_server" 2>/dev/null
 pkill -f "syncmap_server" 2>/dev/null
+pkill -f "semaphore_server" 2>/dev/null
+pkill -f "pool_server" 2>/dev/null
+pkill -f "dcl_server" 2>/dev/null
+pkill -f "singleflight_server" 2>/dev/null
+pkill -f "rwmutex_server" 2>/dev/null
+pkill -f "trylock_server" 2>/dev/null
+pkill -f "fair_server" 2>/dev/null
+pkill -f "scoped_server" 2>/dev/null
+pkill -f "admission_server" 2>/dev/null
+pkill -f "atomicvalue_server" 2>/dev/null
+pkill -f "bigcopy_server" 2>/dev/null
+pkill -f "floor_server" 2>/dev/null
 sleep 2
 print_success "Processus nettoyés"
 
@@ -70,6 +82,66 @@ echo -e "${PURPLE}→ Lancement du serveur 'SYNC.MAP' (sans mutex manuel) sur le
 go run cmd/syncmap_server/syncmap_server.go &
 SYNCMAP_PID=$!
 
+# Démarrer le serveur "semaphore" en arrière-plan
+echo -e "${CYAN}→ Lancement du serveur 'SEMAPHORE' (parallélisme borné) sur le port 8084${NC}"
+go run cmd/semaphore_server/semaphore_server.go &
+SEMAPHORE_PID=$!
+
+# Démarrer le serveur "pool" en arrière-plan
+echo -e "${BLUE}→ Lancement du serveur 'POOL' (workers + file bornée) sur le port 8085${NC}"
+go run cmd/pool_server/pool_server.go &
+POOL_PID=$!
+
+# Démarrer le serveur "dcl" en arrière-plan
+echo -e "${YELLOW}→ Lancement du serveur 'DCL' (cache double-checked locking) sur le port 8086${NC}"
+go run cmd/dcl_server/dcl_server.go &
+DCL_PID=$!
+
+# Démarrer le serveur "singleflight" en arrière-plan
+echo -e "${GREEN}→ Lancement du serveur 'SINGLEFLIGHT' (calculs dédupliqués) sur le port 8087${NC}"
+go run cmd/singleflight_server/singleflight_server.go &
+SINGLEFLIGHT_PID=$!
+
+# Démarrer le serveur "rwmutex" en arrière-plan
+echo -e "${CYAN}→ Lancement du serveur 'RWMUTEX' (lectures parallèles) sur le port 8088${NC}"
+go run cmd/rwmutex_server/rwmutex_server.go &
+RWMUTEX_PID=$!
+
+# Démarrer le serveur "trylock" en arrière-plan
+echo -e "${YELLOW}→ Lancement du serveur 'TRYLOCK' (admission fail-fast) sur le port 8089${NC}"
+go run cmd/trylock_server/trylock_server.go &
+TRYLOCK_PID=$!
+
+# Démarrer le serveur "fair" en arrière-plan
+echo -e "${BLUE}→ Lancement du serveur 'FAIR' (defer ou Unlock, même section) sur le port 8090${NC}"
+go run cmd/fair_server/fair_server.go &
+FAIR_PID=$!
+
+# Démarrer le serveur "scoped" en arrière-plan
+echo -e "${GREEN}→ Lancement du serveur 'SCOPED' (defer dans une fonction anonyme) sur le port 8091${NC}"
+go run cmd/scoped_server/scoped_server.go &
+SCOPED_PID=$!
+
+# Démarrer le serveur "admission" en arrière-plan
+echo -e "${GREEN}→ Lancement du serveur 'ADMISSION' (admission bornée, 503 + Retry-After) sur le port 8092${NC}"
+go run cmd/admission_server/admission_server.go &
+ADMISSION_PID=$!
+
+# Démarrer le serveur "atomicvalue" en arrière-plan
+echo -e "${CYAN}→ Lancement du serveur 'ATOMICVALUE' (copy-on-write, lectures sans verrou) sur le port 8093${NC}"
+go run cmd/atomicvalue_server/atomicvalue_server.go &
+ATOMICVALUE_PID=$!
+
+# Démarrer le serveur "bigcopy" en arrière-plan
+echo -e "${YELLOW}→ Lancement du serveur 'BIGCOPY' (good, copie lourde sous le verrou) sur le port 8094${NC}"
+go run cmd/bigcopy_server/bigcopy_server.go &
+BIGCOPY_PID=$!
+
+# Démarrer le serveur "floor" en arrière-plan
+echo -e "${BLUE}→ Lancement du serveur 'FLOOR' (compteur atomique seul, ni map ni verrou) sur le port 8095${NC}"
+go run cmd/floor_server/floor_server.go &
+FLOOR_PID=$!
+
 # Attendre que les serveurs soient prêts
 echo -e "\n${BLUE}⏳ Attente du démarrage des serveurs...${NC}"
 for i in {1..5}; do
@@ -80,15 +152,51 @@ echo ""
 
 # Vérifier que les serveurs répondent
 print_info "Vérification de la disponibilité des serveurs..."
-curl -s http://localhost:8081/stats > /dev/null || { print_error "Le serveur BAD ne répond pas"; kill $BAD_PID $GOOD_PID $SYNCMAP_PID 2>/dev/null; exit 1; }
+curl -s http://localhost:8081/stats > /dev/null || { print_error "Le serveur BAD ne répond pas"; kill $BAD_PID $GOOD_PID $SYNCMAP_PID $SEMAPHORE_PID $POOL_PID $DCL_PID $SINGLEFLIGHT_PID $RWMUTEX_PID $TRYLOCK_PID $FAIR_PID $SCOPED_PID $ADMISSION_PID $ATOMICVALUE_PID $BIGCOPY_PID $FLOOR_PID 2>/dev/null; exit 1; }
 print_success "Serveur BAD (port 8081) opérationnel"
 
-curl -s http://localhost:8082/stats > /dev/null || { print_error "Le serveur GOOD ne répond pas"; kill $BAD_PID $GOOD_PID $SYNCMAP_PID 2>/dev/null; exit 1; }
+curl -s http://localhost:8082/stats > /dev/null || { print_error "Le serveur GOOD ne répond pas"; kill $BAD_PID $GOOD_PID $SYNCMAP_PID $SEMAPHORE_PID $POOL_PID $DCL_PID $SINGLEFLIGHT_PID $RWMUTEX_PID $TRYLOCK_PID $FAIR_PID $SCOPED_PID $ADMISSION_PID $ATOMICVALUE_PID $BIGCOPY_PID $FLOOR_PID 2>/dev/null; exit 1; }
 print_success "Serveur GOOD (port 8082) opérationnel"
 
-curl -s http://localhost:8083/stats > /dev/null || { print_error "Le serveur SYNC.MAP ne répond pas"; kill $BAD_PID $GOOD_PID $SYNCMAP_PID 2>/dev/null; exit 1; }
+curl -s http://localhost:8083/stats > /dev/null || { print_error "Le serveur SYNC.MAP ne répond pas"; kill $BAD_PID $GOOD_PID $SYNCMAP_PID $SEMAPHORE_PID $POOL_PID $DCL_PID $SINGLEFLIGHT_PID $RWMUTEX_PID $TRYLOCK_PID $FAIR_PID $SCOPED_PID $ADMISSION_PID $ATOMICVALUE_PID $BIGCOPY_PID $FLOOR_PID 2>/dev/null; exit 1; }
 print_success "Serveur SYNC.MAP (port 8083) opérationnel"
 
+curl -s http://localhost:8084/stats > /dev/null || { print_error "Le serveur SEMAPHORE ne répond pas"; kill $BAD_PID $GOOD_PID $SYNCMAP_PID $SEMAPHORE_PID $POOL_PID $DCL_PID $SINGLEFLIGHT_PID $RWMUTEX_PID $TRYLOCK_PID $FAIR_PID $SCOPED_PID $ADMISSION_PID $ATOMICVALUE_PID $BIGCOPY_PID $FLOOR_PID 2>/dev/null; exit 1; }
+print_success "Serveur SEMAPHORE (port 8084) opérationnel"
+
+curl -s http://localhost:8085/stats > /dev/null || { print_error "Le serveur POOL ne répond pas"; kill $BAD_PID $GOOD_PID $SYNCMAP_PID $SEMAPHORE_PID $POOL_PID $DCL_PID $SINGLEFLIGHT_PID $RWMUTEX_PID $TRYLOCK_PID $FAIR_PID $SCOPED_PID $ADMISSION_PID $ATOMICVALUE_PID $BIGCOPY_PID $FLOOR_PID 2>/dev/null; exit 1; }
+print_success "Serveur POOL (port 8085) opérationnel"
+
+curl -s http://localhost:8086/stats > /dev/null || { print_error "Le serveur DCL ne répond pas"; kill $BAD_PID $GOOD_PID $SYNCMAP_PID $SEMAPHORE_PID $POOL_PID $DCL_PID $SINGLEFLIGHT_PID $RWMUTEX_PID $TRYLOCK_PID $FAIR_PID $SCOPED_PID $ADMISSION_PID $ATOMICVALUE_PID $BIGCOPY_PID $FLOOR_PID 2>/dev/null; exit 1; }
+print_success "Serveur DCL (port 8086) opérationnel"
+
+curl -s http://localhost:8087/stats > /dev/null || { print_error "Le serveur SINGLEFLIGHT ne répond pas"; kill $BAD_PID $GOOD_PID $SYNCMAP_PID $SEMAPHORE_PID $POOL_PID $DCL_PID $SINGLEFLIGHT_PID $RWMUTEX_PID $TRYLOCK_PID $FAIR_PID $SCOPED_PID $ADMISSION_PID $ATOMICVALUE_PID $BIGCOPY_PID $FLOOR_PID 2>/dev/null; exit 1; }
+print_success "Serveur SINGLEFLIGHT (port 8087) opérationnel"
+
+curl -s http://localhost:8088/stats > /dev/null || { print_error "Le serveur RWMUTEX ne répond pas"; kill $BAD_PID $GOOD_PID $SYNCMAP_PID $SEMAPHORE_PID $POOL_PID $DCL_PID $SINGLEFLIGHT_PID $RWMUTEX_PID $TRYLOCK_PID $FAIR_PID $SCOPED_PID $ADMISSION_PID $ATOMICVALUE_PID $BIGCOPY_PID $FLOOR_PID 2>/dev/null; exit 1; }
+print_success "Serveur RWMUTEX (port 8088) opérationnel"
+
+curl -s http://localhost:8089/stats > /dev/null || { print_error "Le serveur TRYLOCK ne répond pas"; kill $BAD_PID $GOOD_PID $SYNCMAP_PID $SEMAPHORE_PID $POOL_PID $DCL_PID $SINGLEFLIGHT_PID $RWMUTEX_PID $TRYLOCK_PID $FAIR_PID $SCOPED_PID $ADMISSION_PID $ATOMICVALUE_PID $BIGCOPY_PID $FLOOR_PID 2>/dev/null; exit 1; }
+print_success "Serveur TRYLOCK (port 8089) opérationnel"
+
+curl -s http://localhost:8090/stats > /dev/null || { print_error "Le serveur FAIR ne répond pas"; kill $BAD_PID $GOOD_PID $SYNCMAP_PID $SEMAPHORE_PID $POOL_PID $DCL_PID $SINGLEFLIGHT_PID $RWMUTEX_PID $TRYLOCK_PID $FAIR_PID $SCOPED_PID $ADMISSION_PID $ATOMICVALUE_PID $BIGCOPY_PID $FLOOR_PID 2>/dev/null; exit 1; }
+print_success "Serveur FAIR (port 8090) opérationnel"
+
+curl -s http://localhost:8091/stats > /dev/null || { print_error "Le serveur SCOPED ne répond pas"; kill $BAD_PID $GOOD_PID $SYNCMAP_PID $SEMAPHORE_PID $POOL_PID $DCL_PID $SINGLEFLIGHT_PID $RWMUTEX_PID $TRYLOCK_PID $FAIR_PID $SCOPED_PID $ADMISSION_PID $ATOMICVALUE_PID $BIGCOPY_PID $FLOOR_PID 2>/dev/null; exit 1; }
+print_success "Serveur SCOPED (port 8091) opérationnel"
+
+curl -s http://localhost:8092/stats > /dev/null || { print_error "Le serveur ADMISSION ne répond pas"; kill $BAD_PID $GOOD_PID $SYNCMAP_PID $SEMAPHORE_PID $POOL_PID $DCL_PID $SINGLEFLIGHT_PID $RWMUTEX_PID $TRYLOCK_PID $FAIR_PID $SCOPED_PID $ADMISSION_PID $ATOMICVALUE_PID $BIGCOPY_PID $FLOOR_PID 2>/dev/null; exit 1; }
+print_success "Serveur ADMISSION (port 8092) opérationnel"
+
+curl -s http://localhost:8093/stats > /dev/null || { print_error "Le serveur ATOMICVALUE ne répond pas"; kill $BAD_PID $GOOD_PID $SYNCMAP_PID $SEMAPHORE_PID $POOL_PID $DCL_PID $SINGLEFLIGHT_PID $RWMUTEX_PID $TRYLOCK_PID $FAIR_PID $SCOPED_PID $ADMISSION_PID $ATOMICVALUE_PID $BIGCOPY_PID $FLOOR_PID 2>/dev/null; exit 1; }
+print_success "Serveur ATOMICVALUE (port 8093) opérationnel"
+
+curl -s http://localhost:8094/stats > /dev/null || { print_error "Le serveur BIGCOPY ne répond pas"; kill $BAD_PID $GOOD_PID $SYNCMAP_PID $SEMAPHORE_PID $POOL_PID $DCL_PID $SINGLEFLIGHT_PID $RWMUTEX_PID $TRYLOCK_PID $FAIR_PID $SCOPED_PID $ADMISSION_PID $ATOMICVALUE_PID $BIGCOPY_PID $FLOOR_PID 2>/dev/null; exit 1; }
+print_success "Serveur BIGCOPY (port 8094) opérationnel"
+
+curl -s http://localhost:8095/stats > /dev/null || { print_error "Le serveur FLOOR ne répond pas"; kill $BAD_PID $GOOD_PID $SYNCMAP_PID $SEMAPHORE_PID $POOL_PID $DCL_PID $SINGLEFLIGHT_PID $RWMUTEX_PID $TRYLOCK_PID $FAIR_PID $SCOPED_PID $ADMISSION_PID $ATOMICVALUE_PID $BIGCOPY_PID $FLOOR_PID 2>/dev/null; exit 1; }
+print_success "Serveur FLOOR (port 8095) opérationnel"
+
 # Lancer le test de latence comparative en premier
 print_header "${ROCKET} TEST DE COMPARAISON DE LATENCE"
 echo -e "${PURPLE}Ce test mesure la latence moyenne sous différents niveaux de concurrence${NC}\n"
@@ -107,6 +215,28 @@ format_benchmark_output() {
             echo -e "${GREEN}${line}${NC}" | sed 's/ns\/op/ns\/op/g'
         elif [[ $line == *"BenchmarkSyncMapServer"* ]]; then
             echo -e "${PURPLE}${line}${NC}" | sed 's/ns\/op/ns\/op/g'
+        elif [[ $line == *"BenchmarkSemaphoreServer"* ]]; then
+            echo -e "${CYAN}${line}${NC}" | sed 's/ns\/op/ns\/op/g'
+        elif [[ $line == *"BenchmarkDCLServer"* ]]; then
+            echo -e "${YELLOW}${line}${NC}" | sed 's/ns\/op/ns\/op/g'
+        elif [[ $line == *"BenchmarkSingleflightServer"* ]]; then
+            echo -e "${GREEN}${line}${NC}" | sed 's/ns\/op/ns\/op/g'
+        elif [[ $line == *"BenchmarkRWMutexServer"* ]]; then
+            echo -e "${CYAN}${line}${NC}" | sed 's/ns\/op/ns\/op/g'
+        elif [[ $line == *"BenchmarkTryLockServer"* ]]; then
+            echo -e "${YELLOW}${line}${NC}" | sed 's/ns\/op/ns\/op/g'
+        elif [[ $line == *"BenchmarkFairServer"* ]]; then
+            echo -e "${BLUE}${line}${NC}" | sed 's/ns\/op/ns\/op/g'
+        elif [[ $line == *"BenchmarkScopedServer"* ]]; then
+            echo -e "${GREEN}${line}${NC}" | sed 's/ns\/op/ns\/op/g'
+        elif [[ $line == *"BenchmarkAdmissionServer"* ]]; then
+            echo -e "${GREEN}${line}${NC}" | sed 's/ns\/op/ns\/op/g'
+        elif [[ $line == *"BenchmarkAtomicValueServer"* ]]; then
+            echo -e "${CYAN}${line}${NC}" | sed 's/ns\/op/ns\/op/g'
+        elif [[ $line == *"BenchmarkBigCopyServer"* ]]; then
+            echo -e "${YELLOW}${line}${NC}" | sed 's/ns\/op/ns\/op/g'
+        elif [[ $line == *"BenchmarkFloorServer"* ]]; then
+            echo -e "${BLUE}${line}${NC}" | sed 's/ns\/op/ns\/op/g'
         elif [[ $line == *"PASS"* ]]; then
             echo -e "${GREEN}${BOLD}$line${NC}"
         elif [[ $line == *"FAIL"* ]]; then
@@ -134,10 +264,46 @@ curl -s http://localhost:8082/stats 2>/dev/null | head -5 || echo "Impossible de
 echo -e "\n${BOLD}${PURPLE}Statistiques du serveur SYNC.MAP (sans mutex manuel):${NC}"
 curl -s http://localhost:8083/stats 2>/dev/null | head -5 || echo "Impossible de récupérer les stats"
 
+echo -e "\n${BOLD}${CYAN}Statistiques du serveur SEMAPHORE (parallélisme borné):${NC}"
+curl -s http://localhost:8084/stats 2>/dev/null | head -5 || echo "Impossible de récupérer les stats"
+
+echo -e "\n${BOLD}${BLUE}Statistiques du serveur POOL (workers + file bornée):${NC}"
+curl -s http://localhost:8085/stats 2>/dev/null | head -5 || echo "Impossible de récupérer les stats"
+
+echo -e "\n${BOLD}${YELLOW}Statistiques du serveur DCL (cache double-checked locking):${NC}"
+curl -s http://localhost:8086/stats 2>/dev/null | head -5 || echo "Impossible de récupérer les stats"
+
+echo -e "\n${BOLD}${GREEN}Statistiques du serveur SINGLEFLIGHT (calculs dédupliqués):${NC}"
+curl -s http://localhost:8087/stats 2>/dev/null | head -5 || echo "Impossible de récupérer les stats"
+
+echo -e "\n${BOLD}${CYAN}Statistiques du serveur RWMUTEX (lectures parallèles):${NC}"
+curl -s http://localhost:8088/stats 2>/dev/null | head -5 || echo "Impossible de récupérer les stats"
+
+echo -e "\n${BOLD}${YELLOW}Statistiques du serveur TRYLOCK (admission fail-fast):${NC}"
+curl -s http://localhost:8089/stats 2>/dev/null | head -5 || echo "Impossible de récupérer les stats"
+
+echo -e "\n${BOLD}${BLUE}Statistiques du serveur FAIR (defer ou Unlock, même section):${NC}"
+curl -s http://localhost:8090/stats 2>/dev/null | head -5 || echo "Impossible de récupérer les stats"
+
+echo -e "\n${BOLD}${GREEN}Statistiques du serveur SCOPED (defer dans une fonction anonyme):${NC}"
+curl -s http://localhost:8091/stats 2>/dev/null | head -5 || echo "Impossible de récupérer les stats"
+
+echo -e "\n${BOLD}${GREEN}Statistiques du serveur ADMISSION (admission bornée, 503 + Retry-After):${NC}"
+curl -s http://localhost:8092/stats 2>/dev/null | head -5 || echo "Impossible de récupérer les stats"
+
+echo -e "\n${BOLD}${CYAN}Statistiques du serveur ATOMICVALUE (copy-on-write, lectures sans verrou):${NC}"
+curl -s http://localhost:8093/stats 2>/dev/null | head -5 || echo "Impossible de récupérer les stats"
+
+echo -e "\n${BOLD}${YELLOW}Statistiques du serveur BIGCOPY (good, copie lourde sous le verrou):${NC}"
+curl -s http://localhost:8094/stats 2>/dev/null | head -5 || echo "Impossible de récupérer les stats"
+
+echo -e "\n${BOLD}${BLUE}Statistiques du serveur FLOOR (compteur atomique seul, ni map ni verrou):${NC}"
+curl -s http://localhost:8095/stats 2>/dev/null | head -5 || echo "Impossible de récupérer les stats"
+
 # Tuer les serveurs
 echo ""
 print_info "Arrêt des serveurs..."
-kill $BAD_PID $GOOD_PID $SYNCMAP_PID 2>/dev/null
+kill $BAD_PID $GOOD_PID $SYNCMAP_PID $SEMAPHORE_PID $POOL_PID $DCL_PID $SINGLEFLIGHT_PID $RWMUTEX_PID $TRYLOCK_PID $FAIR_PID $SCOPED_PID $ADMISSION_PID $ATOMICVALUE_PID $BIGCOPY_PID $FLOOR_PID 2>/dev/null
 sleep 1
 
 # Vérifier que les serveurs sont bien arrêtés
@@ -156,6 +322,66 @@ if ps -p $SYNCMAP_PID > /dev/null 2>&1; then
     kill -9 $SYNCMAP_PID 2>/dev/null
 fi
 
+if ps -p $SEMAPHORE_PID > /dev/null 2>&1; then
+    print_warning "Force l'arrêt du serveur SEMAPHORE..."
+    kill -9 $SEMAPHORE_PID 2>/dev/null
+fi
+
+if ps -p $POOL_PID > /dev/null 2>&1; then
+    print_warning "Force l'arrêt du serveur POOL..."
+    kill -9 $POOL_PID 2>/dev/null
+fi
+
+if ps -p $DCL_PID > /dev/null 2>&1; then
+    print_warning "Force l'arrêt du serveur DCL..."
+    kill -9 $DCL_PID 2>/dev/null
+fi
+
+if ps -p $SINGLEFLIGHT_PID > /dev/null 2>&1; then
+    print_warning "Force l'arrêt du serveur SINGLEFLIGHT..."
+    kill -9 $SINGLEFLIGHT_PID 2>/dev/null
+fi
+
+if ps -p $RWMUTEX_PID > /dev/null 2>&1; then
+    print_warning "Force l'arrêt du serveur RWMUTEX..."
+    kill -9 $RWMUTEX_PID 2>/dev/null
+fi
+
+if ps -p $TRYLOCK_PID > /dev/null 2>&1; then
+    print_warning "Force l'arrêt du serveur TRYLOCK..."
+    kill -9 $TRYLOCK_PID 2>/dev/null
+fi
+
+if ps -p $FAIR_PID > /dev/null 2>&1; then
+    print_warning "Force l'arrêt du serveur FAIR..."
+    kill -9 $FAIR_PID 2>/dev/null
+fi
+
+if ps -p $SCOPED_PID > /dev/null 2>&1; then
+    print_warning "Force l'arrêt du serveur SCOPED..."
+    kill -9 $SCOPED_PID 2>/dev/null
+fi
+
+if ps -p $ADMISSION_PID > /dev/null 2>&1; then
+    print_warning "Force l'arrêt du serveur ADMISSION..."
+    kill -9 $ADMISSION_PID 2>/dev/null
+fi
+
+if ps -p $ATOMICVALUE_PID > /dev/null 2>&1; then
+    print_warning "Force l'arrêt du serveur ATOMICVALUE..."
+    kill -9 $ATOMICVALUE_PID 2>/dev/null
+fi
+
+if ps -p $BIGCOPY_PID > /dev/null 2>&1; then
+    print_warning "Force l'arrêt du serveur BIGCOPY..."
+    kill -9 $BIGCOPY_PID 2>/dev/null
+fi
+
+if ps -p $FLOOR_PID > /dev/null 2>&1; then
+    print_warning "Force l'arrêt du serveur FLOOR..."
+    kill -9 $FLOOR_PID 2>/dev/null
+fi
+
 print_success "Serveurs arrêtés"
 
 print_header "${CHECK} BENCHMARK TERMINÉ AVEC SUCCÈS! ${CHECK}"